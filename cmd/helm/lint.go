@@ -19,8 +19,13 @@ package main
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -28,8 +33,12 @@ import (
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -37,92 +46,508 @@ var longLintHelp = `
 This command takes a path to a chart and runs a series of tests to verify that
 the chart is well-formed.
 
+A chart published in a registry or a chart repository can be linted without
+pulling it first, by giving its oci:// reference or the URL of its package.
+With '--from-release', the chart of an installed release is linted instead, with
+the values of the release, to check what is deployed against the current rules.
+Releases don't store the subcharts of their charts, which aren't linted then.
+With '--reuse-values-from', the charts given are linted with the values of the
+named release instead, to check what upgrading it to them would report. The
+values files and --set flags are layered on top of those of the release.
+
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+Findings about a template can be suppressed with a comment naming the rule in
+the template itself:
+
+    # helm-lint:ignore deprecated-api
+
+Findings can also be ignored with .helmlintignore files, each line naming a rule,
+or '*' for any rule, and optionally a glob for the paths it is ignored in. Lines
+starting with 'message:' ignore the findings whose message contains the rest of
+the line. The files are read from the chart directory and its parents up to the
+git repository root, and merged:
+
+    deprecated-api templates/legacy-*.yaml
+    * templates/vendored/*
+    message: icon is recommended
+
+Every chart linted can skip findings with --skip-rules, given rule IDs like
+app-version, chart-name or deprecated-api, or prefixes of the paths of the
+findings like templates or values.yaml. Skipped findings don't fail the lint:
+
+    $ helm lint --skip-rules app-version,icon ./mychart
+
+The rules config of the rules bundle applies to every chart. The one given to
+--rules-config is layered over it, and a chart can add its own config in
+ci/lint-rules.yaml, which applies to the chart and, with --with-subcharts, to its
+subcharts. It is layered over the config of the parent chart. Each layer can only
+make the rules stricter: for example, it can narrow down the allowed image
+registries, but not allow other ones.
+
+With --with-subcharts, each subchart is linted with the values it receives from
+its parent charts: those under its name or alias, and the globals. With
+--subchart-values, these include the values set in the values.yaml files of the
+parents, so an umbrella chart configuring a subchart against its schema is
+reported on the subchart. With --warn-unused-values, the values a subchart
+receives but doesn't define in its values.yaml or values.schema.json, which
+are most likely typos, are reported too:
+
+    $ helm lint --with-subcharts --warn-unused-values --set redis.pasword=x ./umbrella
+
+The values of a chart which violate its values.schema.json are reported as a
+single error. With --schema-strict, each violation is an error of its own with
+the JSON path of the value and the reason, such as:
+
+    [ERROR] values.yaml: $.image.tag: Invalid type. Expected: string, given: integer
+
+The subcharts disabled by the condition or tags of their dependency with these
+values are skipped, as they are not rendered, unless --lint-disabled-subcharts
+is set.
+
+A subchart used by its parent under several aliases is linted once, and
+reported with all of them. With --lint-each-alias, it is linted once for each
+alias instead, with the values its parent sets for that alias.
+
+The subcharts are found in the charts directory of each chart, or in the one
+--subchart-dir names, for charts vendoring them elsewhere. Helm still loads the
+subcharts of a chart from its charts directory when rendering it.
+
+In the structured outputs, each chart is reported with its owner, so findings
+can be routed to the team owning the chart. The owner is set by the
+helm.sh/lint-owner annotation of the Chart.yaml, or else by the nearest
+CODEOWNERS file up to the repository root with a pattern matching the chart.
+
+A values file given as '-' is read from stdin. It is merged with the other
+values files and --set flags in the order they are given, like any values file;
+an empty stdin sets no values:
+
+    $ generate-values | helm lint -f base.yaml -f - --set image.tag=dev ./mychart
+
+To catch the deprecations and capabilities which only affect some Kubernetes
+versions, --kube-version can be repeated. The charts are then linted once for
+each version, and every result is labeled with the version it was linted for:
+
+    $ helm lint --kube-version 1.24.0 --kube-version 1.29.0 ./mychart
+
+To lint against the capabilities of a given cluster, give --capabilities-file a
+directory holding the Kubernetes version of the cluster in a kube-version file
+and the API versions it serves, as listed by 'kubectl api-versions', in an
+api-versions.txt file. The templates see these in .Capabilities, and deprecated
+APIs are reported for that version. --kube-version overrides the version:
+
+    $ kubectl version -o json | jq -r .serverVersion.gitVersion > eks/kube-version
+    $ kubectl api-versions > eks/api-versions.txt
+    $ helm lint --capabilities-file eks ./mychart
+
+To lint only some of the subcharts, give their names, aliases or scopes, which
+may be glob patterns, to --only-subcharts. The charts given to the command are
+still linted, unless --skip-root is set:
+
+    $ helm lint --only-subcharts 'redis,charts/*/charts/common' --skip-root ./umbrella
+
+By default, the command exits with 1 when the lint fails and 0 otherwise. With
+--exit-code, pipelines can tell the outcomes apart by the exit code, which
+reflects the most severe finding whether or not it fails the lint:
+
+    0  no warnings or errors were found
+    1  warnings were found, but no errors
+    2  errors were found
+    3  the charts couldn't be linted, e.g. a chart couldn't be loaded
 `
 
-func newLintCmd(out io.Writer) *cobra.Command {
+func newLintCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewLint()
+	client.Config = cfg
 	valueOpts := &values.Options{}
-	var kubeVersion string
+	var kubeVersions []string
+	var capabilitiesFile string
+	var warnValueOverrides bool
+	var policyFiles []string
+	var maxWarnings int
+	var cacheDir string
+	var groupBy string
+	var groupMessages bool
+	var renderCacheDir string
+	var metricsFile string
+	var junitFile string
+	var escalateThresholds []string
+	var scopeNamespaces []string
+	var rulesConfig string
+	var outfmt output.Format
+	var rulesBundle, rulesBundleKeyring string
+	var score bool
+	var compareTo, compareThreshold string
+	var workers int
+	var severity string
+	var noColor bool
+	var exitCode bool
+	var fromRelease string
+	var reuseValuesFrom string
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
 		Short: "examine a chart for possible issues",
 		Long:  longLintHelp,
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			// With --exit-code, the exit code tells the lints which found
+			// warnings or errors, or which couldn't lint the charts, apart.
+			exit := &lintExit{}
+			if exitCode {
+				defer func() { err = exit.wrap(cmd, err) }()
+			}
+
 			paths := []string{"."}
 			if len(args) > 0 {
 				paths = args
 			}
+			if fromRelease != "" && len(args) > 0 {
+				return errors.New("--from-release can't be combined with chart paths")
+			}
+			if fromRelease != "" && reuseValuesFrom != "" {
+				return errors.New("--reuse-values-from can't be combined with --from-release, which lints with the values of the release already")
+			}
 
-			if kubeVersion != "" {
+			if capabilitiesFile != "" {
+				bundleKubeVersion, apiVersions, err := readCapabilitiesFile(capabilitiesFile)
+				if err != nil {
+					return errors.Wrapf(err, "unable to read capabilities file %s", capabilitiesFile)
+				}
+				client.KubeVersion = bundleKubeVersion
+				client.APIVersions = apiVersions
+			}
+
+			for _, kubeVersion := range kubeVersions {
 				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
 				if err != nil {
 					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
 				}
 				client.KubeVersion = parsedKubeVersion
+				client.KubeVersions = append(client.KubeVersions, parsedKubeVersion)
+			}
+
+			if groupBy != "chart" && groupBy != "rule" {
+				return errors.Errorf("invalid --group-by value %q, must be one of: chart, rule", groupBy)
+			}
+			if groupMessages && groupBy == "rule" {
+				return errors.New("--group-messages can't be combined with --group-by rule")
+			}
+
+			if rulesConfig != "" {
+				config, err := rules.LoadRulesConfig(rulesConfig)
+				if err != nil {
+					return err
+				}
+				client.RulesConfig = config
+			}
+
+			if rulesBundle != "" {
+				data, err := readRulesBundle(rulesBundle, getter.All(settings))
+				if err != nil {
+					return errors.Wrapf(err, "unable to download rules bundle %s", rulesBundle)
+				}
+				bundle, err := lint.LoadRulesBundle(data, rulesBundleKeyring)
+				if err != nil {
+					return err
+				}
+				client.RulesBundle = bundle
+			}
+
+			for _, t := range escalateThresholds {
+				rule, n, ok := strings.Cut(t, "=")
+				threshold, err := strconv.Atoi(n)
+				if !ok || rule == "" || err != nil || threshold < 0 {
+					return errors.Errorf("invalid --escalate-threshold %q, must be RULE=N with N >= 0", t)
+				}
+				if client.EscalateThresholds == nil {
+					client.EscalateThresholds = map[string]int{}
+				}
+				client.EscalateThresholds[rule] = threshold
+			}
+
+			for _, n := range scopeNamespaces {
+				scope, namespace, ok := strings.Cut(n, "=")
+				if !ok || scope == "" || namespace == "" {
+					return errors.Errorf("invalid --scope-namespace %q, must be SCOPE=NAMESPACE", n)
+				}
+				if client.ScopeNamespaces == nil {
+					client.ScopeNamespaces = map[string]string{}
+				}
+				client.ScopeNamespaces[scope] = namespace
+			}
+
+			if client.UpdateSnapshot && client.Snapshot == "" {
+				return errors.New("--update-snapshot requires --snapshot")
+			}
+			if client.ConventionsOnly && client.Snapshot != "" {
+				return errors.New("--snapshot can't be used with --conventions-only, which doesn't render the templates")
+			}
+
+			var previous *lintReport
+			if compareTo != "" {
+				if severityRank(compareThreshold) == 0 {
+					return errors.Errorf("invalid --compare-threshold %q, must be one of: info, warning, error", compareThreshold)
+				}
+				report, err := loadLintReport(compareTo)
+				if err != nil {
+					return err
+				}
+				previous = report
+			}
+
+			if workers < 1 {
+				return errors.Errorf("invalid --lint-workers value %d, must be at least 1", workers)
+			}
+			if client.Timeout < 0 {
+				return errors.Errorf("invalid --timeout value %s, must not be negative", client.Timeout)
+			}
+			if client.MaxFindings < 0 {
+				return errors.Errorf("invalid --max-findings value %d, must be at least 0", client.MaxFindings)
+			}
+			if len(client.OnlySubcharts) > 0 {
+				client.WithSubcharts = true
+			}
+			if client.SkipRoot && !client.WithSubcharts {
+				return errors.New("--skip-root requires --with-subcharts or --only-subcharts")
+			}
+			if client.SubchartValues && !client.WithSubcharts {
+				return errors.New("--subchart-values requires --with-subcharts or --only-subcharts")
+			}
+			if client.WarnUnusedValues && !client.WithSubcharts {
+				return errors.New("--warn-unused-values requires --with-subcharts or --only-subcharts")
+			}
+			if client.LintEachAlias && !client.WithSubcharts {
+				return errors.New("--lint-each-alias requires --with-subcharts or --only-subcharts")
+			}
+			if cmd.Flags().Changed("subchart-dir") && !client.WithSubcharts {
+				return errors.New("--subchart-dir requires --with-subcharts or --only-subcharts")
+			}
+
+			if severity != "" {
+				client.FailSeverity = severityRank(severity)
+				if client.FailSeverity == support.UnknownSev {
+					return errors.Errorf("invalid --severity %q, must be one of: info, warning, error", severity)
+				}
+				if client.Strict && client.FailSeverity != support.WarningSev {
+					return errors.Errorf("--strict is the same as --severity=warning, it can't be combined with --severity=%s", severity)
+				}
+			}
+
+			if client.Strict && maxWarnings >= 0 {
+				warning("--strict fails on any warning, --max-warnings has no effect")
+			} else if client.FailSeverity != support.UnknownSev && client.FailSeverity <= support.WarningSev && maxWarnings >= 0 {
+				warning("--severity=%s fails on any warning, --max-warnings has no effect", severity)
 			}
 
-			if client.WithSubcharts {
-				for _, p := range paths {
-					filepath.Walk(filepath.Join(p, "charts"), func(path string, info os.FileInfo, _ error) error {
-						if info != nil {
-							if info.Name() == "Chart.yaml" {
-								paths = append(paths, filepath.Dir(path))
-							} else if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
-								paths = append(paths, path)
-							}
-						}
-						return nil
-					})
+			for _, f := range policyFiles {
+				policies, err := rules.LoadPolicies(f)
+				if err != nil {
+					return err
 				}
+				client.Policies = append(client.Policies, policies...)
 			}
 
+			if renderCacheDir != "" {
+				cfg.RenderCache = engine.NewDiskRenderCache(renderCacheDir)
+			}
+			if cacheDir != "" {
+				client.Cache = &action.LintCache{Dir: cacheDir}
+			}
 			client.Namespace = settings.Namespace()
-			vals, err := valueOpts.MergeValues(getter.All(settings))
+			vals, overrides, err := valueOpts.MergeValuesWithProvenance(getter.All(settings))
 			if err != nil {
 				return err
 			}
+			if reuseValuesFrom != "" {
+				releaseVals, err := releaseValues(cfg, reuseValuesFrom)
+				if err != nil {
+					return err
+				}
+				// The values given to the lint take precedence over those
+				// of the release, as with helm upgrade --reuse-values.
+				vals = chartutil.CoalesceTables(vals, releaseVals)
+			}
 
 			var message strings.Builder
-			failed := 0
-			errorsOrWarnings := 0
+			warnings := 0
+			errorCount, infos := 0, 0
+			findings := ruleFindings{}
+			var scores []string
+			report := &lintReport{groupByRule: groupBy == "rule"}
+			current := &lintReport{}
+			metrics := &lintMetrics{}
+			failSeverity := support.ErrorSev
+			if client.Strict {
+				failSeverity = support.WarningSev
+			}
+			if client.FailSeverity != support.UnknownSev {
+				failSeverity = client.FailSeverity
+			}
+			// With --quiet, the info messages are hidden, unless they fail
+			// the lint.
+			hideInfos := client.Quiet && failSeverity > support.InfoSev
+			junit := newLintJUnit(failSeverity)
+			colors := lintColors(false)
+			if outfmt == output.Table {
+				colors = newLintColors(out, noColor)
+			}
+			var cached []string
+			truncated, dropped := false, 0
+
+			if warnValueOverrides && len(overrides) > 0 {
+				for _, o := range overrides {
+					err := fmt.Errorf("value is overridden by multiple values files: %s", strings.Join(o.Files, ", "))
+					if o.TypeChanged {
+						err = fmt.Errorf("value is a table in some values files and not in others: %s", strings.Join(o.Files, ", "))
+					}
+					msg := support.NewMessage(support.WarningSev, o.Key, err)
+					if outfmt != output.Table {
+						warning("%s", msg)
+						continue
+					}
+					fmt.Fprintf(&message, "%s\n", colors.message(msg))
+				}
+				if outfmt == output.Table {
+					fmt.Fprint(&message, "\n")
+				}
+			}
+
+			// Remote charts and the chart of a release are downloaded and
+			// extracted first, then linted like local ones.
+			remotes := remoteCharts{}
+			if fromRelease != "" || slices.ContainsFunc(paths, func(p string) bool { return isRemoteChart(p, getter.All(settings)) }) {
+				dir, err := os.MkdirTemp("", "helm-lint-")
+				if err != nil {
+					return errors.Wrap(err, "unable to create temp dir to fetch charts")
+				}
+				defer os.RemoveAll(dir)
+				if fromRelease != "" {
+					chartDir, releaseVals, err := fetchReleaseChart(cfg, fromRelease, dir)
+					if err != nil {
+						return err
+					}
+					remotes[chartDir] = releaseChartRef(fromRelease)
+					paths = []string{chartDir}
+					// The values given to the lint take precedence over
+					// those of the release.
+					vals = chartutil.CoalesceTables(vals, releaseVals)
+				} else if paths, err = remotes.fetch(cfg, paths, dir, client.Timeout); err != nil {
+					return err
+				}
+			}
+
+			client.Workers = workers
+			if len(client.OnlySubcharts) > 0 {
+				_, unmatched, err := client.ScopedCharts(paths)
+				if err != nil {
+					return err
+				}
+				for _, pattern := range unmatched {
+					warning("--only-subcharts %q matches no subchart", pattern)
+				}
+			}
+			results, err := client.RunScoped(paths, vals)
+			if err != nil {
+				return err
+			}
+			exit.linted = true
+			scoped := action.NewScopedLintReport(results)
 
-			for _, path := range paths {
-				result := client.Run([]string{path}, vals)
+			for _, r := range scoped.Results {
+				path, config, result := remotes.path(r.Path), r.RulesConfig, r.Result
+				// With several --kube-version flags, every chart is linted
+				// once per version, and each result is labeled with it.
+				label, kubeVersion := path, ""
+				// A subchart used under several aliases is linted once for
+				// all of them, or once for each with --lint-each-alias.
+				if r.Alias != "" {
+					label = fmt.Sprintf("%s (as %s)", label, r.Alias)
+				} else if len(r.Aliases) > 1 {
+					label = fmt.Sprintf("%s (as %s)", label, strings.Join(r.Aliases, ", "))
+				}
+				if r.KubeVersion != nil {
+					kubeVersion = r.KubeVersion.String()
+					label = fmt.Sprintf("%s (Kubernetes %s)", label, kubeVersion)
+				}
+				metrics.add(label, result)
+				exit.add(result)
+				junit.add(path, r.Name, r.Scope, kubeVersion, result)
+				for _, c := range result.CachedCharts {
+					cached = append(cached, remotes.path(c))
+				}
+				truncated = truncated || result.Truncated
+				dropped += result.Dropped
+
+				for _, msg := range result.Messages {
+					if msg.Severity == support.WarningSev {
+						warnings++
+					}
+					if msg.Severity == support.ErrorSev {
+						errorCount++
+					}
+					if msg.Severity == support.InfoSev && !hideInfos {
+						infos++
+					}
+				}
+				if previous != nil {
+					current.add(path, r, false)
+					continue
+				}
+				// If all the messages of the chart are hidden by the quiet
+				// flag, and it has no errors, go to the next chart.
+				shown := sortMessages(result.Messages)
+				if hideInfos {
+					shown = slices.DeleteFunc(shown, func(msg support.Message) bool { return msg.Severity == support.InfoSev })
+				}
+				if hideInfos && len(shown) == 0 && len(result.Errors) == 0 {
+					continue
+				}
 
-				// If there is no errors/warnings and quiet flag is set
-				// go to the next chart
-				hasWarningsOrErrors := action.HasWarningsOrErrors(result)
-				if hasWarningsOrErrors {
-					errorsOrWarnings++
+				var scoreConfig *rules.ScoreConfig
+				if config != nil {
+					scoreConfig = config.Score
+				}
+				chartScore := lint.Score(result.Messages, scoreConfig)
+				if outfmt != output.Table {
+					report.add(path, r, hideInfos)
+					if score {
+						report.addScore(label, chartScore)
+					}
+					continue
 				}
-				if client.Quiet && !hasWarningsOrErrors {
+				if groupBy == "rule" {
+					findings.add(label, result, hideInfos, colors)
+					if score {
+						scores = append(scores, fmt.Sprintf("%s: %d/100", label, chartScore))
+					}
 					continue
 				}
 
-				fmt.Fprintf(&message, "==> Linting %s\n", path)
+				fmt.Fprintf(&message, "==> Linting %s\n", label)
 
 				// All the Errors that are generated by a chart
 				// that failed a lint will be included in the
 				// results.Messages so we only need to print
-				// the Errors if there are no Messages.
-				if len(result.Messages) == 0 {
+				// the Errors if no Messages are shown.
+				if len(shown) == 0 && !result.Truncated {
 					for _, err := range result.Errors {
-						fmt.Fprintf(&message, "Error %s\n", err)
+						fmt.Fprintf(&message, "%s\n", colors.paint(support.ErrorSev, fmt.Sprintf("Error %s", err)))
 					}
 				}
 
-				for _, msg := range result.Messages {
-					if !client.Quiet || msg.Severity > support.InfoSev {
-						fmt.Fprintf(&message, "%s\n", msg)
+				if groupMessages {
+					for _, g := range groupLintMessages(shown) {
+						fmt.Fprintf(&message, "%s\n", colors.paint(g.Severity, g.String()))
+					}
+				} else {
+					for _, msg := range shown {
+						fmt.Fprintf(&message, "%s\n", colors.message(msg))
 					}
 				}
-
-				if len(result.Errors) != 0 {
-					failed++
+				if score {
+					fmt.Fprintf(&message, "Score: %d/100\n", chartScore)
 				}
 
 				// Adding extra new line here to break up the
@@ -131,13 +556,81 @@ func newLintCmd(out io.Writer) *cobra.Command {
 				fmt.Fprint(&message, "\n")
 			}
 
-			fmt.Fprint(out, message.String())
+			if junitFile != "" {
+				if err := junit.write(junitFile); err != nil {
+					return errors.Wrap(err, "unable to write JUnit report")
+				}
+			}
+
+			// With --compare-to, only the changes since the previous lint are
+			// printed, and only new findings fail the lint.
+			if previous != nil {
+				delta := compareLintReports(previous, current)
+				if err := delta.write(out, outfmt); err != nil {
+					return err
+				}
+				if metricsFile != "" {
+					if err := metrics.write(metricsFile); err != nil {
+						return errors.Wrap(err, "unable to write lint metrics")
+					}
+				}
+				if err := delta.check(compareThreshold); err != nil {
+					return err
+				}
+				if outfmt == output.Table {
+					fmt.Fprintln(out, delta.summary())
+				}
+				return nil
+			}
+
+			// With --quiet, the structured formats print nothing at all when
+			// there are no warnings or errors, like the table format.
+			if outfmt != output.Table {
+				report.Summary = lintSummary{ChartsLinted: len(scoped.Results), ChartsFailed: scoped.Failed, Warnings: warnings, Truncated: truncated, Dropped: dropped}
+				if !client.Quiet || scoped.ErrorsOrWarnings > 0 {
+					if err := report.write(out, outfmt); err != nil {
+						return err
+					}
+				}
+			} else {
+				findings.write(&message)
+				if len(scores) > 0 {
+					fmt.Fprintf(&message, "==> Scores\n%s\n\n", strings.Join(scores, "\n"))
+				}
+				if truncated {
+					fmt.Fprintf(&message, "==> Findings truncated after the first %d, %d more were dropped\n\n", client.MaxFindings, dropped)
+				}
+				fmt.Fprint(out, message.String())
+			}
+
+			if metricsFile != "" {
+				if err := metrics.write(metricsFile); err != nil {
+					return errors.Wrap(err, "unable to write lint metrics")
+				}
+			}
 
-			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed", len(paths), failed)
-			if failed > 0 {
+			// The info messages hidden by --quiet aren't counted either, and
+			// the summary is only printed when there are warnings or errors.
+			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed, %d error(s), %d warning(s)", len(scoped.Results), scoped.Failed, errorCount, warnings)
+			if maxWarnings >= 0 {
+				summary += fmt.Sprintf(" (max %d)", maxWarnings)
+			}
+			if !hideInfos {
+				summary += fmt.Sprintf(", %d info", infos)
+			}
+			if truncated {
+				summary += fmt.Sprintf(", findings truncated at %d with %d more dropped, so the counts are a lower bound", client.MaxFindings, dropped)
+			}
+			if len(cached) > 0 {
+				summary += fmt.Sprintf(", %d chart(s) unchanged since the cached lint: %s", len(cached), strings.Join(cached, ", "))
+			}
+			if scoped.Failed > 0 {
 				return errors.New(summary)
 			}
-			if !client.Quiet || errorsOrWarnings > 0 {
+			if maxWarnings >= 0 && warnings > maxWarnings {
+				return errors.Errorf("%s: too many warnings", summary)
+			}
+			if outfmt == output.Table && (!client.Quiet || errorCount+warnings > 0) {
 				fmt.Fprintln(out, summary)
 			}
 			return nil
@@ -146,10 +639,230 @@ func newLintCmd(out io.Writer) *cobra.Command {
 
 	f := cmd.Flags()
 	f.BoolVar(&client.Strict, "strict", false, "fail on lint warnings")
+	f.StringVar(&severity, "severity", "", "the lowest severity of the findings which fail the lint: info, warning or error. Defaults to error, or warning with --strict")
 	f.BoolVar(&client.WithSubcharts, "with-subcharts", false, "lint dependent charts")
-	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
-	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringSliceVar(&client.OnlySubcharts, "only-subcharts", []string{}, "lint only the subcharts whose name, alias or scope matches one of these glob patterns, implies --with-subcharts (can specify multiple or separate values with commas)")
+	f.StringArrayVar(&scopeNamespaces, "scope-namespace", []string{}, "render the subchart with this scope, alias or name in another namespace than --namespace, as SCOPE=NAMESPACE (can specify multiple)")
+	f.BoolVar(&client.SubchartValues, "subchart-values", false, "lint the subcharts with the values the values.yaml files of their parents set for them too, validating them against the schemas of the subcharts")
+	f.BoolVar(&client.WarnUnusedValues, "warn-unused-values", false, "warn about the values a subchart receives which are not defined in its values.yaml or values.schema.json")
+	f.BoolVar(&client.LintDisabledSubcharts, "lint-disabled-subcharts", false, "also lint the subcharts disabled by the condition or tags of their dependency")
+	f.StringVar(&client.SubchartDir, "subchart-dir", "charts", "the directory of the charts, and of their subcharts, to find the subcharts in with --with-subcharts")
+	f.BoolVar(&client.LintEachAlias, "lint-each-alias", false, "lint the subcharts used under several aliases once for each alias, with its values, instead of once for all of them")
+	f.BoolVar(&client.SkipRoot, "skip-root", false, "don't lint the charts given to the command, only their subcharts")
+	f.DurationVar(&client.Timeout, "timeout", 0, "fail on the charts which take longer than this to download, load and lint, e.g. on a stalled network mount. 0 for no limit")
+	f.IntVar(&workers, "lint-workers", runtime.GOMAXPROCS(0), "number of charts linted concurrently")
+	f.IntVar(&client.MaxFindings, "max-findings", 0, "stop collecting findings after this number across all charts, 0 for no limit. Errors found later still fail the lint")
+	f.IntVar(&maxWarnings, "max-warnings", -1, "fail if more than this number of warnings are found across all charts, -1 for no limit")
+	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings, errors and the findings which fail the lint, and the summary only when there are warnings or errors")
+	f.BoolVar(&exitCode, "exit-code", false, "exit with 1 when warnings are found, even if they don't fail the lint, 2 when errors are found and 3 when the charts can't be linted")
+	f.StringVar(&fromRelease, "from-release", "", "lint the chart the named release was installed or upgraded with, using the values of the release")
+	f.StringVar(&reuseValuesFrom, "reuse-values-from", "", "lint the charts with the values the named release was installed or upgraded with, overridden by the values given to the lint")
+	f.BoolVar(&noColor, "no-color", false, "don't color the findings by severity when printing them to a terminal. Colors are also disabled by the NO_COLOR environment variable")
+	f.StringVar(&groupBy, "group-by", "chart", "group the findings by \"chart\" or by \"rule\"")
+	f.BoolVar(&groupMessages, "group-messages", false, "print the findings of a chart with the same severity and text once, with their count and paths. The counts of the summary and exit status are not affected")
+	f.StringArrayVar(&kubeVersions, "kube-version", []string{}, "Kubernetes version used for capabilities and deprecation checks. Can be repeated to lint the charts once for each version")
+	f.StringVar(&capabilitiesFile, "capabilities-file", "", "render with the API versions listed in this file, or with the kube-version and api-versions.txt files of this directory")
+	f.BoolVar(&warnValueOverrides, "warn-value-overrides", false, "warn about keys set by more than one values file")
+	f.BoolVar(&client.ReportUnusedIgnores, "report-unused-ignores", false, "warn about ignore comments in templates which don't suppress any finding")
+	f.BoolVar(&client.EnableLookup, "enable-lookup", false, "query the configured Kubernetes cluster from the lookup function instead of rendering empty results")
+	f.BoolVar(&client.ValidateCRDs, "validate-crds", false, "validate custom resources against the schemas of the CustomResourceDefinitions installed in the configured Kubernetes cluster")
+	f.StringVar(&cacheDir, "cache-dir", "", "reuse lint results stored in this directory for unchanged charts and values")
+	f.BoolVar(&client.Force, "force", false, "lint all charts again instead of reusing the results stored in --cache-dir")
+	f.StringVar(&renderCacheDir, "render-cache-dir", "", "store the rendered templates in this directory for reuse by 'helm template' with the release name given to --render-release-name")
+	f.StringVar(&client.ReleaseName, "render-release-name", "test-release", "the name of the release the templates are rendered for")
+	f.StringVar(&junitFile, "junit-file", "", "write a JUnit XML report to this file, with a test suite per chart and a test case per finding, failing for the findings which fail the lint")
+	f.StringVar(&metricsFile, "metrics-file", "", "write the number of findings per chart and severity to this file, in the Prometheus text format")
+	f.StringVar(&client.Snapshot, "snapshot", "", "fail if the rendered templates differ from the snapshot stored in this file")
+	f.BoolVar(&client.UpdateSnapshot, "update-snapshot", false, "rewrite the file given to --snapshot with the rendered templates")
+	f.StringSliceVar(&client.SkipRules, "skip-rules", []string{}, "drop the findings of the rules with these IDs, or whose path starts with one of them, e.g. app-version or templates (can specify multiple or separate values with commas)")
+	f.StringArrayVar(&escalateThresholds, "escalate-threshold", []string{}, "raise the severity of a rule's findings by one level when it is found more than N times in a chart, as RULE=N (can specify multiple)")
+	f.BoolVar(&client.SchemaStrict, "schema-strict", false, "report each value which violates the values.schema.json of a chart as an error of its own, with the JSON path of the value and the reason")
+	f.BoolVar(&client.StrictRender, "strict-render", false, "fail the render on references to values which are not defined instead of rendering them as empty")
+	f.BoolVar(&client.ExpandValueTemplates, "expand-value-templates", false, "run the string values containing template syntax through tpl before rendering, so errors in them are reported")
+	f.BoolVar(&client.ConventionsOnly, "conventions-only", false, "only check the Helm conventions of the charts, such as their metadata, values, template sources, helper names and NOTES.txt, without rendering them")
+	f.BoolVar(&client.Style, "style", false, "check the indentation, trailing whitespace and final newlines of the YAML files of the charts, with the indent set in --rules-config or 2 spaces")
+	f.BoolVar(&client.RenderedCount, "show-rendered-count", false, "report the number of Kubernetes objects the templates of each chart render, by kind, as an info")
+	f.BoolVar(&client.Questions, "questions", false, "check that the questions.yaml of charts targeting Rancher only asks for values defined in values.yaml, with the same defaults")
+	f.BoolVar(&score, "score", false, "print a quality score from 0 to 100 for every chart, computed from its findings with the weights set in --rules-config")
+	f.StringVar(&compareTo, "compare-to", "", "only report the findings which are new or resolved since the lint result stored in this file by '-o json', failing only on new findings")
+	f.StringVar(&compareThreshold, "compare-threshold", "warning", "with --compare-to, the lowest severity of new findings which fails the lint: info, warning or error")
+	f.StringVar(&rulesConfig, "rules-config", "", "configure the rules which are off by default, such as image-registry, from this YAML file")
+	f.StringVar(&rulesBundle, "rules-bundle", "", "apply the severity overrides, ignores, rules config and policies of the signed rules bundle at this URL or path")
+	f.StringVar(&rulesBundleKeyring, "rules-bundle-keyring", defaultKeyring(), "keyring containing the public keys the rules bundle may be signed with")
+	f.StringArrayVar(&policyFiles, "policy", []string{}, "evaluate the CEL policies defined in a file against every rendered object (can specify multiple)")
 	addValueOptionsFlags(f, valueOpts)
+	bindLintOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
+
+// readRulesBundle downloads the rules bundle with the getter for the scheme of
+// location, or reads it from the disk when no getter supports it.
+func readRulesBundle(location string, p getter.Providers) ([]byte, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	g, err := p.ByScheme(u.Scheme)
+	if err != nil {
+		return os.ReadFile(location)
+	}
+	data, err := g.Get(location, getter.WithURL(location))
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// readCapabilitiesFile reads the capabilities of a cluster. A file lists the
+// API versions the cluster serves, one per line. A directory holds them in
+// api-versions.txt, and the Kubernetes version in an optional kube-version
+// file, so a bundle describes the cluster as 'kubectl version' and 'kubectl
+// api-versions' report it.
+func readCapabilitiesFile(location string) (*chartutil.KubeVersion, chartutil.VersionSet, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		apiVersions, err := readAPIVersions(location)
+		return nil, apiVersions, err
+	}
+	apiVersions, err := readAPIVersions(filepath.Join(location, "api-versions.txt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(location, "kube-version"))
+	if os.IsNotExist(err) {
+		return nil, apiVersions, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeVersion, err := chartutil.ParseKubeVersion(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid kube-version")
+	}
+	return kubeVersion, apiVersions, nil
+}
+
+// readAPIVersions reads a list of API versions, skipping blank lines and
+// comments starting with '#'.
+func readAPIVersions(filename string) (chartutil.VersionSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	apiVersions := chartutil.VersionSet{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		apiVersions = append(apiVersions, line)
+	}
+	if len(apiVersions) == 0 {
+		return nil, errors.Errorf("%s lists no API versions", filename)
+	}
+	return apiVersions, nil
+}
+
+// ruleFindings collects the findings of several charts by rule ID.
+type ruleFindings map[string][]string
+
+func (f ruleFindings) add(path string, result *action.LintResult, quiet bool, colors lintColors) {
+	// As when grouping by chart, the Errors only need to be printed when
+	// there are no Messages.
+	if len(result.Messages) == 0 {
+		for _, err := range result.Errors {
+			f[""] = append(f[""], fmt.Sprintf("%s: %s", path, colors.paint(support.ErrorSev, fmt.Sprintf("Error %s", err))))
+		}
+	}
+	for _, msg := range result.Messages {
+		if !quiet || msg.Severity > support.InfoSev {
+			f[msg.RuleID] = append(f[msg.RuleID], fmt.Sprintf("%s: %s", path, colors.message(msg)))
+		}
+	}
+}
+
+// write prints the findings sorted by rule ID, followed by the findings
+// which don't belong to a rule.
+func (f ruleFindings) write(out io.Writer) {
+	ids := make([]string, 0, len(f))
+	for id := range f {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if _, ok := f[""]; ok {
+		ids = append(ids, "")
+	}
+
+	for _, id := range ids {
+		if id == "" {
+			fmt.Fprintln(out, "==> Other findings")
+		} else {
+			fmt.Fprintf(out, "==> Rule %s\n", id)
+		}
+		for _, finding := range f[id] {
+			fmt.Fprintln(out, finding)
+		}
+		fmt.Fprint(out, "\n")
+	}
+}
+
+// sortMessages returns a copy of the messages of a chart with the most severe
+// first, and those of the same severity by path, keeping the order in which
+// they were found otherwise.
+func sortMessages(messages []support.Message) []support.Message {
+	sorted := make([]support.Message, len(messages))
+	copy(sorted, messages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Severity != sorted[j].Severity {
+			return sorted[i].Severity > sorted[j].Severity
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted
+}
+
+// lintMessageGroup is a finding of a chart reported for one or more paths,
+// which --group-messages prints once.
+type lintMessageGroup struct {
+	support.Message
+	Paths []string
+}
+
+// groupLintMessages groups messages with the same severity and text, in the
+// order of their first message.
+func groupLintMessages(messages []support.Message) []*lintMessageGroup {
+	type groupKey struct {
+		severity int
+		text     string
+	}
+	var groups []*lintMessageGroup
+	byKey := map[groupKey]*lintMessageGroup{}
+	for _, msg := range messages {
+		k := groupKey{msg.Severity, msg.Err.Error()}
+		if g, ok := byKey[k]; ok {
+			g.Paths = append(g.Paths, msg.Path)
+			continue
+		}
+		g := &lintMessageGroup{Message: msg, Paths: []string{msg.Path}}
+		byKey[k] = g
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// String prints a group of a single message like the message, and a larger
+// one with its count and paths, such as:
+//
+//	[WARNING] missing resources limits (3x): templates/cron.yaml, templates/deployment.yaml, templates/job.yaml
+func (g *lintMessageGroup) String() string {
+	if len(g.Paths) == 1 {
+		return g.Message.Error()
+	}
+	return fmt.Sprintf("[%s] %s (%dx): %s", strings.ToUpper(severityLabels[g.Severity]), g.Err, len(g.Paths), strings.Join(g.Paths, ", "))
+}
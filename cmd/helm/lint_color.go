@@ -0,0 +1,64 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/term"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// severityColors are the ANSI escape sequences the findings of each severity
+// are printed with.
+var severityColors = map[int]string{
+	support.InfoSev:    "\x1b[2m",
+	support.WarningSev: "\x1b[33m",
+	support.ErrorSev:   "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// lintColors reports whether the findings printed as a table are colored by
+// severity.
+type lintColors bool
+
+// newLintColors enables colors when out is a terminal, unless noColor or the
+// NO_COLOR environment variable is set.
+func newLintColors(out io.Writer, noColor bool) lintColors {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return lintColors(ok && term.IsTerminal(int(f.Fd())))
+}
+
+// paint returns text in the color of severity, if colors are enabled.
+func (c lintColors) paint(severity int, text string) string {
+	color, ok := severityColors[severity]
+	if !bool(c) || !ok {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// message formats msg as its Error method does, in the color of its severity.
+func (c lintColors) message(msg support.Message) string {
+	return c.paint(msg.Severity, msg.Error())
+}
@@ -0,0 +1,43 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestLintColors(t *testing.T) {
+	if newLintColors(&bytes.Buffer{}, false) {
+		t.Error("Expected no colors when the output is not a terminal")
+	}
+
+	msg := support.NewMessage(support.ErrorSev, "templates/", errors.New("broken"))
+	if got, want := lintColors(true).message(msg), "\x1b[31m[ERROR] templates/: broken\x1b[0m"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	msg.Severity = support.InfoSev
+	if got, want := lintColors(true).message(msg), "\x1b[2m[INFO] templates/: broken\x1b[0m"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got, want := lintColors(false).message(msg), "[INFO] templates/: broken"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
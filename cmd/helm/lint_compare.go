@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/cli/output"
+)
+
+// lintChange is a finding which is new or resolved since a previous lint.
+type lintChange struct {
+	Chart    string `json:"chart"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Rule     string `json:"rule,omitempty"`
+	Text     string `json:"text"`
+
+	fingerprint string
+}
+
+func (c lintChange) String() string {
+	if c.Path == "" {
+		return fmt.Sprintf("%s: [%s] %s", c.Chart, strings.ToUpper(c.Severity), c.Text)
+	}
+	return fmt.Sprintf("%s: [%s] %s: %s", c.Chart, strings.ToUpper(c.Severity), c.Path, c.Text)
+}
+
+// key identifies a finding across runs. The severity is not part of it, so
+// a finding whose severity changed is neither new nor resolved.
+func (c lintChange) key() string {
+	return strings.Join([]string{c.Chart, c.Path, c.Rule, c.Text}, "\x00")
+}
+
+// lintDelta is the lint output with --compare-to.
+type lintDelta struct {
+	New      []lintChange `json:"new"`
+	Resolved []lintChange `json:"resolved"`
+}
+
+// loadLintReport reads a report written by 'helm lint -o json' or
+// '-o yaml', grouped by chart or by rule.
+func loadLintReport(filename string) (*lintReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read previous lint result")
+	}
+	report := &lintReport{}
+	if err := yaml.Unmarshal(data, report); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse previous lint result %s", filename)
+	}
+	return report, nil
+}
+
+// changes flattens the findings of a report.
+func (r *lintReport) changes() []lintChange {
+	var changes []lintChange
+	for _, chart := range r.Results {
+		if len(chart.Messages) == 0 {
+			for _, err := range chart.Errors {
+				changes = append(changes, lintChange{Chart: chart.Path, Severity: "error", Text: err, fingerprint: lintFingerprint(chart.Path, chart.Name, chart.Scope, "", "", err)})
+			}
+		}
+		for _, msg := range chart.Messages {
+			changes = append(changes, lintChange{Chart: chart.Path, Severity: msg.Severity, Path: msg.Path, Rule: msg.Rule, Text: msg.Text, fingerprint: msg.Fingerprint})
+		}
+	}
+	for _, rule := range r.Rules {
+		for _, f := range rule.Findings {
+			changes = append(changes, lintChange{Chart: f.Chart, Severity: f.Severity, Path: f.Path, Rule: rule.Rule, Text: f.Text, fingerprint: f.Fingerprint})
+		}
+	}
+	return changes
+}
+
+// hasFingerprints reports whether all the changes carry a fingerprint. The
+// reports written before fingerprints were added don't.
+func hasFingerprints(changes []lintChange) bool {
+	for _, c := range changes {
+		if c.fingerprint == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// compareLintReports returns the findings of current which are not in
+// previous, and those of previous which are not in current. The findings are
+// matched by fingerprint if both reports have them.
+func compareLintReports(previous, current *lintReport) *lintDelta {
+	delta := &lintDelta{New: []lintChange{}, Resolved: []lintChange{}}
+	before, after := previous.changes(), current.changes()
+	key := lintChange.key
+	if hasFingerprints(before) && hasFingerprints(after) {
+		key = func(c lintChange) string { return c.fingerprint }
+	}
+	seen := map[string]int{}
+	for _, c := range before {
+		seen[key(c)]++
+	}
+	for _, c := range after {
+		if seen[key(c)] > 0 {
+			seen[key(c)]--
+			continue
+		}
+		delta.New = append(delta.New, c)
+	}
+	remaining := map[string]int{}
+	for _, c := range after {
+		remaining[key(c)]++
+	}
+	for _, c := range before {
+		if remaining[key(c)] > 0 {
+			remaining[key(c)]--
+			continue
+		}
+		delta.Resolved = append(delta.Resolved, c)
+	}
+	return delta
+}
+
+func (d *lintDelta) write(out io.Writer, format output.Format) error {
+	switch format {
+	case output.Table:
+		for _, section := range []struct {
+			title   string
+			changes []lintChange
+		}{{"New findings", d.New}, {"Resolved findings", d.Resolved}} {
+			if len(section.changes) == 0 {
+				continue
+			}
+			fmt.Fprintf(out, "==> %s\n", section.title)
+			for _, c := range section.changes {
+				fmt.Fprintln(out, c)
+			}
+			fmt.Fprint(out, "\n")
+		}
+		return nil
+	case output.JSON:
+		return output.EncodeJSON(out, d)
+	case output.YAML:
+		return output.EncodeYAML(out, d)
+	case lintOutputGitHub:
+		// Only the new findings need the attention of a reviewer.
+		for _, c := range d.New {
+			writeGitHubCommand(out, c.Severity, c.Chart, c.Path, c.Rule, c.Text)
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported lint output format %q", format)
+}
+
+// check fails if any new finding is at least as severe as threshold.
+func (d *lintDelta) check(threshold string) error {
+	failing := 0
+	for _, c := range d.New {
+		if severityRank(c.Severity) >= severityRank(threshold) {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return errors.Errorf("%s, %d new finding(s) at or above %s", d.summary(), failing, threshold)
+	}
+	return nil
+}
+
+// summary describes the delta for the table output.
+func (d *lintDelta) summary() string {
+	return fmt.Sprintf("%d new finding(s), %d resolved finding(s)", len(d.New), len(d.Resolved))
+}
+
+func severityRank(label string) int {
+	for i, l := range severityLabels {
+		if l == label {
+			return i
+		}
+	}
+	return 0
+}
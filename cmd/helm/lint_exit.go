@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// The exit codes of 'helm lint --exit-code'.
+const (
+	// lintExitClean is returned when no warnings or errors were found.
+	lintExitClean = 0
+	// lintExitWarnings is returned when warnings but no errors were found,
+	// or when the lint failed on infos with --severity info.
+	lintExitWarnings = 1
+	// lintExitErrors is returned when errors were found.
+	lintExitErrors = 2
+	// lintExitFailure is returned when the charts couldn't be linted, such
+	// as for invalid flags, or charts which can't be loaded.
+	lintExitFailure = 3
+)
+
+// lintExitError makes helm exit with code rather than 1.
+type lintExitError struct {
+	error
+	code int
+}
+
+// lintExit tracks the findings of a lint to choose its exit code.
+type lintExit struct {
+	// linted is set once the charts were linted.
+	linted bool
+	// loadFailed is set when a chart failed without any finding, as it
+	// couldn't be loaded.
+	loadFailed bool
+	highest    int
+}
+
+func (e *lintExit) add(result *action.LintResult) {
+	if len(result.Messages) == 0 && len(result.Errors) > 0 && !result.Truncated {
+		e.loadFailed = true
+	}
+	// The messages dropped by --max-findings count as well.
+	if result.DroppedSeverity > e.highest {
+		e.highest = result.DroppedSeverity
+	}
+	for _, msg := range result.Messages {
+		if msg.Severity > e.highest {
+			e.highest = msg.Severity
+		}
+	}
+}
+
+// code returns the exit code of a lint which returned err.
+func (e *lintExit) code(err error) int {
+	switch {
+	case !e.linted || e.loadFailed:
+		return lintExitFailure
+	case e.highest >= support.ErrorSev:
+		return lintExitErrors
+	case e.highest == support.WarningSev || err != nil:
+		return lintExitWarnings
+	}
+	return lintExitClean
+}
+
+// wrap returns err with the exit code of the lint. A lint which found
+// warnings without failing only prints its summary, not an error.
+func (e *lintExit) wrap(cmd *cobra.Command, err error) error {
+	code := e.code(err)
+	if code == lintExitClean {
+		return err
+	}
+	if err == nil {
+		cmd.SilenceErrors = true
+		err = errors.New("warnings were found")
+	}
+	return lintExitError{error: err, code: code}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// lintJUnit collects the results of linting several charts as a JUnit XML
+// report. Each chart is a test suite and each message a test case, which
+// fails if the message fails the lint.
+type lintJUnit struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+	// failSeverity is the lowest severity of the messages reported as
+	// failures.
+	failSeverity int
+}
+
+type junitTestSuite struct {
+	Name  string `xml:"name,attr"`
+	Chart string `xml:"chart,attr,omitempty"`
+	Scope string `xml:"scope,attr,omitempty"`
+	// KubeVersion is the Kubernetes version the chart was linted for, when
+	// linting for several versions.
+	KubeVersion string          `xml:"kube-version,attr,omitempty"`
+	Tests       int             `xml:"tests,attr"`
+	Failures    int             `xml:"failures,attr"`
+	Cases       []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func newLintJUnit(failSeverity int) *lintJUnit {
+	return &lintJUnit{Name: "helm lint", failSeverity: failSeverity}
+}
+
+func (j *lintJUnit) add(chartPath, name, scope, kubeVersion string, result *action.LintResult) {
+	suite := junitTestSuite{Name: chartPath, Chart: name, Scope: scope, KubeVersion: kubeVersion}
+	if kubeVersion != "" {
+		suite.Name = fmt.Sprintf("%s (Kubernetes %s)", chartPath, kubeVersion)
+	}
+	classname := name
+	if classname == "" {
+		classname = chartPath
+	}
+	// As in the other outputs, the Errors only need to be reported when
+	// there are no Messages.
+	if len(result.Messages) == 0 && !result.Truncated {
+		for _, err := range result.Errors {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "lint",
+				Classname: classname,
+				Failure:   &junitFailure{Message: err.Error(), Type: severityLabels[support.ErrorSev], Text: err.Error()},
+			})
+		}
+	}
+	for _, msg := range result.Messages {
+		tc := junitTestCase{Name: msg.RuleID, Classname: classname}
+		if tc.Name == "" {
+			tc.Name = msg.Err.Error()
+		}
+		if msg.Path != "" {
+			tc.File = chartPath + "/" + msg.Path
+		}
+		if msg.Severity >= j.failSeverity {
+			tc.Failure = &junitFailure{Message: msg.Err.Error(), Type: severityLabels[msg.Severity], Text: msg.Error()}
+		} else {
+			tc.SystemOut = msg.Error()
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	for _, tc := range suite.Cases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.Cases)
+	j.Tests += suite.Tests
+	j.Failures += suite.Failures
+	j.Suites = append(j.Suites, suite)
+}
+
+// write atomically replaces filename with the report.
+func (j *lintJUnit) write(filename string) error {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(j); err != nil {
+		return err
+	}
+	b.WriteString("\n")
+	return fileutil.AtomicWriteFile(filename, &b, 0644)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// lintMetrics collects the results of linting several charts for export in
+// the Prometheus text format, as read by the textfile collector of
+// node_exporter.
+type lintMetrics struct {
+	charts []chartLintMetrics
+}
+
+type chartLintMetrics struct {
+	chart    string
+	failed   bool
+	messages map[string]int
+}
+
+// severityLabels are the values of the severity label, indexed by severity.
+var severityLabels = []string{"unknown", "info", "warning", "error"}
+
+func (m *lintMetrics) add(chart string, result *action.LintResult) {
+	c := chartLintMetrics{chart: chart, failed: len(result.Errors) > 0, messages: map[string]int{}}
+	for _, msg := range result.Messages {
+		c.messages[severityLabels[msg.Severity]]++
+	}
+	m.charts = append(m.charts, c)
+}
+
+// write atomically replaces filename with the collected metrics, so the
+// collector never reads a partially written file.
+func (m *lintMetrics) write(filename string) error {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "# HELP helm_lint_messages_total Number of lint messages by chart and severity.")
+	fmt.Fprintln(&b, "# TYPE helm_lint_messages_total gauge")
+	for _, c := range m.charts {
+		for sev := support.InfoSev; sev <= support.ErrorSev; sev++ {
+			fmt.Fprintf(&b, "helm_lint_messages_total{chart=\"%s\",severity=\"%s\"} %d\n", escapeLabelValue(c.chart), severityLabels[sev], c.messages[severityLabels[sev]])
+		}
+	}
+	fmt.Fprintln(&b, "# HELP helm_lint_chart_failed Whether linting the chart failed.")
+	fmt.Fprintln(&b, "# TYPE helm_lint_chart_failed gauge")
+	for _, c := range m.charts {
+		failed := 0
+		if c.failed {
+			failed = 1
+		}
+		fmt.Fprintf(&b, "helm_lint_chart_failed{chart=\"%s\"} %d\n", escapeLabelValue(c.chart), failed)
+	}
+	return fileutil.AtomicWriteFile(filename, &b, 0644)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
@@ -0,0 +1,356 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// lintOutputGitHub prints the findings as GitHub Actions workflow commands,
+// which annotate the files of a pull request.
+const lintOutputGitHub output.Format = "github"
+
+// bindLintOutputFlag binds the output flag of 'helm lint', which supports the
+// github format in addition to the common ones.
+func bindLintOutputFlag(cmd *cobra.Command, varRef *output.Format) {
+	formats := append(output.Formats(), lintOutputGitHub.String())
+	cmd.Flags().VarP((*lintOutputValue)(newOutputValue(output.Table, varRef)), outputFlag, "o",
+		fmt.Sprintf("prints the output in the specified format. Allowed values: %s", strings.Join(formats, ", ")))
+
+	err := cmd.RegisterFlagCompletionFunc(outputFlag, func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		var formatNames []string
+		for format, desc := range output.FormatsWithDesc() {
+			formatNames = append(formatNames, fmt.Sprintf("%s\t%s", format, desc))
+		}
+		formatNames = append(formatNames, fmt.Sprintf("%s\t%s", lintOutputGitHub, "Output result as GitHub Actions workflow commands"))
+
+		sort.Strings(formatNames)
+		return formatNames, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type lintOutputValue outputValue
+
+func (o *lintOutputValue) String() string {
+	return (*outputValue)(o).String()
+}
+
+func (o *lintOutputValue) Type() string {
+	return (*outputValue)(o).Type()
+}
+
+func (o *lintOutputValue) Set(s string) error {
+	if output.Format(s) == lintOutputGitHub {
+		*o = lintOutputValue(lintOutputGitHub)
+		return nil
+	}
+	if err := (*outputValue)(o).Set(s); err != nil {
+		return errors.Errorf("invalid format type %q, must be one of: %s, %s", s, strings.Join(output.Formats(), ", "), lintOutputGitHub)
+	}
+	return nil
+}
+
+// lintReport is the lint output in the structured formats. Depending on
+// --group-by, the findings are listed by chart or by rule.
+type lintReport struct {
+	Results []lintChartResult `json:"results,omitempty"`
+	Rules   []lintRuleResult  `json:"rules,omitempty"`
+	Scores  []lintScore       `json:"scores,omitempty"`
+	Summary lintSummary       `json:"summary"`
+
+	groupByRule bool
+}
+
+type lintChartResult struct {
+	// Name is the name in the Chart.yaml of the chart, if it can be read.
+	Name string `json:"name"`
+	// Scope is the location of the chart within the chart it was linted
+	// with as a subchart, e.g. charts/database, or . for the charts given
+	// on the command line.
+	Scope string `json:"scope"`
+	Path  string `json:"path"`
+	// KubeVersion is the Kubernetes version the chart was linted for, when
+	// linting for several versions.
+	KubeVersion string `json:"kube_version,omitempty"`
+	// Owner is the owner of the chart, when known, so an aggregator can
+	// route its findings.
+	Owner string `json:"owner,omitempty"`
+	// Aliases are the aliases the chart is used under, when there are
+	// several, and Alias the one it was linted for with --lint-each-alias.
+	Aliases  []string      `json:"aliases,omitempty"`
+	Alias    string        `json:"alias,omitempty"`
+	Messages []lintMessage `json:"messages"`
+	Errors   []string      `json:"errors"`
+	Failed   bool          `json:"failed"`
+	Score    *int          `json:"score,omitempty"`
+}
+
+type lintScore struct {
+	Chart string `json:"chart"`
+	Score int    `json:"score"`
+}
+
+type lintRuleResult struct {
+	// Rule is empty for the findings which don't belong to a rule.
+	Rule     string        `json:"rule"`
+	Findings []lintFinding `json:"findings"`
+}
+
+type lintFinding struct {
+	Chart       string `json:"chart"`
+	KubeVersion string `json:"kube_version,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Severity    string `json:"severity"`
+	Path        string `json:"path"`
+	Text        string `json:"text"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type lintMessage struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Rule     string `json:"rule,omitempty"`
+	Text     string `json:"text"`
+	// Fingerprint identifies the finding across runs, see lintFingerprint.
+	Fingerprint string `json:"fingerprint"`
+}
+
+type lintSummary struct {
+	ChartsLinted int `json:"charts_linted"`
+	ChartsFailed int `json:"charts_failed"`
+	Warnings     int `json:"warnings"`
+	// Truncated is set when --max-findings dropped findings, so the counts
+	// are a lower bound, and Dropped is the number of them.
+	Truncated bool `json:"truncated,omitempty"`
+	Dropped   int  `json:"dropped,omitempty"`
+}
+
+// add records the result of linting the chart at path, which is the path of
+// c as it is reported.
+func (r *lintReport) add(path string, c action.ScopedResult, quiet bool) {
+	name, scope, result := c.Name, c.Scope, c.Result
+	kubeVersion := ""
+	if c.KubeVersion != nil {
+		kubeVersion = c.KubeVersion.String()
+	}
+	chart := lintChartResult{
+		Name:        name,
+		Scope:       scope,
+		Path:        path,
+		KubeVersion: kubeVersion,
+		Owner:       c.Owner,
+		Aliases:     c.Aliases,
+		Alias:       c.Alias,
+		Messages:    []lintMessage{},
+		Errors:      []string{},
+		Failed:      len(result.Errors) != 0,
+	}
+	for _, err := range result.Errors {
+		chart.Errors = append(chart.Errors, err.Error())
+	}
+	// The same finding for several Kubernetes versions is told apart by the
+	// version.
+	fingerprintScope := scope
+	if c.Alias != "" {
+		fingerprintScope += "#" + c.Alias
+	}
+	if kubeVersion != "" {
+		fingerprintScope += "@" + kubeVersion
+	}
+	for _, msg := range result.Messages {
+		if !quiet || msg.Severity > support.InfoSev {
+			chart.Messages = append(chart.Messages, lintMessage{
+				Severity:    severityLabels[msg.Severity],
+				Path:        msg.Path,
+				Rule:        msg.RuleID,
+				Text:        msg.Err.Error(),
+				Fingerprint: lintFingerprint(path, name, fingerprintScope, msg.Path, msg.RuleID, msg.Err.Error()),
+			})
+		}
+	}
+
+	if !r.groupByRule {
+		r.Results = append(r.Results, chart)
+		return
+	}
+	// As in the table output, the Errors only need to be listed when there
+	// are no Messages.
+	if len(result.Messages) == 0 {
+		for _, err := range chart.Errors {
+			r.addFinding("", lintFinding{Chart: path, KubeVersion: kubeVersion, Owner: c.Owner, Severity: severityLabels[support.ErrorSev], Text: err, Fingerprint: lintFingerprint(path, name, fingerprintScope, "", "", err)})
+		}
+	}
+	for _, msg := range chart.Messages {
+		r.addFinding(msg.Rule, lintFinding{Chart: path, KubeVersion: kubeVersion, Owner: c.Owner, Severity: msg.Severity, Path: msg.Path, Text: msg.Text, Fingerprint: msg.Fingerprint})
+	}
+}
+
+// volatileLocations match the line and column numbers in the messages, which
+// shift as unrelated parts of a file change.
+var volatileLocations = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`\b(line|column) \d+`), "$1"},
+	{regexp.MustCompile(`(\.[a-z]+):\d+(:\d+)?`), "$1"},
+}
+
+// lintFingerprint identifies a finding across runs. It is a hash of the
+// chart's name and scope, the file, the rule and the message. The location
+// of the chart on disk and the line and column numbers in the message are
+// left out, so the fingerprint stays the same when they change.
+func lintFingerprint(chartPath, name, scope, file, rule, text string) string {
+	if chartPath != "" {
+		text = stripChartPath(text, chartPath)
+	}
+	for _, l := range volatileLocations {
+		text = l.pattern.ReplaceAllString(text, l.replace)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{name, scope, file, rule, text}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// stripChartPath removes chartPath from text where it is a whole path, or
+// the leading components of one, with the separator following it. Other
+// occurrences of it, such as the dots of the keys of values when linting
+// ".", are kept.
+func stripChartPath(text, chartPath string) string {
+	location := regexp.MustCompile(`(^|[^\w./\\-])` + regexp.QuoteMeta(filepath.Clean(chartPath)) + `([/\\]|[^\w./\\-]|$)`)
+	return location.ReplaceAllStringFunc(text, func(match string) string {
+		m := location.FindStringSubmatch(match)
+		if m[2] == "/" || m[2] == `\` {
+			return m[1]
+		}
+		return m[1] + m[2]
+	})
+}
+
+// addScore records the score of the chart added last.
+func (r *lintReport) addScore(path string, score int) {
+	if r.groupByRule {
+		r.Scores = append(r.Scores, lintScore{Chart: path, Score: score})
+		return
+	}
+	r.Results[len(r.Results)-1].Score = &score
+}
+
+func (r *lintReport) addFinding(rule string, finding lintFinding) {
+	for i := range r.Rules {
+		if r.Rules[i].Rule == rule {
+			r.Rules[i].Findings = append(r.Rules[i].Findings, finding)
+			return
+		}
+	}
+	r.Rules = append(r.Rules, lintRuleResult{Rule: rule, Findings: []lintFinding{finding}})
+}
+
+// write encodes the report in the given structured format. The rules are
+// sorted by ID, followed by the findings which don't belong to a rule.
+func (r *lintReport) write(out io.Writer, format output.Format) error {
+	sort.SliceStable(r.Rules, func(i, j int) bool {
+		if r.Rules[i].Rule == "" || r.Rules[j].Rule == "" {
+			return r.Rules[j].Rule == "" && r.Rules[i].Rule != ""
+		}
+		return r.Rules[i].Rule < r.Rules[j].Rule
+	})
+	switch format {
+	case output.JSON:
+		return output.EncodeJSON(out, r)
+	case output.YAML:
+		return output.EncodeYAML(out, r)
+	case lintOutputGitHub:
+		return r.writeGitHub(out)
+	}
+	return errors.Errorf("unsupported lint output format %q", format)
+}
+
+// githubCommands maps the severities to the workflow commands which annotate
+// files with them.
+var githubCommands = map[string]string{
+	"info":    "notice",
+	"warning": "warning",
+	"error":   "error",
+}
+
+// writeGitHub prints one workflow command per finding. The support.Message
+// of a finding carries no line, so the annotations are attached to files.
+func (r *lintReport) writeGitHub(out io.Writer) error {
+	for _, chart := range r.Results {
+		if len(chart.Messages) == 0 {
+			for _, err := range chart.Errors {
+				writeGitHubCommand(out, "error", chart.Path, "", "", err)
+			}
+		}
+		for _, msg := range chart.Messages {
+			writeGitHubCommand(out, msg.Severity, chart.Path, msg.Path, msg.Rule, msg.Text)
+		}
+		if chart.Score != nil {
+			writeGitHubCommand(out, "info", chart.Path, "", "", fmt.Sprintf("Score: %d/100", *chart.Score))
+		}
+	}
+	for _, rule := range r.Rules {
+		for _, f := range rule.Findings {
+			writeGitHubCommand(out, f.Severity, f.Chart, f.Path, rule.Rule, f.Text)
+		}
+	}
+	for _, s := range r.Scores {
+		writeGitHubCommand(out, "info", s.Chart, "", "", fmt.Sprintf("Score: %d/100", s.Score))
+	}
+	return nil
+}
+
+func writeGitHubCommand(out io.Writer, severity, chart, file, rule, text string) {
+	command, ok := githubCommands[severity]
+	if !ok {
+		command = "notice"
+	}
+	properties := []string{"file=" + escapeGitHubProperty(path.Join(chart, file))}
+	if rule != "" {
+		properties = append(properties, "title="+escapeGitHubProperty(rule))
+	}
+	fmt.Fprintf(out, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeGitHubData(text))
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes the value of a workflow command property.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
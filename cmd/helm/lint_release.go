@@ -0,0 +1,134 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// releaseChartRef is how the chart of the release name is reported.
+func releaseChartRef(name string) string {
+	return "release:" + name
+}
+
+// fetchReleaseChart saves the chart of the current revision of the release
+// name into a new directory below dir, so it is linted like a local chart. It
+// returns the chart directory and the values the release was installed or
+// upgraded with.
+func fetchReleaseChart(cfg *action.Configuration, name, dir string) (string, map[string]interface{}, error) {
+	rel, err := getLintRelease(cfg, name)
+	if err != nil {
+		return "", nil, err
+	}
+	ch := rel.Chart
+	if ch == nil || ch.Metadata == nil {
+		return "", nil, errors.Errorf("release %s doesn't store the chart it was installed with", name)
+	}
+
+	// A release stores the values of its chart, but not the values.yaml they
+	// were read from.
+	hasValuesFile := false
+	for _, f := range ch.Raw {
+		hasValuesFile = hasValuesFile || f.Name == chartutil.ValuesfileName
+	}
+	if !hasValuesFile && len(ch.Values) > 0 {
+		data, err := yaml.Marshal(ch.Values)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "unable to save the values of release %s", name)
+		}
+		ch.Raw = append(ch.Raw, &chart.File{Name: chartutil.ValuesfileName, Data: data})
+	}
+
+	// Nor does it store the subcharts, which can't be linted then. They are
+	// dropped from the dependencies for the chart not to be reported as
+	// missing them.
+	if missing := missingSubcharts(ch); len(missing) > 0 {
+		warning("the subcharts of release %s aren't stored with it and aren't linted: %s", name, strings.Join(missing, ", "))
+		var kept []*chart.Dependency
+		for _, dep := range ch.Metadata.Dependencies {
+			if !slices.Contains(missing, dependencyName(dep)) {
+				kept = append(kept, dep)
+			}
+		}
+		ch.Metadata.Dependencies = kept
+		ch.Lock = nil
+	}
+
+	dest, err := os.MkdirTemp(dir, "release-")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := chartutil.SaveDir(ch, dest); err != nil {
+		return "", nil, errors.Wrapf(err, "unable to save the chart of release %s", name)
+	}
+	return filepath.Join(dest, ch.Name()), rel.Config, nil
+}
+
+// releaseValues returns the values the release name was installed or
+// upgraded with, which --reuse-values-from lints a chart with.
+func releaseValues(cfg *action.Configuration, name string) (map[string]interface{}, error) {
+	rel, err := getLintRelease(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	return rel.Config, nil
+}
+
+// getLintRelease gets the current revision of the release name in the
+// namespace of the command.
+func getLintRelease(cfg *action.Configuration, name string) (*release.Release, error) {
+	rel, err := action.NewGet(cfg).Run(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get release %s in namespace %s", name, settings.Namespace())
+	}
+	return rel, nil
+}
+
+// missingSubcharts returns the names of the dependencies of ch which aren't
+// among its subcharts.
+func missingSubcharts(ch *chart.Chart) []string {
+	var missing []string
+	for _, dep := range ch.Metadata.Dependencies {
+		found := false
+		for _, sub := range ch.Dependencies() {
+			found = found || sub.Name() == dep.Name
+		}
+		if !found {
+			missing = append(missing, dependencyName(dep))
+		}
+	}
+	return missing
+}
+
+// dependencyName is the name of dep in the values of the parent chart.
+func dependencyName(dep *chart.Dependency) string {
+	if dep.Alias != "" {
+		return dep.Alias
+	}
+	return dep.Name
+}
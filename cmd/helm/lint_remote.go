@@ -0,0 +1,130 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// isRemoteChart reports whether a getter supports the scheme of the chart
+// reference, e.g. an oci:// reference or an https:// URL of a packaged chart.
+func isRemoteChart(ref string, p getter.Providers) bool {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	_, err = p.ByScheme(u.Scheme)
+	return err == nil
+}
+
+// remoteCharts maps the directories remote charts are extracted into to their
+// references.
+type remoteCharts map[string]string
+
+// fetch downloads the remote charts among paths and extracts each into its
+// own directory below dir, so they are linted, subcharts included, like local
+// charts. It returns paths with the remote charts replaced by their
+// directories. A positive timeout limits the time each download may take.
+func (r remoteCharts) fetch(cfg *action.Configuration, paths []string, dir string, timeout time.Duration) ([]string, error) {
+	providers := getter.All(settings)
+	local := make([]string, len(paths))
+	for i, ref := range paths {
+		if !isRemoteChart(ref, providers) {
+			local[i] = ref
+			continue
+		}
+		chartDir, err := fetchRemoteChart(cfg, ref, providers, dir, timeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to fetch chart %s", ref)
+		}
+		r[chartDir] = ref
+		local[i] = chartDir
+	}
+	return local, nil
+}
+
+// fetchRemoteChart downloads the chart at ref into a new directory below dir
+// and extracts it there, returning the chart directory.
+func fetchRemoteChart(cfg *action.Configuration, ref string, providers getter.Providers, dir string, timeout time.Duration) (string, error) {
+	dest, err := os.MkdirTemp(dir, "chart-")
+	if err != nil {
+		return "", err
+	}
+	c := downloader.ChartDownloader{
+		Out:              io.Discard,
+		Verify:           downloader.VerifyNever,
+		Getters:          providers,
+		RegistryClient:   cfg.RegistryClient,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	if registry.IsOCI(ref) {
+		c.Options = append(c.Options, getter.WithRegistryClient(cfg.RegistryClient))
+	}
+	if timeout > 0 {
+		c.Options = append(c.Options, getter.WithTimeout(timeout))
+	}
+	saved, _, err := c.DownloadTo(ref, "", dest)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(saved)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	extracted := filepath.Join(dest, "chart")
+	if err := chartutil.Expand(extracted, file); err != nil {
+		return "", errors.Wrap(err, "unable to extract chart")
+	}
+	entries, err := os.ReadDir(extracted)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", errors.New("the chart archive must contain a single directory")
+	}
+	return filepath.Join(extracted, entries[0].Name()), nil
+}
+
+// path returns the path of a chart linted by RunScoped as it is reported: for
+// a remote chart or one of its subcharts, the reference of the remote chart
+// and the location of the subchart within it.
+func (r remoteCharts) path(p string) string {
+	for dir, ref := range r {
+		if p == dir {
+			return ref
+		}
+		if rest, ok := strings.CutPrefix(p, dir+string(filepath.Separator)); ok {
+			return ref + "/" + filepath.ToSlash(rest)
+		}
+	}
+	return p
+}
@@ -17,8 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/internal/test"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 func TestLintCmdWithSubchartsFlag(t *testing.T) {
@@ -33,6 +45,31 @@ func TestLintCmdWithSubchartsFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --with-subcharts %s", testChart),
 		golden:    "output/lint-chart-with-bad-subcharts-with-subcharts.txt",
 		wantError: true,
+	}, {
+		name:      "lint good chart with bad subcharts using several workers",
+		cmd:       fmt.Sprintf("lint --with-subcharts --lint-workers 4 %s", testChart),
+		golden:    "output/lint-chart-with-bad-subcharts-with-subcharts.txt",
+		wantError: true,
+	}, {
+		name:      "lint good chart with bad subcharts using a single worker",
+		cmd:       fmt.Sprintf("lint --with-subcharts --lint-workers 1 %s", testChart),
+		golden:    "output/lint-chart-with-bad-subcharts-with-subcharts.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with an invalid number of workers",
+		cmd:       fmt.Sprintf("lint --lint-workers 0 %s", testChart),
+		golden:    "output/lint-invalid-workers.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a negative timeout",
+		cmd:       fmt.Sprintf("lint --timeout -1s %s", testChart),
+		golden:    "output/lint-invalid-timeout.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a timeout",
+		cmd:       fmt.Sprintf("lint --timeout 1m --with-subcharts %s", testChart),
+		golden:    "output/lint-chart-with-bad-subcharts-with-subcharts.txt",
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
@@ -58,11 +95,77 @@ func TestLintCmdWithQuietFlag(t *testing.T) {
 		cmd:       "lint --quiet thischartdoesntexist/",
 		golden:    "",
 		wantError: true,
+	}, {
+		name:   "lint chart with only info messages using --quiet flag",
+		cmd:    "lint --quiet testdata/testcharts/chart-with-schema",
+		golden: "output/lint-quiet-info-only.txt",
+	}, {
+		name:   "lint chart with only info messages and chart with warning using --quiet flag",
+		cmd:    "lint --quiet --kube-version 1.22.0 testdata/testcharts/chart-with-schema testdata/testcharts/chart-with-deprecated-api",
+		golden: "output/lint-quiet-info-only-with-warning.txt",
+	}, {
+		name:      "lint chart with failing info messages using --quiet flag",
+		cmd:       "lint --quiet --severity info testdata/testcharts/chart-with-schema",
+		golden:    "output/lint-quiet-failing-info.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with only info messages grouped by rule using --quiet flag",
+		cmd:    "lint --quiet --group-by rule testdata/testcharts/chart-with-schema",
+		golden: "output/lint-quiet-info-only.txt",
 	}}
 	runTestCmd(t, tests)
 
 }
 
+func TestLintCmdWithOutputFlag(t *testing.T) {
+	testChart1 := "testdata/testcharts/alpine"
+	testChart2 := "testdata/testcharts/chart-bad-requirements"
+	tests := []cmdTestCase{{
+		name:   "lint good chart as json",
+		cmd:    fmt.Sprintf("lint -o json %s", testChart1),
+		golden: "output/lint-output-json.txt",
+	}, {
+		name:   "lint good chart as json using --quiet flag",
+		cmd:    fmt.Sprintf("lint -o json --quiet %s", testChart1),
+		golden: "output/lint-quiet-json.txt",
+	}, {
+		name:   "lint chart with warning as json using --quiet flag",
+		cmd:    "lint -o json --quiet --kube-version 1.22.0 testdata/testcharts/chart-with-deprecated-api",
+		golden: "output/lint-quiet-json-with-warning.txt",
+	}, {
+		name:      "lint two charts, one with error, as yaml using --quiet flag",
+		cmd:       fmt.Sprintf("lint -o yaml --quiet %s %s", testChart1, testChart2),
+		golden:    "output/lint-quiet-yaml-with-error.txt",
+		wantError: true,
+	}, {
+		name:      "lint two charts as json grouped by rule",
+		cmd:       fmt.Sprintf("lint -o json --group-by rule %s %s", testChart1, testChart2),
+		golden:    "output/lint-group-by-rule-json.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithGitHubOutput(t *testing.T) {
+	testChart1 := "testdata/testcharts/chart-with-deprecated-api"
+	testChart2 := "testdata/testcharts/chart-bad-requirements"
+	tests := []cmdTestCase{{
+		name:      "lint charts as GitHub Actions annotations",
+		cmd:       fmt.Sprintf("lint -o github --kube-version 1.22.0 %s %s", testChart1, testChart2),
+		golden:    "output/lint-github.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart as GitHub Actions annotations using --quiet flag",
+		cmd:    "lint -o github --quiet testdata/testcharts/alpine",
+		golden: "output/lint-quiet-json.txt",
+	}, {
+		name:      "lint chart with an unknown output format",
+		cmd:       "lint -o gitlab testdata/testcharts/alpine",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 	testChart := "testdata/testcharts/chart-with-deprecated-api"
 	tests := []cmdTestCase{{
@@ -91,7 +194,956 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestLintCmdWithSeverityFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:      "lint chart with info messages using --severity info",
+		cmd:       "lint --severity info testdata/testcharts/alpine",
+		golden:    "output/lint-severity-info.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with warnings using --severity warning",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --severity warning %s", testChart),
+		golden:    "output/lint-chart-with-deprecated-api-strict.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with warnings using --severity error",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --severity error %s", testChart),
+		golden: "output/lint-chart-with-deprecated-api.txt",
+	}, {
+		name:      "lint chart using --strict with a different --severity",
+		cmd:       fmt.Sprintf("lint --strict --severity error %s", testChart),
+		golden:    "output/lint-severity-strict.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart using an invalid --severity",
+		cmd:       fmt.Sprintf("lint --severity fatal %s", testChart),
+		golden:    "output/lint-severity-invalid.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithWarnValueOverridesFlag(t *testing.T) {
+	testChart := "testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:   "lint chart with overridden values",
+		cmd:    fmt.Sprintf("lint --warn-value-overrides -f %[1]s/extra_values.yaml -f %[1]s/more_values.yaml %[1]s", testChart),
+		golden: "output/lint-warn-value-overrides.txt",
+	}, {
+		name:   "lint chart with a value changing between a table and a scalar",
+		cmd:    fmt.Sprintf("lint --warn-value-overrides -f %[1]s/extra_values.yaml -f %[1]s/scalar_values.yaml %[1]s", testChart),
+		golden: "output/lint-warn-value-type-change.txt",
+	}, {
+		name:   "lint chart with overridden values without the flag",
+		cmd:    fmt.Sprintf("lint --quiet -f %[1]s/extra_values.yaml -f %[1]s/more_values.yaml %[1]s", testChart),
+		golden: "output/lint-quiet.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithValuesFromStdin(t *testing.T) {
+	testChart := "testdata/testcharts/alpine"
+	in, err := os.Open(filepath.Join(testChart, "more_values.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	// The values read from stdin take their place among the other values
+	// files.
+	cmd := fmt.Sprintf("lint --warn-value-overrides -f %[1]s/extra_values.yaml -f - %[1]s", testChart)
+	_, out, err := executeActionCommandStdinC(storageFixture(), in, cmd)
+	if err != nil {
+		t.Fatalf("unexpected error, got '%v'", err)
+	}
+	if !strings.Contains(out, "value is overridden by multiple values files: testdata/testcharts/alpine/extra_values.yaml, -") {
+		t.Errorf("Expected the values from stdin to override the values file, got %s", out)
+	}
+
+	// An empty stdin sets no values.
+	empty, err := os.Create(filepath.Join(t.TempDir(), "empty.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if _, out, err := executeActionCommandStdinC(storageFixture(), empty, fmt.Sprintf("lint -f - %s", testChart)); err != nil {
+		t.Errorf("Expected the lint to pass with an empty stdin, got '%v': %s", err, out)
+	}
+}
+
+func TestLintCmdWithPolicyFlag(t *testing.T) {
+	testChart := "testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:      "lint chart with CEL policies",
+		cmd:       fmt.Sprintf("lint --policy testdata/lint-policies.yaml %s", testChart),
+		golden:    "output/lint-policy.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a missing policy file",
+		cmd:       fmt.Sprintf("lint --policy testdata/does-not-exist.yaml %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithMaxWarningsFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:   "lint chart with warnings under the limit",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --max-warnings 1 %s", testChart),
+		golden: "output/lint-max-warnings.txt",
+	}, {
+		name:      "lint chart with warnings over the limit",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --max-warnings 0 %s", testChart),
+		golden:    "output/lint-max-warnings-exceeded.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with warnings and strict flag",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --max-warnings 1 --strict %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithOnlySubchartsFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-bad-subcharts"
+	tests := []cmdTestCase{{
+		name:      "lint the subcharts matching a pattern and the root chart",
+		cmd:       fmt.Sprintf("lint --only-subcharts good-* %s", testChart),
+		golden:    "output/lint-only-subcharts.txt",
+		wantError: true,
+	}, {
+		name:   "lint only the subcharts matching a pattern",
+		cmd:    fmt.Sprintf("lint --only-subcharts charts/good-subchart,missing --skip-root %s", testChart),
+		golden: "output/lint-only-subcharts-skip-root.txt",
+	}, {
+		name:      "lint without the root chart nor its subcharts",
+		cmd:       fmt.Sprintf("lint --skip-root %s", testChart),
+		golden:    "output/lint-skip-root-without-subcharts.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSubchartValuesFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-subchart-values"
+	tests := []cmdTestCase{{
+		name:      "lint subchart with the values set by its parent",
+		cmd:       fmt.Sprintf("lint --conventions-only --with-subcharts --subchart-values %s", testChart),
+		golden:    "output/lint-subchart-values.txt",
+		wantError: true,
+	}, {
+		name:   "lint subchart with the user values only",
+		cmd:    fmt.Sprintf("lint --conventions-only --with-subcharts %s", testChart),
+		golden: "output/lint-subchart-values-off.txt",
+	}, {
+		name:      "lint with subchart values but without subcharts",
+		cmd:       fmt.Sprintf("lint --subchart-values %s", testChart),
+		golden:    "output/lint-subchart-values-without-subcharts.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithScopeNamespaceFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-bad-subcharts"
+	tests := []cmdTestCase{{
+		name:   "lint subchart in another namespace",
+		cmd:    fmt.Sprintf("lint --only-subcharts good-subchart --skip-root --scope-namespace good-subchart=cache %s", testChart),
+		golden: "output/lint-only-subcharts-skip-root.txt",
+	}, {
+		name:      "lint with an invalid scope namespace",
+		cmd:       fmt.Sprintf("lint --scope-namespace good-subchart %s", testChart),
+		golden:    "output/lint-invalid-scope-namespace.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithMaxFindingsFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-bad-subcharts"
+	tests := []cmdTestCase{{
+		name:      "lint chart with findings over the limit",
+		cmd:       fmt.Sprintf("lint --with-subcharts --max-findings 1 %s", testChart),
+		golden:    "output/lint-max-findings.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a negative limit",
+		cmd:       fmt.Sprintf("lint --max-findings -1 %s", testChart),
+		golden:    "output/lint-invalid-max-findings.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithEnableLookupFlag(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint chart with lookup enabled and no cluster",
+		cmd:       "lint --enable-lookup testdata/testcharts/alpine",
+		golden:    "output/lint-enable-lookup-no-cluster.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithGroupByFlag(t *testing.T) {
+	testCharts := "testdata/testcharts/chart-with-deprecated-api testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:      "lint charts grouped by rule",
+		cmd:       fmt.Sprintf("lint --group-by rule --kube-version 1.22.0 --policy testdata/lint-policies.yaml %s", testCharts),
+		golden:    "output/lint-group-by-rule.txt",
+		wantError: true,
+	}, {
+		name:   "lint charts grouped by rule with quiet flag",
+		cmd:    fmt.Sprintf("lint --group-by rule --quiet --kube-version 1.22.0 %s", testCharts),
+		golden: "output/lint-group-by-rule-quiet.txt",
+	}, {
+		name:      "lint charts with an invalid group",
+		cmd:       fmt.Sprintf("lint --group-by template %s", testCharts),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithJUnitFile(t *testing.T) {
+	junitFile := filepath.Join(t.TempDir(), "helm-lint.xml")
+	tests := []cmdTestCase{{
+		name:      "lint charts and write a JUnit report",
+		cmd:       fmt.Sprintf("lint --junit-file %s --kube-version 1.22.0 --strict testdata/testcharts/chart-with-deprecated-api testdata/testcharts/alpine", junitFile),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+	test.AssertGoldenFile(t, junitFile, "output/lint-junit.xml")
+}
+
+func TestLintCmdWithMetricsFile(t *testing.T) {
+	metricsFile := filepath.Join(t.TempDir(), "helm-lint.prom")
+	tests := []cmdTestCase{{
+		name:      "lint charts and write metrics",
+		cmd:       fmt.Sprintf("lint --metrics-file %s --kube-version 1.22.0 --strict testdata/testcharts/chart-with-deprecated-api testdata/testcharts/alpine", metricsFile),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+	test.AssertGoldenFile(t, metricsFile, "output/lint-metrics.prom")
+}
+
+func TestLintCmdWithMergeStrategyFlag(t *testing.T) {
+	testChart := "testdata/testcharts/merge-strategy"
+	tests := []cmdTestCase{{
+		name:      "lint chart with lists replaced by later values files",
+		cmd:       fmt.Sprintf("lint --policy %[1]s/policies.yaml -f %[1]s/base.yaml -f %[1]s/override.yaml %[1]s", testChart),
+		golden:    "output/lint-merge-strategy-replace-arrays.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with lists merged element by element",
+		cmd:    fmt.Sprintf("lint --merge-strategy deep --policy %[1]s/policies.yaml -f %[1]s/base.yaml -f %[1]s/override.yaml %[1]s", testChart),
+		golden: "output/lint-merge-strategy-deep.txt",
+	}, {
+		name:      "lint chart with an unknown merge strategy",
+		cmd:       fmt.Sprintf("lint --merge-strategy union %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSnapshotFlag(t *testing.T) {
+	testChart := "testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:   "lint chart matching its snapshot",
+		cmd:    fmt.Sprintf("lint --snapshot testdata/lint-snapshot.yaml %s", testChart),
+		golden: "output/lint-snapshot.txt",
+	}, {
+		name:      "lint chart differing from its snapshot",
+		cmd:       fmt.Sprintf("lint --snapshot testdata/lint-snapshot.yaml --set restartPolicy=Always %s", testChart),
+		golden:    "output/lint-snapshot-changed.txt",
+		wantError: true,
+	}, {
+		name:      "lint several charts with a snapshot",
+		cmd:       fmt.Sprintf("lint --snapshot testdata/lint-snapshot.yaml %s %s", testChart, testChart),
+		wantError: true,
+	}, {
+		name:      "update snapshot without a snapshot file",
+		cmd:       fmt.Sprintf("lint --update-snapshot %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithCacheDir(t *testing.T) {
+	cacheDir := t.TempDir()
+	testCharts := "testdata/testcharts/alpine testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:   "lint charts and populate the cache",
+		cmd:    fmt.Sprintf("lint --cache-dir %s %s", cacheDir, testCharts),
+		golden: "output/lint-cache-populate.txt",
+	}, {
+		name:   "lint unchanged charts from the cache",
+		cmd:    fmt.Sprintf("lint --cache-dir %s %s", cacheDir, testCharts),
+		golden: "output/lint-cache-hit.txt",
+	}, {
+		name:   "lint charts ignoring the cache",
+		cmd:    fmt.Sprintf("lint --force --cache-dir %s %s", cacheDir, testCharts),
+		golden: "output/lint-cache-populate.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithEscalateThresholdFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:      "lint chart with an escalated rule",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --escalate-threshold deprecated-api=0 %s", testChart),
+		golden:    "output/lint-escalate-threshold.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with a rule below its threshold",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --escalate-threshold deprecated-api=1 %s", testChart),
+		golden: "output/lint-chart-with-deprecated-api.txt",
+	}, {
+		name:      "lint chart with an invalid threshold",
+		cmd:       fmt.Sprintf("lint --escalate-threshold deprecated-api %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithExitCodeFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		code int
+	}{
+		{"clean chart", "lint --exit-code testdata/testcharts/alpine", lintExitClean},
+		{"chart with warnings", "lint --exit-code --kube-version 1.22.0 testdata/testcharts/chart-with-deprecated-api", lintExitWarnings},
+		{"chart with warnings and --strict", "lint --exit-code --strict --kube-version 1.22.0 testdata/testcharts/chart-with-deprecated-api", lintExitWarnings},
+		{"chart with errors", "lint --exit-code testdata/testcharts/chart-bad-requirements", lintExitErrors},
+		{"chart with errors dropped by --max-findings", "lint --exit-code --max-findings 1 testdata/testcharts/chart-with-warning-then-error", lintExitErrors},
+		{"missing chart", "lint --exit-code testdata/testcharts/no-such-chart", lintExitFailure},
+		{"invalid flags", "lint --exit-code --lint-workers 0 testdata/testcharts/alpine", lintExitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, err := executeActionCommand(tt.cmd)
+			code := lintExitClean
+			var exitErr lintExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.code
+			} else if err != nil {
+				t.Fatalf("Expected an exit code, got %v", err)
+			}
+			if code != tt.code {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.code, code, out)
+			}
+		})
+	}
+
+	_, out, _ := executeActionCommand("lint --exit-code --kube-version 1.22.0 testdata/testcharts/chart-with-deprecated-api")
+	if strings.Contains(out, "Error:") || !strings.Contains(out, "1 chart(s) linted, 0 chart(s) failed, 0 error(s), 1 warning(s)") {
+		t.Errorf("Expected only the summary of a lint with warnings, got %s", out)
+	}
+}
+
+func TestLintCmdWithSkipRulesFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:   "lint chart skipping rules",
+		cmd:    fmt.Sprintf("lint --strict --kube-version 1.22.0 --skip-rules deprecated-api,icon %s", testChart),
+		golden: "output/lint-skip-rules.txt",
+	}, {
+		name:   "lint chart skipping the findings of a path",
+		cmd:    fmt.Sprintf("lint --strict --kube-version 1.22.0 --skip-rules templates %s", testChart),
+		golden: "output/lint-skip-rules-path.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithRulesConfig(t *testing.T) {
+	testChart := "testdata/testcharts/signtest"
+	tests := []cmdTestCase{{
+		name:   "lint chart with an image from a registry which is not allowed",
+		cmd:    fmt.Sprintf("lint --rules-config testdata/lint-rules-config.yaml %s", testChart),
+		golden: "output/lint-rules-config.txt",
+	}, {
+		name:      "lint chart with a missing rules config",
+		cmd:       fmt.Sprintf("lint --rules-config testdata/no-such-file.yaml %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithStrictRenderFlag(t *testing.T) {
+	testChart := "testdata/testcharts/merge-strategy"
+	tests := []cmdTestCase{{
+		name:   "lint chart rendering a missing value as empty",
+		cmd:    fmt.Sprintf("lint -f %s/override.yaml %s", testChart, testChart),
+		golden: "output/lint-strict-render-off.txt",
+	}, {
+		name:      "lint chart failing the render on a missing value",
+		cmd:       fmt.Sprintf("lint --strict-render -f %s/override.yaml %s", testChart, testChart),
+		golden:    "output/lint-strict-render.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithRemoteChart(t *testing.T) {
+	ch, err := loader.Load("testdata/testcharts/chart-with-subchart-values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := chartutil.Save(ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	ref := srv.URL + "/umbrella-0.1.0.tgz"
+	_, out, err := executeActionCommand(fmt.Sprintf("lint --conventions-only --with-subcharts %s", ref))
+	if err != nil {
+		t.Fatalf("unexpected error, got '%v': %s", err, out)
+	}
+	for _, want := range []string{"==> Linting " + ref + "\n", "==> Linting " + ref + "/charts/database\n", "2 chart(s) linted, 0 chart(s) failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the output to contain %q, got %s", want, out)
+		}
+	}
+
+	if _, _, err := executeActionCommand(fmt.Sprintf("lint %s/missing-0.1.0.tgz", srv.URL)); err == nil || !strings.Contains(err.Error(), "unable to fetch chart") {
+		t.Errorf("Expected an error fetching a missing chart, got %v", err)
+	}
+}
+
+func TestLintCmdWithRulesBundle(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	keyring := "--rules-bundle-keyring testdata/helm-test-key.pub"
+	tests := []cmdTestCase{{
+		name:      "lint chart with a rules bundle from a URL",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --rules-bundle %s/lint-rules-bundle.yaml.asc %s %s", srv.URL, keyring, testChart),
+		golden:    "output/lint-rules-bundle.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a rules bundle from a file",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --rules-bundle testdata/lint-rules-bundle.yaml.asc %s %s", keyring, testChart),
+		golden:    "output/lint-rules-bundle.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with a tampered rules bundle",
+		cmd:       fmt.Sprintf("lint --rules-bundle testdata/lint-rules-bundle-tampered.yaml.asc %s %s", keyring, testChart),
+		golden:    "output/lint-rules-bundle-tampered.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithConventionsOnlyFlag(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:   "lint chart with deprecated api version using --conventions-only flag",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --strict --conventions-only %s", testChart),
+		golden: "output/lint-conventions-only.txt",
+	}, {
+		name:      "lint chart using --conventions-only and --snapshot flags",
+		cmd:       fmt.Sprintf("lint --conventions-only --snapshot testdata/lint-snapshot.yaml %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithExpandValueTemplatesFlag(t *testing.T) {
+	testChart := "testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:   "lint chart with a broken value template without --expand-value-templates",
+		cmd:    fmt.Sprintf("lint -f testdata/lint-value-templates.yaml %s", testChart),
+		golden: "output/lint-expand-value-templates-off.txt",
+	}, {
+		name:      "lint chart with a broken value template using --expand-value-templates",
+		cmd:       fmt.Sprintf("lint --expand-value-templates -f testdata/lint-value-templates.yaml %s", testChart),
+		golden:    "output/lint-expand-value-templates.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithStyleFlag(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint chart with style issues using --style flag",
+		cmd:    "lint --style testdata/testcharts/chart-with-style-issues",
+		golden: "output/lint-style.txt",
+	}, {
+		name:   "lint chart with style issues without --style flag",
+		cmd:    "lint testdata/testcharts/chart-with-style-issues",
+		golden: "output/lint-style-off.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithScoreFlag(t *testing.T) {
+	testCharts := "testdata/testcharts/chart-with-deprecated-api testdata/testcharts/alpine"
+	tests := []cmdTestCase{{
+		name:   "lint charts using --score flag",
+		cmd:    fmt.Sprintf("lint --score --kube-version 1.22.0 %s", testCharts),
+		golden: "output/lint-score.txt",
+	}, {
+		name:   "lint charts using --score flag grouped by rule",
+		cmd:    fmt.Sprintf("lint --score --group-by rule --kube-version 1.22.0 %s", testCharts),
+		golden: "output/lint-score-group-by-rule.txt",
+	}, {
+		name:   "lint charts using --score flag with weights from --rules-config",
+		cmd:    fmt.Sprintf("lint --score --rules-config testdata/lint-score-rules.yaml --kube-version 1.22.0 -o json %s", testCharts),
+		golden: "output/lint-score-json.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithCompareTo(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	previous := "--compare-to testdata/lint-compare-previous.json"
+	tests := []cmdTestCase{{
+		name:      "lint chart with a new warning using --compare-to",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 %s %s", previous, testChart),
+		golden:    "output/lint-compare-to.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with a new warning using --compare-to and --compare-threshold error",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --compare-threshold error %s %s", previous, testChart),
+		golden: "output/lint-compare-to-threshold.txt",
+	}, {
+		name:   "lint chart using --compare-to with json output",
+		cmd:    fmt.Sprintf("lint --kube-version 1.22.0 --compare-threshold error -o json %s %s", previous, testChart),
+		golden: "output/lint-compare-to-json.txt",
+	}, {
+		name:   "lint chart without changes using --compare-to",
+		cmd:    fmt.Sprintf("lint --compare-to testdata/lint-compare-unchanged.json %s", testChart),
+		golden: "output/lint-compare-to-unchanged.txt",
+	}, {
+		name:      "lint chart using --compare-to with an invalid threshold",
+		cmd:       fmt.Sprintf("lint --compare-threshold fatal %s %s", previous, testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithScopedRulesConfig(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-scoped-rules-config"
+	tests := []cmdTestCase{{
+		name:   "lint umbrella chart with a subchart rules config",
+		cmd:    fmt.Sprintf("lint --with-subcharts %s", testChart),
+		golden: "output/lint-scoped-rules-config.txt",
+	}, {
+		name:   "lint umbrella chart with a subchart rules config restricting --rules-config",
+		cmd:    fmt.Sprintf("lint --with-subcharts --rules-config testdata/lint-scoped-rules.yaml %s", testChart),
+		golden: "output/lint-scoped-rules-config-restricted.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSubchartsAsJSON(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint umbrella chart with subcharts as json",
+		cmd:    "lint --with-subcharts -o json testdata/testcharts/chart-with-scoped-rules-config",
+		golden: "output/lint-with-subcharts-json.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithLoaderPlugin(t *testing.T) {
+	defer resetEnv()()
+
+	pluginDir := filepath.Join(t.TempDir(), "chartdir")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pluginYaml := "name: chartdir\nversion: 0.1.0\nloaders:\n- patterns: [\"*.chartdir\"]\n  command: convert.sh\n"
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin.yaml"), []byte(pluginYaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncp -R \"$1\"/. \"$2\"\n"
+	if err := os.WriteFile(filepath.Join(pluginDir, "convert.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings.PluginsDirectory = filepath.Dir(pluginDir)
+
+	chartPath := filepath.Join(t.TempDir(), "mychart.chartdir")
+	if err := os.MkdirAll(chartPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chartYaml := "apiVersion: v2\nname: mychart\nversion: 0.1.0\n"
+	if err := os.WriteFile(filepath.Join(chartPath, "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("lint %s", chartPath))
+	if err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, out)
+	}
+	if !strings.Contains(out, "1 chart(s) linted, 0 chart(s) failed") {
+		t.Errorf("expected the converted chart to be linted, got:\n%s", out)
+	}
+}
+
+func TestLintCmdWithNestedSubcharts(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	charts := map[string][]string{
+		filepath.Join(root, "lib"):            nil,
+		app:                                   {"lib", "redis", "common"},
+		filepath.Join(app, "charts", "redis"): {"common"},
+		filepath.Join(app, "charts", "redis", "charts", "common"): nil,
+	}
+	for dir, dependencies := range charts {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		chartYaml := fmt.Sprintf("apiVersion: v2\nname: %s\nversion: 0.1.0\ndependencies:\n", filepath.Base(dir))
+		for _, d := range dependencies {
+			chartYaml += fmt.Sprintf("- name: %s\n  version: 0.1.0\n", d)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Symlinked subcharts are linted, unless they were already found.
+	if err := os.Symlink(filepath.Join(root, "lib"), filepath.Join(app, "charts", "lib")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(app, "charts", "redis", "charts", "common"), filepath.Join(app, "charts", "shared")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("lint --with-subcharts -o json %s", app))
+	if err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, out)
+	}
+	for _, scope := range []string{`"scope":"."`, `"scope":"charts/lib"`, `"scope":"charts/redis"`, `"scope":"charts/redis/charts/common"`} {
+		if !strings.Contains(out, scope) {
+			t.Errorf("expected %s in the output, got:\n%s", scope, out)
+		}
+	}
+	if strings.Contains(out, "charts/shared") {
+		t.Errorf("expected the symlinked subchart to be skipped, got:\n%s", out)
+	}
+}
+
+func TestLintFingerprint(t *testing.T) {
+	const renderError = "template: app/templates/deployment.yaml:12:3: executing \"app/templates/deployment.yaml\" at <.Values.image>: nil pointer"
+	fingerprint := lintFingerprint("charts/app", "app", ".", "templates/", "", renderError)
+	for name, other := range map[string]string{
+		"shifted line":  lintFingerprint("charts/app", "app", ".", "templates/", "", strings.Replace(renderError, ":12:3:", ":15:7:", 1)),
+		"other chart":   lintFingerprint("charts/app", "other", ".", "templates/", "", renderError),
+		"other rule":    lintFingerprint("charts/app", "app", ".", "templates/", "yaml-syntax", renderError),
+		"other message": lintFingerprint("charts/app", "app", ".", "templates/", "", strings.Replace(renderError, ".Values.image", ".Values.tag", 1)),
+	} {
+		if same := other == fingerprint; same != (name == "shifted line") {
+			t.Errorf("%s: unexpected fingerprint %s, the original is %s", name, other, fingerprint)
+		}
+	}
+
+	if lintFingerprint("charts/app", "app", ".", "values.yaml", "", "unable to read charts/app/values.yaml") != lintFingerprint("/tmp/checkout/app", "app", ".", "values.yaml", "", "unable to read /tmp/checkout/app/values.yaml") {
+		t.Error("expected the location of the chart to be left out of the fingerprint")
+	}
+	if lintFingerprint("c", "app", ".", "templates/a.yaml", "yaml-syntax", "yaml: line 6: did not find expected key") != lintFingerprint("c", "app", ".", "templates/a.yaml", "yaml-syntax", "yaml: line 9: did not find expected key") {
+		t.Error("expected the line of a YAML error to be left out of the fingerprint")
+	}
+	// Linting ".", only the chart path itself is left out, and not the dots
+	// of the keys of values.
+	if lintFingerprint(".", "app", ".", "values.yaml", "", "image.tag is required") == lintFingerprint(".", "app", ".", "values.yaml", "", "imagetag is required") {
+		t.Error("expected the dots of the keys of values to be kept in the fingerprint")
+	}
+	if lintFingerprint(".", "app", ".", "values.yaml", "", "unable to read ./values.yaml") != lintFingerprint("charts/app", "app", ".", "values.yaml", "", "unable to read charts/app/values.yaml") {
+		t.Error("expected the location of the chart \".\" to be left out of the fingerprint")
+	}
+}
+
 func TestLintFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "lint", true)
 	checkFileCompletion(t, "lint mypath", true) // Multiple paths can be given
 }
+
+func TestLintCmdWithCapabilitiesFile(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-capabilities"
+	tests := []cmdTestCase{{
+		name:   "lint with a capabilities bundle serving the API",
+		cmd:    fmt.Sprintf("lint --capabilities-file testdata/lint-capabilities/eks-1.25 %s", testChart),
+		golden: "output/lint-capabilities.txt",
+	}, {
+		name:   "lint with a capabilities bundle missing the API",
+		cmd:    fmt.Sprintf("lint --capabilities-file testdata/lint-capabilities/legacy %s", testChart),
+		golden: "output/lint-capabilities-legacy.txt",
+	}, {
+		name:      "lint with a capabilities file listing no API versions",
+		cmd:       fmt.Sprintf("lint --capabilities-file testdata/lint-capabilities/empty.txt %s", testChart),
+		golden:    "output/lint-capabilities-empty.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithKubeVersions(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-deprecated-api"
+	tests := []cmdTestCase{{
+		name:   "lint for several Kubernetes versions",
+		cmd:    fmt.Sprintf("lint --kube-version 1.20.0 --kube-version 1.22.0 %s", testChart),
+		golden: "output/lint-kube-versions.txt",
+	}, {
+		name:   "lint for several Kubernetes versions with json output",
+		cmd:    fmt.Sprintf("lint -o json --quiet --kube-version 1.20.0 --kube-version 1.22.0 %s", testChart),
+		golden: "output/lint-kube-versions.json",
+	}, {
+		name:      "lint for an invalid Kubernetes version",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --kube-version latest %s", testChart),
+		golden:    "output/lint-invalid-kube-versions.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithOwners(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint with the owners of the charts",
+		cmd:    "lint -o json --with-subcharts testdata/testcharts/chart-with-owner",
+		golden: "output/lint-owners.json",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithWarnUnusedValues(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-subchart-values"
+	tests := []cmdTestCase{{
+		name:   "lint subcharts receiving values they don't define",
+		cmd:    fmt.Sprintf("lint --conventions-only --with-subcharts --warn-unused-values --set database.prot=5433,database.port=5433,global.env=dev %s", testChart),
+		golden: "output/lint-warn-unused-values.txt",
+	}, {
+		name:      "lint with unused values warnings but without subcharts",
+		cmd:       fmt.Sprintf("lint --warn-unused-values %s", testChart),
+		golden:    "output/lint-warn-unused-values-without-subcharts.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithDisabledSubcharts(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-disabled-subcharts"
+	tests := []cmdTestCase{{
+		name:   "lint the enabled subcharts",
+		cmd:    fmt.Sprintf("lint --with-subcharts %s", testChart),
+		golden: "output/lint-disabled-subcharts.txt",
+	}, {
+		name:   "lint the subcharts enabled by the values",
+		cmd:    fmt.Sprintf("lint --with-subcharts --set redis.enabled=true,tags.monitoring=true,database.enabled=false %s", testChart),
+		golden: "output/lint-disabled-subcharts-enabled.txt",
+	}, {
+		name:   "lint the disabled subcharts too",
+		cmd:    fmt.Sprintf("lint --with-subcharts --lint-disabled-subcharts %s", testChart),
+		golden: "output/lint-disabled-subcharts-all.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithFromRelease(t *testing.T) {
+	releaseChart := func(deps ...*chart.Dependency) *chart.Chart {
+		return &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "0.1.0", Icon: "https://example.com/icon.png", Dependencies: deps},
+			Values:   map[string]interface{}{"replicas": 1},
+			Schema:   []byte(`{"type": "object", "required": ["image"]}`),
+			Templates: []*chart.File{{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  image: {{ .Values.image | quote }}\n  replicas: {{ .Values.replicas | quote }}\n"),
+			}},
+		}
+	}
+	mockRelease := func(name string, ch *chart.Chart, config map[string]interface{}) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{Name: name, Chart: ch})
+		rel.Config = config
+		return rel
+	}
+	rels := []*release.Release{
+		mockRelease("web", releaseChart(), map[string]interface{}{"image": "nginx"}),
+		mockRelease("broken", releaseChart(), nil),
+		mockRelease("unstored", nil, nil),
+		mockRelease("with-deps", releaseChart(&chart.Dependency{Name: "redis", Version: "1.0.0", Alias: "cache"}), map[string]interface{}{"image": "nginx"}),
+	}
+	rels[2].Chart = nil
+
+	tests := []cmdTestCase{{
+		name:   "lint the chart of a release with its values",
+		cmd:    "lint --from-release web",
+		golden: "output/lint-from-release.txt",
+		rels:   rels,
+	}, {
+		name:      "lint the chart of a release failing with its values",
+		cmd:       "lint --from-release broken",
+		golden:    "output/lint-from-release-broken.txt",
+		rels:      rels,
+		wantError: true,
+	}, {
+		name:   "lint the chart of a release with values given to the lint",
+		cmd:    "lint --from-release broken --set image=busybox",
+		golden: "output/lint-from-release-set.txt",
+		rels:   rels,
+	}, {
+		name:   "lint the chart of a release without its subcharts",
+		cmd:    "lint --from-release with-deps",
+		golden: "output/lint-from-release-deps.txt",
+		rels:   rels,
+	}, {
+		name:      "lint a release which doesn't store its chart",
+		cmd:       "lint --from-release unstored",
+		golden:    "output/lint-from-release-unstored.txt",
+		rels:      rels,
+		wantError: true,
+	}, {
+		name:      "lint a release which doesn't exist",
+		cmd:       "lint --from-release missing",
+		golden:    "output/lint-from-release-missing.txt",
+		rels:      rels,
+		wantError: true,
+	}, {
+		name:      "lint a release and chart paths",
+		cmd:       "lint --from-release web testdata/testcharts/alpine",
+		golden:    "output/lint-from-release-paths.txt",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSchemaStrict(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-schema-negative"
+	tests := []cmdTestCase{{
+		name:      "report each schema violation on its own",
+		cmd:       fmt.Sprintf("lint --schema-strict %s", testChart),
+		golden:    "output/lint-schema-strict.txt",
+		wantError: true,
+	}, {
+		name:      "report the schema violations of the values given to the lint",
+		cmd:       fmt.Sprintf("lint --schema-strict --set age=10,employmentInfo.salary=x %s", testChart),
+		golden:    "output/lint-schema-strict-set.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSetLiteral(t *testing.T) {
+	// The literal value, commas included, reaches the scope of the subchart
+	// and fails its schema as a string.
+	tests := []cmdTestCase{{
+		name:      "lint subchart with a literal value",
+		cmd:       "lint --only-subcharts subchart-with-schema --skip-root --schema-strict --set-literal subchart-with-schema.age=1,2 testdata/testcharts/chart-with-schema-and-subchart",
+		golden:    "output/lint-set-literal-subchart.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithShowRenderedCount(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint charts showing the number of objects they render",
+		cmd:    "lint --show-rendered-count testdata/testcharts/alpine testdata/testcharts/chart-with-deprecated-api",
+		golden: "output/lint-show-rendered-count.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithLintEachAlias(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint a subchart once for all its aliases",
+		cmd:    "lint --with-subcharts --skip-root --set redis.port=6379 testdata/testcharts/chart-with-aliased-subchart",
+		golden: "output/lint-aliased-subchart.txt",
+	}, {
+		name:      "lint a subchart once for each of its aliases",
+		cmd:       "lint --with-subcharts --skip-root --lint-each-alias --subchart-values testdata/testcharts/chart-with-aliased-subchart",
+		golden:    "output/lint-each-alias.txt",
+		wantError: true,
+	}, {
+		name:      "lint each alias without subcharts",
+		cmd:       "lint --lint-each-alias testdata/testcharts/chart-with-aliased-subchart",
+		golden:    "output/lint-each-alias-without-subcharts.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithReuseValuesFrom(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-schema-negative"
+	rel := release.Mock(&release.MockReleaseOptions{Name: "people"})
+	rel.Config = map[string]interface{}{"age": 30, "employmentInfo": map[string]interface{}{"salary": 1000}}
+	rels := []*release.Release{rel}
+
+	tests := []cmdTestCase{{
+		name:   "lint a chart with the values of a release",
+		cmd:    fmt.Sprintf("lint --reuse-values-from people %s", testChart),
+		golden: "output/lint-reuse-values-from.txt",
+		rels:   rels,
+	}, {
+		name:      "lint a chart with the values of a release and values given to the lint",
+		cmd:       fmt.Sprintf("lint --reuse-values-from people --set age=-1 %s", testChart),
+		golden:    "output/lint-reuse-values-from-set.txt",
+		rels:      rels,
+		wantError: true,
+	}, {
+		name:      "lint a chart with the values of a release which doesn't exist",
+		cmd:       fmt.Sprintf("lint --reuse-values-from missing %s", testChart),
+		golden:    "output/lint-reuse-values-from-missing.txt",
+		rels:      rels,
+		wantError: true,
+	}, {
+		name:      "lint the chart of a release with the values of another",
+		cmd:       "lint --from-release people --reuse-values-from people",
+		golden:    "output/lint-reuse-values-from-release.txt",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithGroupMessages(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint a chart grouping the same warning in several templates",
+		cmd:    "lint --group-messages testdata/testcharts/chart-with-repeated-warnings testdata/testcharts/alpine",
+		golden: "output/lint-group-messages.txt",
+	}, {
+		name:      "lint a chart grouping the same warning failing with --strict",
+		cmd:       "lint --group-messages --strict testdata/testcharts/chart-with-repeated-warnings",
+		golden:    "output/lint-group-messages-strict.txt",
+		wantError: true,
+	}, {
+		name:      "lint grouping the messages by rule",
+		cmd:       "lint --group-messages --group-by rule testdata/testcharts/chart-with-repeated-warnings",
+		golden:    "output/lint-group-messages-by-rule.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithSubchartDir(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint the subcharts vendored in another directory",
+		cmd:    "lint --with-subcharts --subchart-dir vendor testdata/testcharts/chart-with-vendored-subchart",
+		golden: "output/lint-subchart-dir.txt",
+	}, {
+		name:      "lint the subcharts in a directory which doesn't exist",
+		cmd:       "lint --with-subcharts --subchart-dir deps testdata/testcharts/chart-with-vendored-subchart",
+		golden:    "output/lint-subchart-dir-missing.txt",
+		wantError: true,
+	}, {
+		name:      "lint with a subchart directory without subcharts",
+		cmd:       "lint --subchart-dir vendor testdata/testcharts/chart-with-vendored-subchart",
+		golden:    "output/lint-subchart-dir-without-subcharts.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithHPAMetrics(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint a chart with HorizontalPodAutoscalers which can't compute their metrics",
+		cmd:    "lint testdata/testcharts/chart-with-hpa-metrics",
+		golden: "output/lint-hpa-metrics.txt",
+	}}
+	runTestCmd(t, tests)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/plugin"
 )
 
@@ -44,6 +45,53 @@ type pluginError struct {
 	code int
 }
 
+// registerLoaderPlugins registers the loaders supplied by plugins, so that
+// charts matching their patterns are converted by the plugin when loaded.
+func registerLoaderPlugins() {
+	// If HELM_NO_PLUGINS is set to 1, do not load plugins.
+	if os.Getenv("HELM_NO_PLUGINS") == "1" {
+		return
+	}
+
+	found, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load plugins: %s\n", err)
+		return
+	}
+
+	for _, plug := range found {
+		for _, l := range plug.Metadata.Loaders {
+			loader.RegisterExternalLoader(loader.ExternalLoader{
+				Name:     plug.Metadata.Name,
+				Patterns: l.Patterns,
+				Convert:  loaderPluginCommand(plug, l.Command),
+			})
+		}
+	}
+}
+
+// loaderPluginCommand returns a function running the loader command of a
+// plugin with the chart path and the output directory as arguments.
+func loaderPluginCommand(plug *plugin.Plugin, command string) func(path, dir string) error {
+	return func(path, dir string) error {
+		commands := strings.Split(os.ExpandEnv(command), " ")
+		argv := append(commands[1:], path, dir)
+		prog := exec.Command(filepath.Join(plug.Dir, commands[0]), argv...)
+		plugin.SetupPluginEnv(settings, plug.Metadata.Name, plug.Dir)
+		prog.Env = os.Environ()
+		prog.Stdout = os.Stderr
+		buf := bytes.NewBuffer(nil)
+		prog.Stderr = buf
+		if err := prog.Run(); err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				return errors.Errorf("plugin %q exited with error: %s", plug.Metadata.Name, strings.TrimSpace(buf.String()))
+			}
+			return err
+		}
+		return nil
+	}
+}
+
 // loadPlugins loads plugins into the command list.
 //
 // This follows a different pattern than the other commands because it has
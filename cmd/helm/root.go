@@ -166,7 +166,7 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newDependencyCmd(actionConfig, out),
 		newPullCmd(actionConfig, out),
 		newShowCmd(actionConfig, out),
-		newLintCmd(out),
+		newLintCmd(actionConfig, out),
 		newPackageCmd(actionConfig, out),
 		newRepoCmd(out),
 		newSearchCmd(out),
@@ -200,6 +200,7 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 
 	// Find and add plugins
 	loadPlugins(cmd, out)
+	registerLoaderPlugins()
 
 	// Check permissions on critical files
 	checkPerms()
@@ -35,6 +35,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
@@ -55,6 +56,7 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var kubeVersion string
 	var extraAPIs []string
 	var showFiles []string
+	var renderCacheDir string
 
 	cmd := &cobra.Command{
 		Use:   "template [NAME] [CHART]",
@@ -80,6 +82,10 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			client.SetRegistryClient(registryClient)
 
+			if renderCacheDir != "" {
+				cfg.RenderCache = engine.NewDiskRenderCache(renderCacheDir)
+			}
+
 			// This is for the case where "" is specifically passed in as a
 			// value. When there is no value passed in NoOptDefVal will be used
 			// and it is set to client. See addInstallFlags.
@@ -200,6 +206,7 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
 	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
+	f.StringVar(&renderCacheDir, "render-cache-dir", "", "reuse templates rendered before with the same chart and values, e.g. by 'helm lint', from this directory")
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
 	return cmd
@@ -94,6 +94,10 @@ type Configuration struct {
 	// Capabilities describes the capabilities of the Kubernetes cluster.
 	Capabilities *chartutil.Capabilities
 
+	// RenderCache, when set, is shared by the actions rendering templates, so
+	// that a chart rendered before with the same values isn't rendered again.
+	RenderCache engine.RenderCache
+
 	Log func(string, ...interface{})
 }
 
@@ -131,10 +135,12 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 		}
 		e := engine.New(restConfig)
 		e.EnableDNS = enableDNS
+		e.Cache = cfg.RenderCache
 		files, err2 = e.Render(ch, values)
 	} else {
 		var e engine.Engine
 		e.EnableDNS = enableDNS
+		e.Cache = cfg.RenderCache
 		files, err2 = e.Render(ch, values)
 	}
 
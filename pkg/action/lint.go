@@ -17,14 +17,21 @@ limitations under the License.
 package action
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
 
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -37,6 +44,116 @@ type Lint struct {
 	WithSubcharts bool
 	Quiet         bool
 	KubeVersion   *chartutil.KubeVersion
+	// KubeVersions, when more than one is set, are the Kubernetes versions
+	// RunScoped lints the charts for, each in place of KubeVersion.
+	KubeVersions []*chartutil.KubeVersion
+	// APIVersions, when set, are the API versions the templates see as
+	// served by the cluster, e.g. those of a capabilities bundle.
+	APIVersions chartutil.VersionSet
+	// FailSeverity is the lowest severity of the findings which fail the
+	// lint. Unset, it is ErrorSev, or WarningSev with Strict.
+	FailSeverity int
+	// Policies are CEL policies evaluated against every rendered object.
+	Policies []rules.Policy
+	// ReportUnusedIgnores reports ignore comments in templates which don't suppress any finding.
+	ReportUnusedIgnores bool
+	// EnableLookup lets the lookup template function query the cluster
+	// configured in Config, rather than always returning empty results.
+	EnableLookup bool
+	// Config provides the cluster connection used when EnableLookup or
+	// ValidateCRDs is set.
+	Config *Configuration
+	// ReleaseName is the name of the release the charts are rendered for,
+	// so that their render is shared through the RenderCache of Config with
+	// Install for that release. Unset, it is "test-release".
+	ReleaseName string
+	// Cache, when set, stores lint results and reuses them for unchanged
+	// charts and values. It is not used when EnableLookup or ValidateCRDs is
+	// set, as the results then depend on the state of the cluster.
+	Cache *LintCache
+	// ChartCache loads the charts linted, so a chart linted several times,
+	// e.g. for several KubeVersions, is read from its files once while they
+	// are unchanged. NewLint sets it.
+	ChartCache *loader.Cache
+	// Force lints every chart again, ignoring cached results. The new
+	// results are still stored in the cache.
+	Force bool
+	// Snapshot, when set, is the file the rendered templates are compared
+	// with. With UpdateSnapshot, the file is rewritten instead.
+	Snapshot       string
+	UpdateSnapshot bool
+	// EscalateThresholds raises the severity of the findings of a rule by one
+	// level when the rule is found more often in a chart than its threshold.
+	EscalateThresholds map[string]int
+	// SkipRules drops the findings of the rules with these IDs, or whose
+	// path starts with one of them, before they are counted.
+	SkipRules []string
+	// RulesConfig configures the rules which are off without settings.
+	RulesConfig *rules.RulesConfig
+	// StrictRender fails the render on references to missing values.
+	StrictRender bool
+	// SchemaStrict reports each value violating the values.schema.json of a
+	// chart as an error of its own, naming the path of the value.
+	SchemaStrict bool
+	// ExpandValueTemplates runs the string values containing template
+	// syntax through tpl before rendering.
+	ExpandValueTemplates bool
+	// RulesBundle applies centrally managed rule configurations.
+	RulesBundle *lint.RulesBundle
+	// ConventionsOnly only runs the rules about the Helm conventions of the
+	// charts, without rendering them.
+	ConventionsOnly bool
+	// Style checks the layout of the YAML files of the charts.
+	Style bool
+	// Questions checks the questions file of charts targeting Rancher
+	// against their values.
+	Questions bool
+	// RenderedCount reports the number of objects the templates of each
+	// chart render, by kind.
+	RenderedCount bool
+	// Workers is the number of charts RunScoped lints concurrently. Unset,
+	// the charts are linted one after the other.
+	Workers int
+	// OnlySubcharts, when set, limits the subcharts RunScoped lints to those
+	// whose name, alias or scope matches one of the glob patterns.
+	OnlySubcharts []string
+	// SkipRoot makes RunScoped lint only the subcharts of the charts it is
+	// given.
+	SkipRoot bool
+	// SubchartValues makes RunScoped lint the subcharts with the values the
+	// values.yaml files of their parents set for them too, so that they are
+	// validated against the schemas of the subcharts.
+	SubchartValues bool
+	// WarnUnusedValues makes RunScoped warn about the values a subchart
+	// receives from its parents which its values.yaml and values.schema.json
+	// don't define, as these are most likely typos.
+	WarnUnusedValues bool
+	// LintDisabledSubcharts makes RunScoped also lint the subcharts which the
+	// condition or tags of their dependency disable, and which are skipped
+	// otherwise.
+	LintDisabledSubcharts bool
+	// LintEachAlias makes RunScoped lint a subchart the dependencies of its
+	// parent use under several aliases once for each alias, with the values
+	// of that alias, instead of once for all of them.
+	LintEachAlias bool
+	// SubchartDir is the directory of a chart, relative to it, which
+	// RunScoped finds its subcharts in at any depth, charts if unset.
+	SubchartDir string
+	// ScopeNamespaces sets the namespace RunScoped renders subcharts in, by
+	// their scope, alias or name. The other charts are rendered in Namespace.
+	ScopeNamespaces map[string]string
+	// MaxFindings, when set, is the number of messages kept across all the
+	// linted charts. Later messages are dropped as soon as their chart is
+	// linted, but still fail the lint.
+	MaxFindings int
+	// ValidateCRDs validates the rendered custom resources against the
+	// schemas of the CustomResourceDefinitions installed in the cluster
+	// configured in Config.
+	ValidateCRDs bool
+	// Timeout, when positive, limits the time loading and linting each
+	// chart, and finding the subcharts of the charts given to RunScoped, may
+	// take. A chart which doesn't load in time is reported by its path.
+	Timeout time.Duration
 }
 
 // LintResult is the result of Lint
@@ -44,11 +161,37 @@ type LintResult struct {
 	TotalChartsLinted int
 	Messages          []support.Message
 	Errors            []error
+	// CachedCharts lists the paths of the charts whose results were read
+	// from the cache instead of linting them again.
+	CachedCharts []string
+	// Truncated is set when messages were dropped because of MaxFindings.
+	Truncated bool
+	// Dropped is the number of messages dropped because of MaxFindings, and
+	// DroppedSeverity the highest severity among them, so a lint is judged
+	// by all it found.
+	Dropped         int
+	DroppedSeverity int
+}
+
+// truncate keeps the first n messages of r, dropping the others.
+func (r *LintResult) truncate(n int) {
+	if len(r.Messages) <= n {
+		return
+	}
+	for _, msg := range r.Messages[n:] {
+		if msg.Severity > r.DroppedSeverity {
+			r.DroppedSeverity = msg.Severity
+		}
+	}
+	r.Dropped += len(r.Messages) - n
+	// The messages are copied for the dropped ones not to be kept around.
+	r.Messages = append([]support.Message(nil), r.Messages[:n]...)
+	r.Truncated = true
 }
 
 // NewLint creates a new Lint object with the given configuration.
 func NewLint() *Lint {
-	return &Lint{}
+	return &Lint{ChartCache: loader.NewCache()}
 }
 
 // Run executes 'helm Lint' against the given chart.
@@ -57,25 +200,150 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	if l.Strict {
 		lowestTolerance = support.WarningSev
 	}
+	if l.FailSeverity != support.UnknownSev {
+		lowestTolerance = l.FailSeverity
+	}
 	result := &LintResult{}
+	options := l.linterOptions()
+	if l.EnableLookup {
+		config, err := l.clusterConfig("lookup")
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			return result
+		}
+		options = append(options, lint.WithLookupConfig(config))
+	}
+	if l.ValidateCRDs {
+		schemas, err := l.clusterCRDs()
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			return result
+		}
+		options = append(options, lint.WithClusterCRDs(schemas))
+	}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion)
+		var messages []support.Message
+		var cached bool
+		err := withTimeout(l.Timeout, "linting", func(progress *chartProgress) error {
+			progress.at(path)
+			var err error
+			messages, cached, err = l.lintChartCached(path, vals, options)
+			return err
+		})
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			continue
 		}
+		if cached {
+			result.CachedCharts = append(result.CachedCharts, path)
+		}
 
-		result.Messages = append(result.Messages, linter.Messages...)
 		result.TotalChartsLinted++
-		for _, msg := range linter.Messages {
+		for _, msg := range messages {
 			if msg.Severity >= lowestTolerance {
 				result.Errors = append(result.Errors, msg.Err)
 			}
 		}
+		result.Messages = append(result.Messages, messages...)
+		if l.MaxFindings > 0 {
+			result.truncate(l.MaxFindings)
+		}
 	}
 	return result
 }
 
+// lintChartCached lints the chart at path, going through the cache if one is
+// configured. It reports whether the messages were read from the cache.
+func (l *Lint) lintChartCached(path string, vals map[string]interface{}, options []lint.LinterOption) ([]support.Message, bool, error) {
+	if l.Cache == nil || l.EnableLookup || l.ValidateCRDs || l.Snapshot != "" {
+		linter, err := lintChart(path, vals, l.Namespace, options...)
+		return linter.Messages, false, err
+	}
+
+	key, err := l.Cache.Key(path, vals, l)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to compute lint cache key")
+	}
+	if !l.Force {
+		if messages, ok := l.Cache.Get(key); ok {
+			return messages, true, nil
+		}
+	}
+	linter, err := lintChart(path, vals, l.Namespace, options...)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := l.Cache.Set(key, linter.Messages); err != nil {
+		return nil, false, errors.Wrap(err, "unable to write lint cache")
+	}
+	return linter.Messages, false, nil
+}
+
+func (l *Lint) linterOptions() []lint.LinterOption {
+	options := []lint.LinterOption{
+		lint.WithKubeVersion(l.KubeVersion),
+		lint.WithAPIVersions(l.APIVersions),
+		lint.WithPolicies(l.Policies),
+		lint.WithReportUnusedIgnores(l.ReportUnusedIgnores),
+		lint.WithStrictRender(l.StrictRender),
+		lint.WithSchemaStrict(l.SchemaStrict),
+		lint.WithExpandValueTemplates(l.ExpandValueTemplates),
+		lint.WithConventionsOnly(l.ConventionsOnly),
+		lint.WithStyle(l.Style),
+		lint.WithQuestions(l.Questions),
+		lint.WithRenderedCount(l.RenderedCount),
+		lint.WithSkipRules(l.SkipRules),
+		lint.WithChartCache(l.ChartCache),
+		lint.WithReleaseName(l.ReleaseName),
+	}
+	if l.RulesConfig != nil {
+		options = append(options, lint.WithRulesConfig(l.RulesConfig))
+	}
+	if l.RulesBundle != nil {
+		options = append(options, lint.WithRulesBundle(l.RulesBundle))
+	}
+	if len(l.EscalateThresholds) > 0 {
+		options = append(options, lint.WithEscalateThresholds(l.EscalateThresholds))
+	}
+	if l.Snapshot != "" {
+		options = append(options, lint.WithSnapshot(l.Snapshot, l.UpdateSnapshot))
+	}
+	if l.Config != nil && l.Config.RenderCache != nil {
+		options = append(options, lint.WithRenderCache(l.Config.RenderCache))
+	}
+	return options
+}
+
+// clusterConfig returns the configuration of the cluster used by the named
+// feature, failing if no cluster is available.
+func (l *Lint) clusterConfig(feature string) (*rest.Config, error) {
+	if l.Config == nil || l.Config.RESTClientGetter == nil || l.Config.KubeClient == nil {
+		return nil, errors.Errorf("%s is enabled, but no Kubernetes cluster is configured", feature)
+	}
+	if err := l.Config.KubeClient.IsReachable(); err != nil {
+		return nil, errors.Wrapf(err, "%s is enabled, but the Kubernetes cluster is unreachable", feature)
+	}
+	return l.Config.RESTClientGetter.ToRESTConfig()
+}
+
+// clusterCRDs returns the schemas of the CustomResourceDefinitions installed
+// in the cluster.
+func (l *Lint) clusterCRDs() (rules.CRDSchemas, error) {
+	config, err := l.clusterConfig("CRD validation")
+	if err != nil {
+		return nil, err
+	}
+	client, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the Kubernetes cluster")
+	}
+	crds, err := client.ApiextensionsV1().CustomResourceDefinitions().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the CustomResourceDefinitions installed in the cluster")
+	}
+	return rules.NewCRDSchemas(crds.Items)
+}
+
 // HasWarningsOrErrors checks is LintResult has any warnings or errors
 func HasWarningsOrErrors(result *LintResult) bool {
 	for _, msg := range result.Messages {
@@ -86,11 +354,17 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) (support.Linter, error) {
+func lintChart(path string, vals map[string]interface{}, namespace string, options ...lint.LinterOption) (support.Linter, error) {
 	var chartPath string
 	linter := support.Linter{}
 
-	if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
+	if tempDir, convertedPath, ok, err := convertExternalChart(path); ok {
+		if err != nil {
+			return linter, err
+		}
+		defer os.RemoveAll(tempDir)
+		chartPath = convertedPath
+	} else if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
 		tempDir, err := os.MkdirTemp("", "helm-lint")
 		if err != nil {
 			return linter, errors.Wrap(err, "unable to create temp dir to extract tarball")
@@ -119,11 +393,35 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 	} else {
 		chartPath = path
 	}
+	// A chart extracted or converted into a temp dir is removed after the
+	// lint, so it isn't kept in the chart cache.
+	if chartPath != path {
+		options = append(options[:len(options):len(options)], lint.WithChartCache(nil))
+	}
 
 	// Guard: Error out if this is not a chart.
 	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
 		return linter, errors.Wrap(err, "unable to check Chart.yaml file in chart")
 	}
 
-	return lint.AllWithKubeVersion(chartPath, vals, namespace, kubeVersion), nil
+	return lint.RunAll(chartPath, vals, namespace, options...), nil
+}
+
+// convertExternalChart converts the chart at path into a temporary directory
+// if a registered external loader handles it, returning the temporary
+// directory and the chart directory within it.
+func convertExternalChart(path string) (string, string, bool, error) {
+	if !loader.IsExternal(path) {
+		return "", "", false, nil
+	}
+	tempDir, err := os.MkdirTemp("", "helm-lint")
+	if err != nil {
+		return "", "", true, errors.Wrap(err, "unable to create temp dir to convert chart")
+	}
+	chartPath := filepath.Join(tempDir, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if _, err := loader.ConvertExternal(path, chartPath); err != nil {
+		os.RemoveAll(tempDir)
+		return "", "", true, err
+	}
+	return tempDir, chartPath, true, nil
 }
@@ -0,0 +1,192 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/sympath"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// lintCacheVersion is part of every cache key, so that changes to the linter
+// invalidate results cached by older versions.
+const lintCacheVersion = "helm-lint-cache/v1"
+
+// LintCache stores lint results on disk, keyed by the content of the linted
+// chart, the values profile it was linted with and the lint settings.
+//
+// Linting the same chart against several values profiles creates one entry
+// per profile, so changing a profile only invalidates the results for that
+// profile.
+type LintCache struct {
+	// Dir is the directory the results are stored in.
+	Dir string
+}
+
+type cachedMessage struct {
+	Severity int    `json:"severity"`
+	Path     string `json:"path"`
+	RuleID   string `json:"ruleID,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Key returns the cache key for linting the chart at path with the given
+// values profile and lint settings.
+func (c *LintCache) Key(path string, vals map[string]interface{}, l *Lint) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, lintCacheVersion)
+	if err := hashChart(h, path); err != nil {
+		return "", err
+	}
+	if err := hashIgnoreFiles(h, path); err != nil {
+		return "", err
+	}
+
+	if vals == nil {
+		vals = map[string]interface{}{}
+	}
+	settings := struct {
+		Profile     map[string]interface{} `json:"profile"`
+		Namespace   string                 `json:"namespace"`
+		KubeVersion interface{}            `json:"kubeVersion"`
+		Policies    interface{}            `json:"policies"`
+		Unused      bool                   `json:"reportUnusedIgnores"`
+		Escalate    map[string]int         `json:"escalateThresholds"`
+		RulesConfig interface{}            `json:"rulesConfig"`
+		Strict      bool                   `json:"strictRender"`
+		Bundle      interface{}            `json:"rulesBundle"`
+		Conventions bool                   `json:"conventionsOnly"`
+		Expand      bool                   `json:"expandValueTemplates"`
+		Style       bool                   `json:"style"`
+		Questions   bool                   `json:"questions"`
+		APIVersions []string               `json:"apiVersions"`
+		SkipRules   []string               `json:"skipRules"`
+		Schema      bool                   `json:"schemaStrict"`
+		Rendered    bool                   `json:"renderedCount"`
+		Release     string                 `json:"releaseName"`
+	}{vals, l.Namespace, l.KubeVersion, l.Policies, l.ReportUnusedIgnores, l.EscalateThresholds, l.RulesConfig, l.StrictRender, l.RulesBundle, l.ConventionsOnly, l.ExpandValueTemplates, l.Style, l.Questions, l.APIVersions, l.SkipRules, l.SchemaStrict, l.RenderedCount, l.ReleaseName}
+	// encoding/json sorts map keys, so equal profiles always hash the same.
+	if err := json.NewEncoder(h).Encode(settings); err != nil {
+		return "", errors.Wrap(err, "unable to hash lint settings")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashChart writes the names and contents of all files of the chart at path,
+// which is either a directory or an archive, to h. Symlinks are followed, as
+// the loader does, so symlinked subcharts are hashed with their contents.
+func hashChart(h io.Writer, path string) error {
+	return sympath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		io.WriteString(h, filepath.ToSlash(rel)+"\x00")
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// hashIgnoreFiles writes the contents of the ignore files applying to the
+// chart at path to h.
+func hashIgnoreFiles(h io.Writer, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for _, filename := range lint.IgnoreFiles(abs) {
+		data, err := os.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		io.WriteString(h, filename+"\x00")
+		h.Write(data)
+	}
+	return nil
+}
+
+// Get returns the messages cached under key, if any.
+func (c *LintCache) Get(key string) ([]support.Message, bool) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var cached []cachedMessage
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	msgs := make([]support.Message, 0, len(cached))
+	for _, m := range cached {
+		msg := support.NewMessage(m.Severity, m.Path, errors.New(m.Text))
+		msg.RuleID = m.RuleID
+		msgs = append(msgs, msg)
+	}
+	return msgs, true
+}
+
+// Set stores msgs under key.
+func (c *LintCache) Set(key string, msgs []support.Message) error {
+	cached := make([]cachedMessage, 0, len(msgs))
+	for _, m := range msgs {
+		cached = append(cached, cachedMessage{Severity: m.Severity, Path: m.Path, RuleID: m.RuleID, Text: m.Err.Error()})
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create lint cache directory")
+	}
+	// Write to a temporary file first, so concurrent runs never read a
+	// partially written entry.
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.Dir, key+".json"))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestLintCacheKeyedByProfile(t *testing.T) {
+	const chartPath = "testdata/charts/decompressedchart/"
+	dev := map[string]interface{}{"replicaCount": 1}
+	prod := map[string]interface{}{"replicaCount": 3}
+
+	cache := &LintCache{Dir: t.TempDir()}
+	client := NewLint()
+	client.Namespace = namespace
+	client.Cache = cache
+
+	keys := func() (string, string) {
+		t.Helper()
+		devKey, err := cache.Key(chartPath, dev, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prodKey, err := cache.Key(chartPath, prod, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return devKey, prodKey
+	}
+
+	client.Run([]string{chartPath}, dev)
+	client.Run([]string{chartPath}, prod)
+	devKey, prodKey := keys()
+	if devKey == prodKey {
+		t.Fatal("expected different cache keys for different values profiles")
+	}
+	for _, key := range []string{devKey, prodKey} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected a cache entry for key %s", key)
+		}
+	}
+
+	devInfo, err := os.Stat(filepath.Join(cache.Dir, devKey+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing one profile must only invalidate its own entry.
+	prod["replicaCount"] = 5
+	newDevKey, newProdKey := keys()
+	if newDevKey != devKey {
+		t.Error("expected the unchanged profile to keep its cache key")
+	}
+	if newProdKey == prodKey {
+		t.Error("expected the changed profile to get a new cache key")
+	}
+	if _, ok := cache.Get(newProdKey); ok {
+		t.Error("expected no cache entry for the changed profile")
+	}
+
+	client.Run([]string{chartPath}, dev)
+	client.Run([]string{chartPath}, prod)
+	if _, ok := cache.Get(newProdKey); !ok {
+		t.Error("expected the changed profile to be cached after linting")
+	}
+	info, err := os.Stat(filepath.Join(cache.Dir, devKey+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(devInfo.ModTime()) {
+		t.Error("expected the unchanged profile's entry not to be rewritten")
+	}
+}
+
+func TestLintCacheRoundTrip(t *testing.T) {
+	client := NewLint()
+	client.Namespace = namespace
+	client.Strict = true
+
+	want := client.Run([]string{chartWithNoTemplatesDir}, values)
+
+	client.Cache = &LintCache{Dir: t.TempDir()}
+	client.Run([]string{chartWithNoTemplatesDir}, values)
+	got := client.Run([]string{chartWithNoTemplatesDir}, values)
+
+	if len(got.Messages) != len(want.Messages) || len(got.Errors) != len(want.Errors) {
+		t.Fatalf("expected %d messages and %d errors from the cache, got %d and %d",
+			len(want.Messages), len(want.Errors), len(got.Messages), len(got.Errors))
+	}
+	for i := range want.Messages {
+		if got.Messages[i].Error() != want.Messages[i].Error() || got.Messages[i].RuleID != want.Messages[i].RuleID {
+			t.Errorf("expected cached message %q, got %q", want.Messages[i], got.Messages[i])
+		}
+	}
+}
+
+func TestLintCacheSkipsUnchangedCharts(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"parent", "sub"} {
+		path, err := chartutil.Create(name, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	client := NewLint()
+	client.Namespace = namespace
+	client.Cache = &LintCache{Dir: t.TempDir()}
+
+	if result := client.Run(paths, values); len(result.CachedCharts) != 0 {
+		t.Fatalf("expected no cached charts on the first run, got %v", result.CachedCharts)
+	}
+	if result := client.Run(paths, values); !reflect.DeepEqual(result.CachedCharts, paths) {
+		t.Errorf("expected all charts to be served from the cache, got %v", result.CachedCharts)
+	}
+
+	valuesFile := filepath.Join(paths[1], chartutil.ValuesfileName)
+	if err := os.WriteFile(valuesFile, []byte("replicaCount: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := client.Run(paths, values); !reflect.DeepEqual(result.CachedCharts, paths[:1]) {
+		t.Errorf("expected only the unchanged chart to be served from the cache, got %v", result.CachedCharts)
+	}
+
+	client.Force = true
+	if result := client.Run(paths, values); len(result.CachedCharts) != 0 {
+		t.Errorf("expected --force to bypass the cache, got %v", result.CachedCharts)
+	}
+}
+
+func TestLintCacheKeyWithSymlinkedSubchart(t *testing.T) {
+	dir := t.TempDir()
+	parent, err := chartutil.Create("parent", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := chartutil.Create("sub", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(sub, filepath.Join(parent, "charts", "sub")); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := &LintCache{Dir: t.TempDir()}
+	client := NewLint()
+	client.Namespace = namespace
+	key, err := cache.Key(parent, values, client)
+	if err != nil {
+		t.Fatalf("expected a cache key for a chart with a symlinked subchart, got %s", err)
+	}
+
+	// The contents of the linked subchart are part of the key.
+	valuesFile := filepath.Join(sub, chartutil.ValuesfileName)
+	if err := os.WriteFile(valuesFile, []byte("replicaCount: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := cache.Key(parent, values, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == key {
+		t.Error("expected a change to the symlinked subchart to change the cache key")
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// ownerAnnotation is the Chart.yaml annotation naming the owner of a chart,
+// e.g. the team its lint findings are routed to.
+const ownerAnnotation = "helm.sh/lint-owner"
+
+// codeOwnersFile is the name of the files assigning owners to paths, in the
+// format of GitHub's CODEOWNERS files.
+const codeOwnersFile = "CODEOWNERS"
+
+// lintChartOwner returns the owner of the chart at path: the one set by its
+// ownerAnnotation or, failing that, the owners a CODEOWNERS file assigns to
+// it. It returns an empty string when the owner is unknown.
+func lintChartOwner(cache *loader.Cache, path string) string {
+	if md := lintChartMetadata(cache, path); md != nil {
+		if owner := strings.TrimSpace(md.Annotations[ownerAnnotation]); owner != "" {
+			return owner
+		}
+	}
+	return strings.Join(codeOwners(path), ", ")
+}
+
+// codeOwners returns the owners of the chart at path in the nearest
+// CODEOWNERS file with a pattern matching it. The files are looked up in the
+// chart directory and its parents up to the root of the enclosing git
+// repository, where they may also be in the .github and docs directories.
+func codeOwners(chartPath string) []string {
+	abs, err := filepath.Abs(chartPath)
+	if err != nil {
+		return nil
+	}
+	dir := abs
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		dir = filepath.Dir(abs)
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		files := []string{filepath.Join(dir, codeOwnersFile)}
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		root := err == nil || filepath.Dir(dir) == dir
+		if root {
+			files = []string{filepath.Join(dir, ".github", codeOwnersFile), files[0], filepath.Join(dir, "docs", codeOwnersFile)}
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return nil
+		}
+		for _, filename := range files {
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				continue
+			}
+			if owners, ok := matchCodeOwners(data, filepath.ToSlash(rel)); ok {
+				return owners
+			}
+		}
+		if root {
+			return nil
+		}
+	}
+}
+
+// matchCodeOwners returns the owners of the last line of a CODEOWNERS file
+// whose pattern matches rel, the path of a chart relative to the file.
+func matchCodeOwners(data []byte, rel string) ([]string, bool) {
+	var owners []string
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if codeOwnersMatch(fields[0], rel) {
+			owners, found = fields[1:], true
+		}
+	}
+	return owners, found
+}
+
+// codeOwnersMatch reports whether a CODEOWNERS pattern matches the chart at
+// rel, or one of the directories containing it. As in .gitignore files, a
+// pattern with a slash other than a trailing one is relative to the file, and
+// any other pattern matches at any depth.
+func codeOwnersMatch(pattern, rel string) bool {
+	if pattern == "*" || pattern == "/**" {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimSuffix(strings.Trim(pattern, "/"), "/**")
+	segments := strings.Split(rel, "/")
+	for i := range segments {
+		candidate := segments[i]
+		if anchored {
+			candidate = strings.Join(segments[:i+1], "/")
+		}
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
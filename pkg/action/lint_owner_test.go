@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintChartOwner(t *testing.T) {
+	repo := t.TempDir()
+	files := map[string]string{
+		".github/CODEOWNERS":                 "*  @example/maintainers\n/charts/app/  @example/app\nredis  @example/cache\n",
+		"charts/app/Chart.yaml":              "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"charts/app/charts/redis/Chart.yaml": "apiVersion: v2\nname: redis\nversion: 0.1.0\n",
+		"charts/web/Chart.yaml":              "apiVersion: v2\nname: web\nversion: 0.1.0\nannotations:\n  helm.sh/lint-owner: \"@example/web\"\n",
+		"charts/jobs/Chart.yaml":             "apiVersion: v2\nname: jobs\nversion: 0.1.0\n",
+		"charts/jobs/CODEOWNERS":             "# unowned\n*\n",
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		filename := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		chart string
+		owner string
+	}{
+		{"charts/app", "@example/app"},
+		{"charts/app/charts/redis", "@example/cache"},
+		{"charts/web", "@example/web"},
+		{"charts/jobs", ""},
+		{"charts/other", "@example/maintainers"},
+	}
+	for _, tt := range tests {
+		if owner := lintChartOwner(nil, filepath.Join(repo, tt.chart)); owner != tt.owner {
+			t.Errorf("Expected %s to be owned by %q, got %q", tt.chart, tt.owner, owner)
+		}
+	}
+}
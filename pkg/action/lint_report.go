@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ScopedLintReport collects the results of RunScoped for all the scopes
+// linted, with the counts they are summarized by.
+type ScopedLintReport struct {
+	// Results are the results of RunScoped, in its order.
+	Results []ScopedResult
+	// Failed is the number of results which failed the lint.
+	Failed int
+	// ErrorsOrWarnings is the number of results with warnings or errors.
+	ErrorsOrWarnings int
+}
+
+// NewScopedLintReport builds the report of the results of RunScoped.
+func NewScopedLintReport(results []ScopedResult) *ScopedLintReport {
+	report := &ScopedLintReport{Results: results}
+	for _, r := range results {
+		if len(r.Result.Errors) != 0 {
+			report.Failed++
+		}
+		if HasWarningsOrErrors(r.Result) {
+			report.ErrorsOrWarnings++
+		}
+	}
+	return report
+}
+
+// HighestSeverity returns the highest severity of the messages of all the
+// results, including those MaxFindings dropped, support.ErrorSev if a chart
+// failed without any, e.g. as it couldn't be loaded, or support.UnknownSev if
+// nothing was found.
+func (r *ScopedLintReport) HighestSeverity() int {
+	highest := support.UnknownSev
+	for _, res := range r.Results {
+		if len(res.Result.Messages) == 0 && len(res.Result.Errors) != 0 && !res.Result.Truncated {
+			return support.ErrorSev
+		}
+		if res.Result.DroppedSeverity > highest {
+			highest = res.Result.DroppedSeverity
+		}
+		for _, msg := range res.Result.Messages {
+			if msg.Severity > highest {
+				highest = msg.Severity
+			}
+		}
+	}
+	return highest
+}
+
+// FailedScopes returns the results of the charts which failed the lint.
+func (r *ScopedLintReport) FailedScopes() []ScopedResult {
+	var failed []ScopedResult
+	for _, res := range r.Results {
+		if len(res.Result.Errors) != 0 {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
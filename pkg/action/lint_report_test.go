@@ -0,0 +1,59 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestScopedLintReport(t *testing.T) {
+	scoped := func(scope string, result *LintResult) ScopedResult {
+		return ScopedResult{ScopedChart: ScopedChart{Path: "chart/" + scope, Scope: scope}, Result: result}
+	}
+	info := support.Message{Severity: support.InfoSev, Path: "Chart.yaml", Err: errors.New("icon is recommended")}
+	warning := support.Message{Severity: support.WarningSev, Path: "templates/hpa.yaml", Err: errors.New("deprecated")}
+	failure := support.Message{Severity: support.ErrorSev, Path: "values.yaml", Err: errors.New("invalid")}
+
+	report := NewScopedLintReport([]ScopedResult{
+		scoped(".", &LintResult{Messages: []support.Message{info}}),
+		scoped("charts/web", &LintResult{Messages: []support.Message{warning}}),
+		scoped("charts/db", &LintResult{Messages: []support.Message{failure}, Errors: []error{failure.Err}}),
+	})
+	if report.Failed != 1 || report.ErrorsOrWarnings != 2 {
+		t.Errorf("Expected 1 failed and 2 with warnings or errors, got %d and %d", report.Failed, report.ErrorsOrWarnings)
+	}
+	if failed := report.FailedScopes(); len(failed) != 1 || failed[0].Scope != "charts/db" {
+		t.Errorf("Expected charts/db to fail, got %v", failed)
+	}
+	if s := report.HighestSeverity(); s != support.ErrorSev {
+		t.Errorf("Expected the highest severity to be %d, got %d", support.ErrorSev, s)
+	}
+
+	if s := NewScopedLintReport([]ScopedResult{scoped(".", &LintResult{Messages: []support.Message{info, warning}})}).HighestSeverity(); s != support.WarningSev {
+		t.Errorf("Expected the highest severity to be %d, got %d", support.WarningSev, s)
+	}
+	if s := NewScopedLintReport(nil).HighestSeverity(); s != support.UnknownSev {
+		t.Errorf("Expected the highest severity of no results to be %d, got %d", support.UnknownSev, s)
+	}
+	unloadable := NewScopedLintReport([]ScopedResult{scoped(".", &LintResult{Errors: []error{errors.New("unable to load chart")}})})
+	if s := unloadable.HighestSeverity(); s != support.ErrorSev || len(unloadable.FailedScopes()) != 1 {
+		t.Errorf("Expected a chart which can't be loaded to fail with an error, got %d", s)
+	}
+}
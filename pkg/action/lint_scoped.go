@@ -0,0 +1,863 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/mitchellh/copystructure"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ScopedChart is a chart linted by RunScoped: one of the charts it is given
+// or, with WithSubcharts, one of their subcharts.
+type ScopedChart struct {
+	// Path is the location of the chart, a directory or an archive.
+	Path string
+	// Name is the name in the Chart.yaml of the chart, if it can be read.
+	Name string
+	// Scope is the location of the chart within the chart it was found in
+	// as a subchart, e.g. charts/database, or . for the charts given to
+	// RunScoped.
+	Scope string
+	// Parent is the path of the chart this chart is a dependency of. It is
+	// empty for the charts given to RunScoped.
+	Parent string
+	// Owner is the owner of the chart, set by the helm.sh/lint-owner
+	// annotation of its Chart.yaml or by a CODEOWNERS file, if known.
+	Owner string
+	// Aliases are the aliases of the dependencies of the parent which all
+	// map to this chart, when there are several, e.g. redis-primary and
+	// redis-replica both using the redis chart.
+	Aliases []string
+	// Alias is the one of Aliases the chart is linted for, with
+	// LintEachAlias. Otherwise the chart is linted once for all of them.
+	Alias string
+}
+
+// ScopedResult is the result of linting a chart with RunScoped.
+type ScopedResult struct {
+	ScopedChart
+	// RulesConfig is the rules config the chart was linted with.
+	RulesConfig *rules.RulesConfig
+	// KubeVersion is the Kubernetes version the chart was linted for, when
+	// linting for several KubeVersions.
+	KubeVersion *chartutil.KubeVersion
+	Result      *LintResult
+}
+
+// RunScoped lints the charts at paths and, with WithSubcharts, their
+// subcharts at any depth. Each chart is linted with the values of its scope
+// and with the rules config in RulesConfig layered over the one of
+// RulesBundle, then with the ci/lint-rules.yaml files of the chart and its
+// parents. It is rendered in the namespace ScopeNamespaces sets for it, or
+// Namespace.
+// Only the charts kept by OnlySubcharts and SkipRoot are linted, and unless
+// LintDisabledSubcharts is set, only the subcharts their parents enable with
+// these values. Up to Workers charts are linted concurrently. The results
+// are in the order of FindScopedCharts, and MaxFindings applies to all of
+// them in that order. Subcharts which share their values key with another
+// subchart of the same parent are reported with a warning, as they can't be
+// told apart. A subchart used under several aliases is linted once, with the
+// values of its name, unless LintEachAlias is set.
+//
+// With several KubeVersions, the charts are linted once for each of them,
+// and the results are grouped by version in the order of KubeVersions.
+func (l *Lint) RunScoped(paths []string, vals map[string]interface{}) ([]ScopedResult, error) {
+	budget := newFindingsBudget(l.MaxFindings)
+	if len(l.KubeVersions) < 2 {
+		return l.runScoped(paths, vals, budget)
+	}
+	if l.Snapshot != "" {
+		return nil, errors.New("a snapshot can only be used with a single Kubernetes version")
+	}
+	var results []ScopedResult
+	for _, v := range l.KubeVersions {
+		linter := *l
+		linter.KubeVersion = v
+		linter.KubeVersions = nil
+		versionResults, err := linter.runScoped(paths, vals, budget)
+		if err != nil {
+			return nil, err
+		}
+		for i := range versionResults {
+			versionResults[i].KubeVersion = v
+		}
+		results = append(results, versionResults...)
+	}
+	return results, nil
+}
+
+func (l *Lint) runScoped(paths []string, vals map[string]interface{}, budget *findingsBudget) ([]ScopedResult, error) {
+	all, err := l.findScopedCharts(paths)
+	if err != nil {
+		return nil, err
+	}
+	// The parents of the charts which are filtered out are still needed to
+	// resolve the values and rules config of their subcharts.
+	parents := map[string]string{}
+	for _, c := range all {
+		if c.Parent != "" {
+			parents[c.Path] = c.Parent
+		}
+	}
+	enabled := all
+	if l.LintEachAlias {
+		enabled = eachAlias(all)
+	}
+	if !l.LintDisabledSubcharts {
+		enabled = enabledScopedCharts(l.ChartCache, enabled, vals, parents)
+	}
+	charts, _, err := l.filterScopedCharts(enabled)
+	if err != nil {
+		return nil, err
+	}
+	if l.Snapshot != "" && len(charts) > 1 {
+		return nil, errors.New("a snapshot can only be used when linting a single chart")
+	}
+	scopes := &rulesConfigScopes{global: l.RulesConfig, parents: parents, configs: map[string]*rules.RulesConfig{}}
+	bundle := l.RulesBundle
+	if bundle != nil {
+		scopes.global = rules.MergeRulesConfig(bundle.Rules, l.RulesConfig)
+		// The rules of the bundle are in the config of every scope, and
+		// mustn't be layered again when the charts are linted.
+		withoutRules := *bundle
+		withoutRules.Rules = nil
+		bundle = &withoutRules
+	}
+	results := make([]ScopedResult, len(charts))
+	for i, c := range charts {
+		config, err := scopes.config(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = ScopedResult{ScopedChart: c, RulesConfig: config}
+	}
+
+	collisions := scopeCollisions(l.ChartCache, all)
+	budget.start(len(results))
+	workers := l.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(results); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				linter := *l
+				linter.RulesConfig = results[i].RulesConfig
+				linter.RulesBundle = bundle
+				linter.Namespace = l.scopeNamespace(results[i].ScopedChart)
+				scoped := scopeAliasValues(l.ChartCache, vals, results[i].Path, results[i].Alias, parents, l.SubchartValues)
+				results[i].Result = linter.Run([]string{results[i].Path}, scoped)
+				if l.WarnUnusedValues && results[i].Parent != "" {
+					results[i].Result.Messages = append(results[i].Result.Messages, lint.SkipRules(unusedValues(l.ChartCache, results[i].Path, scoped), l.SkipRules)...)
+				}
+				l.addScopeWarnings(results[i], collisions)
+				budget.add(results, i)
+			}
+		}()
+	}
+	for i := range results {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results, nil
+}
+
+// addScopeWarnings warns about the subchart of r sharing its values key with
+// the others of collisions, and about it not being declared by its parent.
+func (l *Lint) addScopeWarnings(r ScopedResult, collisions map[string][]string) {
+	if others, ok := collisions[r.Path]; ok {
+		r.Result.Messages = append(r.Result.Messages, lint.SkipRules([]support.Message{{
+			Severity: support.WarningSev,
+			Path:     chartutil.ChartfileName,
+			Err:      errors.Errorf("%s shares the values key %q of its parent with %s, so they receive the same values and scoped settings", r.Scope, dependencyKey(l.ChartCache, r.Parent, r.Path), strings.Join(others, ", ")),
+			RuleID:   rules.RuleDependencyAlias,
+		}}, l.SkipRules)...)
+	}
+	if r.Parent != "" && !dependencyDeclared(l.ChartCache, r.ScopedChart) {
+		r.Result.Messages = append(r.Result.Messages, lint.SkipRules([]support.Message{{
+			Severity: support.WarningSev,
+			Path:     chartutil.ChartfileName,
+			Err:      errors.Errorf("%s is unpacked in the %s directory of its parent but isn't declared as one of its dependencies", r.Scope, filepath.Base(filepath.Dir(r.Path))),
+			RuleID:   rules.RuleChartDependencies,
+		}}, l.SkipRules)...)
+	}
+}
+
+// findingsBudget applies MaxFindings to the results of RunScoped, in their
+// order, as they are linted, so the messages past it aren't kept while the
+// other charts are linted. A nil budget keeps every message.
+type findingsBudget struct {
+	mu   sync.Mutex
+	left int
+	// done marks the results of the current runScoped which were linted,
+	// and counted the number of them, in order, taken from the budget.
+	done    []bool
+	counted int
+}
+
+func newFindingsBudget(maxFindings int) *findingsBudget {
+	if maxFindings <= 0 {
+		return nil
+	}
+	return &findingsBudget{left: maxFindings}
+}
+
+// start tracks the n results of a runScoped.
+func (b *findingsBudget) start(n int) {
+	if b == nil {
+		return
+	}
+	b.done, b.counted = make([]bool, n), 0
+}
+
+// add takes the messages of results[i], linted, from the budget once the
+// results before it are, dropping them right away if it is spent.
+func (b *findingsBudget) add(results []ScopedResult, i int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done[i] = true
+	if b.left == 0 {
+		results[i].Result.truncate(0)
+	}
+	for b.counted < len(results) && b.done[b.counted] {
+		r := results[b.counted].Result
+		r.truncate(b.left)
+		b.left -= len(r.Messages)
+		b.counted++
+	}
+}
+
+// scopeCollisions finds the subcharts whose values key in their parent, their
+// alias or name, is the key of another subchart of the same parent. At render
+// time, both read the values under that key, and the values, namespace and
+// rules config RunScoped resolves for one also apply to the others, so the
+// settings meant for one of them silently end up on both. It returns the
+// scopes of the colliding charts by the path of each.
+func scopeCollisions(cache *loader.Cache, charts []ScopedChart) map[string][]string {
+	type siblingKey struct{ parent, key string }
+	groups := map[siblingKey][]ScopedChart{}
+	var keys []siblingKey
+	for _, c := range charts {
+		if c.Parent == "" {
+			continue
+		}
+		k := siblingKey{c.Parent, dependencyKey(cache, c.Parent, c.Path)}
+		if k.key == "" {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+	collisions := map[string][]string{}
+	for _, k := range keys {
+		group := groups[k]
+		if len(group) < 2 {
+			continue
+		}
+		for _, c := range group {
+			for _, other := range group {
+				if other.Path != c.Path {
+					collisions[c.Path] = append(collisions[c.Path], other.Scope)
+				}
+			}
+		}
+	}
+	return collisions
+}
+
+// enabledScopedCharts drops the subcharts disabled by the condition or tags
+// of their dependency in the Chart.yaml of their parent, as they are not
+// rendered, and the subcharts of these. As at render time, the condition is
+// looked up in the values of the parent, coalesced with its values.yaml, and
+// the tags in the values of the chart given to RunScoped. A subchart which
+// its parent lists under several aliases is kept if any of them is enabled.
+func enabledScopedCharts(cache *loader.Cache, charts []ScopedChart, vals map[string]interface{}, parents map[string]string) []ScopedChart {
+	disabled := map[string]bool{}
+	var enabled []ScopedChart
+	for _, c := range charts {
+		if c.Parent != "" && (disabled[c.Parent] || !dependencyEnabled(cache, c, vals, parents)) {
+			disabled[c.Path] = true
+			continue
+		}
+		enabled = append(enabled, c)
+	}
+	return enabled
+}
+
+// dependencyEnabled reports whether the parent of the subchart c enables it,
+// following chartutil.ProcessDependencies.
+func dependencyEnabled(cache *loader.Cache, c ScopedChart, vals map[string]interface{}, parents map[string]string) bool {
+	md, err := chartutil.LoadChartfile(filepath.Join(c.Parent, chartutil.ChartfileName))
+	if err != nil {
+		return true
+	}
+	var version string
+	if sub := lintChartMetadata(cache, c.Path); sub != nil {
+		version = sub.Version
+	}
+	var deps []*chart.Dependency
+	for _, d := range md.Dependencies {
+		if dependencyMatches(d, c.Name, version) && (c.Alias == "" || d.Alias == c.Alias) {
+			deps = append(deps, d)
+		}
+	}
+	if len(deps) == 0 {
+		return true
+	}
+
+	root := c.Parent
+	for parents[root] != "" {
+		root = parents[root]
+	}
+	tags, _ := chartutil.Values(withChartDefaults(cache, vals, root)).Table("tags")
+	parentVals := chartutil.Values(withChartDefaults(cache, scopeValues(cache, vals, c.Parent, parents, true), c.Parent))
+	for _, d := range deps {
+		if conditionEnabled(d, parentVals, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyDeclared reports whether the subchart c matches one of the
+// dependencies in the Chart.yaml of its parent. The dependencies of apiVersion
+// v1 charts are in their requirements.yaml, which isn't read, so these are
+// assumed to be declared, as are the subcharts which can't be read.
+func dependencyDeclared(cache *loader.Cache, c ScopedChart) bool {
+	md, err := chartutil.LoadChartfile(filepath.Join(c.Parent, chartutil.ChartfileName))
+	if err != nil || md.APIVersion == chart.APIVersionV1 || c.Name == "" {
+		return true
+	}
+	var version string
+	if sub := lintChartMetadata(cache, c.Path); sub != nil {
+		version = sub.Version
+	}
+	for _, d := range md.Dependencies {
+		if dependencyMatches(d, c.Name, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionEnabled evaluates the condition of a dependency, falling back to
+// its tags when none of the condition paths is set to a boolean. A dependency
+// with neither is enabled.
+func conditionEnabled(d *chart.Dependency, vals chartutil.Values, tags chartutil.Values) bool {
+	for _, condition := range strings.Split(strings.TrimSpace(d.Condition), ",") {
+		if condition == "" {
+			continue
+		}
+		if v, err := vals.PathValue(condition); err == nil {
+			if b, ok := v.(bool); ok {
+				return b
+			}
+		}
+	}
+	hasTrue, hasFalse := false, false
+	for _, tag := range d.Tags {
+		if b, ok := tags[tag].(bool); ok {
+			hasTrue = hasTrue || b
+			hasFalse = hasFalse || !b
+		}
+	}
+	return hasTrue || !hasFalse
+}
+
+// scopeNamespace returns the namespace a chart is rendered in: the one
+// ScopeNamespaces sets for its scope, alias or name, in that order, or
+// Namespace.
+func (l *Lint) scopeNamespace(c ScopedChart) string {
+	if c.Parent == "" || len(l.ScopeNamespaces) == 0 {
+		return l.Namespace
+	}
+	for _, key := range []string{c.Scope, dependencyKey(l.ChartCache, c.Parent, c.Path), c.Name} {
+		if ns, ok := l.ScopeNamespaces[key]; ok {
+			return ns
+		}
+	}
+	return l.Namespace
+}
+
+// ScopedCharts returns the charts RunScoped lints for paths, and the patterns
+// of OnlySubcharts which match none of the subcharts.
+func (l *Lint) ScopedCharts(paths []string) ([]ScopedChart, []string, error) {
+	all, err := l.findScopedCharts(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l.filterScopedCharts(all)
+}
+
+// findScopedCharts runs FindScopedCharts with WithSubcharts and
+// SubchartDir, within Timeout. A SubchartDir other than charts must exist in
+// each chart directory at paths, as it is likely mistyped otherwise.
+func (l *Lint) findScopedCharts(paths []string) ([]ScopedChart, error) {
+	subchartDir := defaultSubchartDir
+	if l.SubchartDir != "" {
+		subchartDir = filepath.Clean(l.SubchartDir)
+	}
+	if l.WithSubcharts && subchartDir != defaultSubchartDir {
+		if !filepath.IsLocal(subchartDir) {
+			return nil, errors.Errorf("subchart directory %s must be a relative path within the charts", l.SubchartDir)
+		}
+		for _, p := range paths {
+			if info, err := os.Stat(p); err != nil || !info.IsDir() {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(p, subchartDir)); err != nil || !info.IsDir() {
+				return nil, errors.Errorf("subchart directory %s doesn't exist in chart %s", l.SubchartDir, p)
+			}
+		}
+	}
+	var all []ScopedChart
+	err := withTimeout(l.Timeout, "loading", func(progress *chartProgress) error {
+		all = findScopedCharts(l.ChartCache, paths, l.WithSubcharts, subchartDir, progress)
+		return nil
+	})
+	return all, err
+}
+
+// filterScopedCharts keeps the subcharts matching OnlySubcharts, if any are
+// set, and the charts given to RunScoped unless SkipRoot is set. A pattern
+// matches the name of a subchart, the alias its parent gives it, or its
+// scope.
+func (l *Lint) filterScopedCharts(charts []ScopedChart) ([]ScopedChart, []string, error) {
+	matched := make([]bool, len(l.OnlySubcharts))
+	var kept []ScopedChart
+	for _, c := range charts {
+		if c.Parent == "" {
+			if !l.SkipRoot {
+				kept = append(kept, c)
+			}
+			continue
+		}
+		if len(l.OnlySubcharts) == 0 {
+			kept = append(kept, c)
+			continue
+		}
+		keys := []string{c.Name, dependencyKey(l.ChartCache, c.Parent, c.Path), c.Scope}
+		if c.Alias != "" {
+			keys = append(keys, c.Alias)
+		} else {
+			keys = append(keys, c.Aliases...)
+		}
+		match := false
+		for i, pattern := range l.OnlySubcharts {
+			for _, key := range keys {
+				ok, err := path.Match(pattern, key)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "invalid subchart pattern %q", pattern)
+				}
+				if ok {
+					matched[i], match = true, true
+					break
+				}
+			}
+		}
+		if match {
+			kept = append(kept, c)
+		}
+	}
+	var unmatched []string
+	for i, pattern := range l.OnlySubcharts {
+		if !matched[i] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return kept, unmatched, nil
+}
+
+// FindScopedCharts returns the charts at paths followed, if withSubcharts is
+// set, by the subcharts in their charts directories at any depth. Symlinked
+// subcharts are followed; a chart whose resolved location was already found
+// is skipped, which also stops symlink cycles.
+func FindScopedCharts(paths []string, withSubcharts bool) []ScopedChart {
+	return findScopedCharts(nil, paths, withSubcharts, defaultSubchartDir, nil)
+}
+
+// defaultSubchartDir is the directory subcharts are unpacked in by helm
+// dependency update, and loaded from.
+const defaultSubchartDir = "charts"
+
+func findScopedCharts(cache *loader.Cache, paths []string, withSubcharts bool, subchartDir string, progress *chartProgress) []ScopedChart {
+	var charts []ScopedChart
+	visited := map[string]bool{}
+	for _, p := range paths {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			visited[real] = true
+		}
+	}
+	for _, p := range paths {
+		progress.at(p)
+		charts = append(charts, ScopedChart{Path: p, Name: lintChartName(cache, p), Scope: ".", Owner: lintChartOwner(cache, p)})
+	}
+	if withSubcharts {
+		for _, p := range paths {
+			charts = append(charts, findSubcharts(cache, filepath.Clean(p), filepath.Clean(p), subchartDir, visited, progress)...)
+		}
+	}
+	return charts
+}
+
+// findSubcharts returns the subcharts in the subchartDir directory of the
+// chart at dir and, recursively, their own subcharts. root is the chart given
+// to FindScopedCharts the scopes are relative to.
+func findSubcharts(cache *loader.Cache, root, dir, subchartDir string, visited map[string]bool, progress *chartProgress) []ScopedChart {
+	chartsDir := filepath.Join(dir, subchartDir)
+	entries, err := os.ReadDir(chartsDir)
+	if err != nil {
+		return nil
+	}
+	var found []ScopedChart
+	for _, e := range entries {
+		path := filepath.Join(chartsDir, e.Name())
+		progress.at(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
+				found = append(found, newScopedChart(cache, root, dir, path))
+			}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, chartutil.ChartfileName)); err != nil {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil || visited[real] {
+			continue
+		}
+		visited[real] = true
+		found = append(found, newScopedChart(cache, root, dir, path))
+		found = append(found, findSubcharts(cache, root, path, subchartDir, visited, progress)...)
+	}
+	return found
+}
+
+func newScopedChart(cache *loader.Cache, root, parent, path string) ScopedChart {
+	scope, err := filepath.Rel(root, path)
+	if err != nil {
+		scope = path
+	}
+	c := ScopedChart{Path: path, Name: lintChartName(cache, path), Scope: filepath.ToSlash(scope), Parent: parent, Owner: lintChartOwner(cache, path)}
+	if aliases := dependencyAliases(cache, parent, path); len(aliases) > 1 {
+		c.Aliases = aliases
+	}
+	return c
+}
+
+// eachAlias replaces the charts mapped to by several aliases with a copy for
+// each alias, keeping their order.
+func eachAlias(charts []ScopedChart) []ScopedChart {
+	var expanded []ScopedChart
+	for _, c := range charts {
+		if len(c.Aliases) < 2 {
+			expanded = append(expanded, c)
+			continue
+		}
+		for _, alias := range c.Aliases {
+			aliased := c
+			aliased.Alias = alias
+			expanded = append(expanded, aliased)
+		}
+	}
+	return expanded
+}
+
+// rulesConfigScopes resolves the rules config of every linted chart. The
+// global config applies to all charts. The ci/lint-rules.yaml file of a chart
+// is layered over the config of its scope, and applies to the chart and its
+// subcharts.
+type rulesConfigScopes struct {
+	global  *rules.RulesConfig
+	parents map[string]string
+	configs map[string]*rules.RulesConfig
+}
+
+func (s *rulesConfigScopes) config(path string) (*rules.RulesConfig, error) {
+	path = filepath.Clean(path)
+	if config, ok := s.configs[path]; ok {
+		return config, nil
+	}
+	base := s.global
+	if parent, ok := s.parents[path]; ok {
+		config, err := s.config(parent)
+		if err != nil {
+			return nil, err
+		}
+		base = config
+	}
+	var local *rules.RulesConfig
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if local, err = rules.LoadLocalRulesConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	config := rules.MergeRulesConfig(base, local)
+	s.configs[path] = config
+	return config, nil
+}
+
+// scopeValues returns the part of vals a subchart receives from the charts
+// given to RunScoped, following the chain of parents down to it. The globals
+// of every level are merged into the next one, so they reach the deepest
+// subchart as they do at render time. If the values of a level are missing
+// or not a table, the subchart receives only the globals. With withDefaults,
+// the values.yaml of every parent is coalesced under the values of its
+// level, so the subchart also receives the values its parents set for it.
+func scopeValues(cache *loader.Cache, vals map[string]interface{}, path string, parents map[string]string, withDefaults bool) map[string]interface{} {
+	return scopeAliasValues(cache, vals, path, "", parents, withDefaults)
+}
+
+// scopeAliasValues returns the values of the subchart at path as
+// scopeValues does, but with alias, when set, as the key of its values in
+// its parent.
+func scopeAliasValues(cache *loader.Cache, vals map[string]interface{}, path, alias string, parents map[string]string, withDefaults bool) map[string]interface{} {
+	chain := []string{filepath.Clean(path)}
+	for {
+		parent, ok := parents[chain[0]]
+		if !ok {
+			break
+		}
+		chain = append([]string{parent}, chain...)
+	}
+	for i := 1; i < len(chain); i++ {
+		if withDefaults {
+			vals = withChartDefaults(cache, vals, chain[i-1])
+		}
+		globals, _ := vals[chartutil.GlobalKey].(map[string]interface{})
+		key := dependencyKey(cache, chain[i-1], chain[i])
+		if alias != "" && i == len(chain)-1 {
+			key = alias
+		}
+		next, ok := vals[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+		}
+		scoped := make(map[string]interface{}, len(next)+1)
+		for k, v := range next {
+			scoped[k] = v
+		}
+		own, _ := next[chartutil.GlobalKey].(map[string]interface{})
+		if merged := mergeGlobals(own, globals); len(merged) > 0 {
+			scoped[chartutil.GlobalKey] = merged
+		}
+		if !ok {
+			return scoped
+		}
+		vals = scoped
+	}
+	return vals
+}
+
+// unusedValues warns about the top-level keys of vals, the values the
+// subchart at path receives, which are neither defined in its values.yaml nor
+// declared in its values.schema.json. The globals and the values of its own
+// subcharts are not checked. The chart is loaded with cache.
+func unusedValues(cache *loader.Cache, path string, vals map[string]interface{}) []support.Message {
+	c, err := cache.Load(path)
+	if err != nil {
+		// A chart which can't be loaded is reported by the lint itself.
+		return nil
+	}
+	declared := map[string]bool{chartutil.GlobalKey: true}
+	for key := range c.Values {
+		declared[key] = true
+	}
+	for _, d := range c.Metadata.Dependencies {
+		declared[d.Name] = true
+		if d.Alias != "" {
+			declared[d.Alias] = true
+		}
+	}
+	if len(c.Schema) > 0 {
+		var schema struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		if err := json.Unmarshal(c.Schema, &schema); err == nil {
+			for key := range schema.Properties {
+				declared[key] = true
+			}
+		}
+	}
+	var keys []string
+	for key := range vals {
+		if !declared[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var messages []support.Message
+	for _, key := range keys {
+		messages = append(messages, support.Message{
+			Severity: support.WarningSev,
+			Path:     chartutil.ValuesfileName,
+			Err:      errors.Errorf("value %q is set for this subchart, but is not defined in its values.yaml or values.schema.json", key),
+			RuleID:   rules.RuleUnusedValues,
+		})
+	}
+	return messages
+}
+
+// withChartDefaults coalesces the values.yaml of the chart at path under
+// vals, which take precedence. vals is not modified. Archives are loaded
+// through cache.
+func withChartDefaults(cache *loader.Cache, vals map[string]interface{}, path string) map[string]interface{} {
+	var defaults map[string]interface{}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		defaults, _ = chartutil.ReadValuesFile(filepath.Join(path, chartutil.ValuesfileName))
+	} else if c, err := cache.Load(path); err == nil {
+		defaults = c.Values
+	}
+	if len(defaults) == 0 {
+		return vals
+	}
+	copied, err := copystructure.Copy(vals)
+	if err != nil {
+		return vals
+	}
+	return chartutil.CoalesceTables(copied.(map[string]interface{}), defaults)
+}
+
+// mergeGlobals merges the globals of a parent chart into those of its
+// subchart, the values of the parent taking precedence. Neither map is
+// modified.
+func mergeGlobals(dest, src map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dest)+len(src))
+	for k, v := range dest {
+		merged[k] = v
+	}
+	for k, v := range src {
+		if sv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = mergeGlobals(dv, sv)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// dependencyKey returns the key of the values of the subchart at path in the
+// values of the chart at parent: the alias the parent gives the subchart, or
+// its name.
+func dependencyKey(cache *loader.Cache, parent, path string) string {
+	if aliases := dependencyAliases(cache, parent, path); len(aliases) == 1 {
+		return aliases[0]
+	}
+	return lintChartName(cache, path)
+}
+
+// dependencyAliases returns the aliases of the dependencies of the chart at
+// parent which the subchart at path is unpacked for.
+func dependencyAliases(cache *loader.Cache, parent, path string) []string {
+	var name, version string
+	if sub := lintChartMetadata(cache, path); sub != nil {
+		name, version = sub.Name, sub.Version
+	}
+	md, err := chartutil.LoadChartfile(filepath.Join(parent, chartutil.ChartfileName))
+	if err != nil {
+		return nil
+	}
+	var aliases []string
+	for _, d := range md.Dependencies {
+		if dependencyMatches(d, name, version) && d.Alias != "" {
+			aliases = append(aliases, d.Alias)
+		}
+	}
+	return aliases
+}
+
+// dependencyMatches reports whether the subchart with the given name and
+// version can be the one unpacked for the dependency d: d has its name, and
+// either pins its version or has a range the version satisfies, such as
+// >=1.2.0 <2.0.0. Without a version, or with one which isn't valid SemVer,
+// the subchart matches by name only.
+func dependencyMatches(d *chart.Dependency, name, version string) bool {
+	if d == nil || d.Name != name {
+		return false
+	}
+	if d.Version == "" || version == "" || d.Version == version {
+		return true
+	}
+	constraint, err := semver.NewConstraint(d.Version)
+	if err != nil {
+		return true
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return true
+	}
+	return constraint.Check(v)
+}
+
+// lintChartName returns the name of the chart at path, or an empty string if
+// it can't be read.
+func lintChartName(cache *loader.Cache, path string) string {
+	if md := lintChartMetadata(cache, path); md != nil {
+		return md.Name
+	}
+	return ""
+}
+
+// lintChartMetadata returns the Chart.yaml of the chart at path, loading an
+// archive through cache, or nil if it can't be read.
+func lintChartMetadata(cache *loader.Cache, path string) *chart.Metadata {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		md, err := chartutil.LoadChartfile(filepath.Join(path, chartutil.ChartfileName))
+		if err != nil {
+			return nil
+		}
+		return md
+	}
+	c, err := cache.Load(path)
+	if err != nil {
+		return nil
+	}
+	return c.Metadata
+}
@@ -0,0 +1,886 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	cliValues "helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestScopeValues(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	common := filepath.Join(redis, "charts", "common")
+	for dir, chartYaml := range map[string]string{
+		app:    "name: app\ndependencies:\n- name: redis\n  alias: cache\n",
+		redis:  "name: redis\ndependencies:\n- name: common\n",
+		common: "name: common\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parents := map[string]string{redis: app, common: redis}
+
+	vals := map[string]interface{}{
+		"global": map[string]interface{}{"registry": "example.com", "labels": map[string]interface{}{"team": "a"}},
+		"cache": map[string]interface{}{
+			"global": map[string]interface{}{"registry": "ignored.example.com", "labels": map[string]interface{}{"tier": "cache"}},
+			"common": map[string]interface{}{"enabled": true},
+		},
+	}
+	expected := map[string]interface{}{
+		"enabled": true,
+		"global":  map[string]interface{}{"registry": "example.com", "labels": map[string]interface{}{"team": "a", "tier": "cache"}},
+	}
+	if got := scopeValues(nil, vals, common, parents, false); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// Without values for the intermediate chart, only the globals are passed on.
+	delete(vals, "cache")
+	expected = map[string]interface{}{"global": vals["global"]}
+	if got := scopeValues(nil, vals, common, parents, false); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := scopeValues(nil, vals, app, parents, false); !reflect.DeepEqual(got, vals) {
+		t.Errorf("Expected the values of the chart given on the command line, got %v", got)
+	}
+}
+
+func TestScopeValuesWithLiteralValues(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	for dir, chartYaml := range map[string]string{
+		app:   "name: app\ndependencies:\n- name: redis\n",
+		redis: "name: redis\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parents := map[string]string{redis: app}
+
+	// Unlike --set, --set-literal keeps the commas and dots of the value, and
+	// the value reaches the scope of the subchart as it was given.
+	opts := &cliValues.Options{
+		Values:        []string{"redis.port=6379"},
+		LiteralValues: []string{"redis.connection_string=redis://cache.example.com:6379/0?opts=a,b"},
+	}
+	vals, _, err := opts.MergeValuesWithProvenance(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"port":              int64(6379),
+		"connection_string": "redis://cache.example.com:6379/0?opts=a,b",
+	}
+	if got := scopeValues(nil, vals, redis, parents, false); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestScopeValuesWithDefaults(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	if err := os.MkdirAll(redis, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(redis, "Chart.yaml"), []byte("apiVersion: v2\nname: redis\nversion: 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app, "values.yaml"), []byte("global:\n  region: eu\nredis:\n  port: 6379\n  auth: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vals := map[string]interface{}{"redis": map[string]interface{}{"port": 6380}}
+	expected := map[string]interface{}{
+		"port":   6380,
+		"auth":   true,
+		"global": map[string]interface{}{"region": "eu"},
+	}
+	parents := map[string]string{redis: app}
+	if got := scopeValues(nil, vals, redis, parents, true); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if len(vals["redis"].(map[string]interface{})) != 1 {
+		t.Errorf("Expected the user values to be left unchanged, got %v", vals)
+	}
+}
+
+func TestLintRunScoped(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	common := filepath.Join(redis, "charts", "common")
+	for dir, chartYaml := range map[string]string{
+		app:    "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n",
+		redis:  "name: redis\ndependencies:\n- name: common\n  version: 0.1.0\n",
+		common: "name: common\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.Workers = 2
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ScopedChart{
+		{Path: app, Name: "app", Scope: "."},
+		{Path: redis, Name: "redis", Scope: "charts/redis", Parent: app},
+		{Path: common, Name: "common", Scope: "charts/redis/charts/common", Parent: redis},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %v", len(expected), results)
+	}
+	for i, want := range expected {
+		if !reflect.DeepEqual(results[i].ScopedChart, want) {
+			t.Errorf("Expected %+v, got %+v", want, results[i].ScopedChart)
+		}
+		if results[i].Result == nil || results[i].Result.TotalChartsLinted != 1 || len(results[i].Result.Errors) != 0 {
+			t.Errorf("Expected %s to be linted without errors, got %+v", want.Path, results[i].Result)
+		}
+	}
+}
+
+func TestLintScopedCharts(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	postgresql := filepath.Join(app, "charts", "postgresql")
+	for dir, chartYaml := range map[string]string{
+		app:        "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n  alias: cache\n- name: postgresql\n  version: 0.1.0\n",
+		redis:      "name: redis\n",
+		postgresql: "name: postgresql\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		only      []string
+		skipRoot  bool
+		charts    []string
+		unmatched []string
+	}{
+		{nil, false, []string{"app", "postgresql", "redis"}, nil},
+		{[]string{"cache"}, false, []string{"app", "redis"}, nil},
+		{[]string{"post*", "mysql"}, true, []string{"postgresql"}, []string{"mysql"}},
+		{[]string{"charts/*"}, true, []string{"postgresql", "redis"}, nil},
+	}
+	for _, tt := range tests {
+		testLint := NewLint()
+		testLint.WithSubcharts = true
+		testLint.OnlySubcharts = tt.only
+		testLint.SkipRoot = tt.skipRoot
+		charts, unmatched, err := testLint.ScopedCharts([]string{app})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, c := range charts {
+			names = append(names, c.Name)
+		}
+		if !reflect.DeepEqual(names, tt.charts) || !reflect.DeepEqual(unmatched, tt.unmatched) {
+			t.Errorf("With %v, expected charts %v and unmatched patterns %v, got %v and %v", tt.only, tt.charts, tt.unmatched, names, unmatched)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.OnlySubcharts = []string{"[redis"}
+	if _, _, err := testLint.ScopedCharts([]string{app}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestLintRunScopedWithScopeNamespaces(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	for dir, chartYaml := range map[string]string{
+		app:   "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n",
+		redis: "name: redis\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, "templates"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The subchart renders an object with an invalid name, which is reported
+	// with the namespace the subchart is rendered in.
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: Rendered-In-{{ .Release.Namespace }}\n"
+	if err := os.WriteFile(filepath.Join(redis, "templates", "configmap.yaml"), []byte(configMap), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.Namespace = "default"
+	testLint.ScopeNamespaces = map[string]string{"redis": "cache"}
+	if ns := testLint.scopeNamespace(ScopedChart{Path: redis, Name: "redis", Scope: "charts/redis", Parent: app}); ns != "cache" {
+		t.Errorf("Expected the subchart to be rendered in cache, got %q", ns)
+	}
+	if ns := testLint.scopeNamespace(ScopedChart{Path: app, Name: "app", Scope: "."}); ns != "default" {
+		t.Errorf("Expected the root chart to be rendered in default, got %q", ns)
+	}
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", results)
+	}
+	found := false
+	for _, msg := range results[1].Result.Messages {
+		found = found || strings.Contains(msg.Err.Error(), "Rendered-In-cache")
+	}
+	if !found {
+		t.Errorf("Expected the subchart to be rendered in cache, got %v", results[1].Result.Messages)
+	}
+}
+
+func TestLintRunScopedWithCollidingSubcharts(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	cache := filepath.Join(app, "charts", "cache")
+	postgresql := filepath.Join(app, "charts", "postgresql")
+	for dir, chartYaml := range map[string]string{
+		app:        "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n  alias: cache\n- name: cache\n  version: 0.1.0\n- name: postgresql\n  version: 0.1.0\n",
+		redis:      "name: redis\n",
+		cache:      "name: cache\n",
+		postgresql: "name: postgresql\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		cache: `charts/cache shares the values key "cache" of its parent with charts/redis`,
+		redis: `charts/redis shares the values key "cache" of its parent with charts/cache`,
+	}
+	for _, r := range results {
+		var collision *support.Message
+		for i, msg := range r.Result.Messages {
+			if msg.RuleID == rules.RuleDependencyAlias && msg.Path == "Chart.yaml" && strings.Contains(msg.Err.Error(), "shares the values key") {
+				collision = &r.Result.Messages[i]
+			}
+		}
+		want, ok := expected[r.Path]
+		if !ok {
+			if collision != nil {
+				t.Errorf("Unexpected collision reported for %s: %s", r.Scope, collision.Err)
+			}
+			continue
+		}
+		if collision == nil || collision.Severity != support.WarningSev || !strings.HasPrefix(collision.Err.Error(), want) {
+			t.Errorf("Expected a warning %q for %s, got %v", want, r.Scope, r.Result.Messages)
+		}
+	}
+}
+
+func TestLintRunScopedWithKubeVersions(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	for dir, chartYaml := range map[string]string{
+		app:   "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n",
+		redis: "name: redis\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var versions []*chartutil.KubeVersion
+	for _, v := range []string{"1.24.0", "1.29.0"} {
+		kubeVersion, err := chartutil.ParseKubeVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, kubeVersion)
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.KubeVersions = versions
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		path, kubeVersion string
+	}{{app, "v1.24.0"}, {redis, "v1.24.0"}, {app, "v1.29.0"}, {redis, "v1.29.0"}}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %v", len(expected), results)
+	}
+	for i, want := range expected {
+		if results[i].Path != want.path || results[i].KubeVersion == nil || results[i].KubeVersion.String() != want.kubeVersion {
+			t.Errorf("Expected %s linted for %s, got %+v", want.path, want.kubeVersion, results[i])
+		}
+	}
+
+	testLint.Snapshot = filepath.Join(t.TempDir(), "snapshot.yaml")
+	if _, err := testLint.RunScoped([]string{app}, values); err == nil || !strings.Contains(err.Error(), "single Kubernetes version") {
+		t.Errorf("Expected a snapshot to need a single Kubernetes version, got %v", err)
+	}
+}
+
+func TestUnusedValues(t *testing.T) {
+	redis := filepath.Join(t.TempDir(), "redis")
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: redis\nversion: 0.1.0\ndependencies:\n- name: common\n  version: 0.1.0\n  alias: helpers\n",
+		"values.yaml":              "password: \"\"\n",
+		"values.schema.json":       `{"type": "object", "properties": {"replicas": {"type": "integer"}}}`,
+		"charts/common/Chart.yaml": "apiVersion: v2\nname: common\nversion: 0.1.0\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(redis, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals := map[string]interface{}{
+		"password": "secret",
+		"replicas": 3,
+		"helpers":  map[string]interface{}{},
+		"global":   map[string]interface{}{"env": "dev"},
+		"pasword":  "typo",
+		"image":    map[string]interface{}{"tag": "7"},
+	}
+	messages := unusedValues(nil, redis, vals)
+	expected := []string{
+		`value "image" is set for this subchart, but is not defined in its values.yaml or values.schema.json`,
+		`value "pasword" is set for this subchart, but is not defined in its values.yaml or values.schema.json`,
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %v", len(expected), messages)
+	}
+	for i, want := range expected {
+		msg := messages[i]
+		if msg.Severity != support.WarningSev || msg.RuleID != rules.RuleUnusedValues || msg.Path != "values.yaml" || msg.Err.Error() != want {
+			t.Errorf("Expected %q, got %v", want, msg)
+		}
+	}
+}
+
+func TestEnabledScopedCharts(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	common := filepath.Join(redis, "charts", "common")
+	metrics := filepath.Join(redis, "charts", "metrics")
+	files := map[string]string{
+		app:     "name: app\ndependencies:\n- name: redis\n  condition: cache.enabled\n  alias: cache\n- name: redis\n  condition: session.enabled\n  alias: session\n",
+		redis:   "name: redis\ndependencies:\n- name: common\n- name: metrics\n  condition: metrics.enabled,enabled\n  tags: [monitoring]\n",
+		common:  "name: common\n",
+		metrics: "name: metrics\n",
+	}
+	for dir, chartYaml := range files {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	charts := FindScopedCharts([]string{app}, true)
+	parents := map[string]string{}
+	for _, c := range charts {
+		if c.Parent != "" {
+			parents[c.Path] = c.Parent
+		}
+	}
+
+	tests := []struct {
+		name string
+		vals map[string]interface{}
+		want []string
+	}{{
+		name: "every alias disabled",
+		vals: map[string]interface{}{"cache": map[string]interface{}{"enabled": false}, "session": map[string]interface{}{"enabled": false}},
+		want: []string{app},
+	}, {
+		name: "one alias enabled",
+		vals: map[string]interface{}{"cache": map[string]interface{}{"enabled": false}, "session": map[string]interface{}{"enabled": true}},
+		want: []string{app, redis, common, metrics},
+	}, {
+		name: "tag disabled",
+		vals: map[string]interface{}{"tags": map[string]interface{}{"monitoring": false}},
+		want: []string{app, redis, common},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range enabledScopedCharts(nil, charts, tt.vals, parents) {
+				got = append(got, c.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// The condition takes precedence over the tags, and its first path set
+	// to a boolean wins.
+	dep := &chart.Dependency{Name: "metrics", Condition: "metrics.enabled,enabled", Tags: []string{"monitoring"}}
+	tags := chartutil.Values{"monitoring": false}
+	if !conditionEnabled(dep, chartutil.Values{"metrics": map[string]interface{}{"enabled": "yes"}, "enabled": true}, tags) {
+		t.Error("Expected the first condition path set to a boolean to enable the dependency")
+	}
+	if conditionEnabled(dep, chartutil.Values{}, tags) {
+		t.Error("Expected the tags to disable the dependency without a condition value")
+	}
+}
+
+func TestDependencyMatches(t *testing.T) {
+	tests := []struct {
+		dep     chart.Dependency
+		name    string
+		version string
+		want    bool
+	}{
+		{chart.Dependency{Name: "redis", Version: "1.5.3"}, "redis", "1.5.3", true},
+		{chart.Dependency{Name: "redis", Version: "1.5.3"}, "redis", "1.5.4", false},
+		{chart.Dependency{Name: "redis", Version: ">=1.2.0 <2.0.0"}, "redis", "1.5.3", true},
+		{chart.Dependency{Name: "redis", Version: ">=1.2.0 <2.0.0"}, "redis", "2.0.0", false},
+		{chart.Dependency{Name: "redis", Version: "~1.5"}, "redis", "1.5.9", true},
+		{chart.Dependency{Name: "redis", Version: "^1.2.0"}, "postgresql", "1.5.3", false},
+		{chart.Dependency{Name: "redis"}, "redis", "1.5.3", true},
+		{chart.Dependency{Name: "redis", Version: "latest"}, "redis", "1.5.3", true},
+	}
+	for _, tt := range tests {
+		if got := dependencyMatches(&tt.dep, tt.name, tt.version); got != tt.want {
+			t.Errorf("Expected %s %s to match %s %q: %t, got %t", tt.name, tt.version, tt.dep.Name, tt.dep.Version, tt.want, got)
+		}
+	}
+}
+
+func TestDependencyKeyWithVersionRanges(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	files := map[string]string{
+		app:                                     "name: app\nversion: 0.1.0\ndependencies:\n- name: redis\n  version: \">=1.2.0 <2.0.0\"\n  alias: cache\n- name: redis\n  version: ~2.1.0\n  alias: sessions\n",
+		filepath.Join(app, "charts", "redis-1"): "name: redis\nversion: 1.5.3\n",
+		filepath.Join(app, "charts", "redis-2"): "name: redis\nversion: 2.1.4\n",
+	}
+	for dir, chartYaml := range files {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for sub, want := range map[string]string{"redis-1": "cache", "redis-2": "sessions"} {
+		if key := dependencyKey(nil, app, filepath.Join(app, "charts", sub)); key != want {
+			t.Errorf("Expected the values key of charts/%s to be %q, got %q", sub, want, key)
+		}
+	}
+	if collisions := scopeCollisions(nil, FindScopedCharts([]string{app}, true)); len(collisions) != 0 {
+		t.Errorf("Expected no collisions between the subcharts of different versions, got %v", collisions)
+	}
+}
+
+func TestLintRunScopedWithAliases(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	files := map[string]string{
+		filepath.Join(app, "Chart.yaml"):           "apiVersion: v2\nname: app\nversion: 0.1.0\ndependencies:\n- name: redis\n  version: 0.1.0\n  alias: cache\n- name: redis\n  version: 0.1.0\n  alias: sessions\n",
+		filepath.Join(redis, "Chart.yaml"):         "apiVersion: v2\nname: redis\nversion: 0.1.0\n",
+		filepath.Join(redis, "values.schema.json"): `{"type": "object", "required": ["port"]}`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.SkipRoot = true
+	vals := map[string]interface{}{
+		"cache": map[string]interface{}{"port": 6379},
+		"redis": map[string]interface{}{"port": 6379},
+	}
+	results, err := testLint.RunScoped([]string{app}, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected the subchart to be linted once for both its aliases, got %d results", len(results))
+	}
+	if !reflect.DeepEqual(results[0].Aliases, []string{"cache", "sessions"}) || results[0].Alias != "" {
+		t.Errorf("Expected the aliases cache and sessions to be recorded, got %v (%q)", results[0].Aliases, results[0].Alias)
+	}
+
+	testLint.LintEachAlias = true
+	results, err = testLint.RunScoped([]string{app}, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected the subchart to be linted once for each alias, got %d results", len(results))
+	}
+	for _, r := range results {
+		failed := len(r.Result.Errors) != 0
+		switch r.Alias {
+		case "cache":
+			if failed {
+				t.Errorf("Expected the subchart to pass with the values of cache, got %v", r.Result.Messages)
+			}
+		case "sessions":
+			if !failed {
+				t.Error("Expected the subchart to fail without a port in the values of sessions")
+			}
+		default:
+			t.Errorf("Unexpected alias %q", r.Alias)
+		}
+	}
+}
+
+func TestLintRunScopedWithUndeclaredSubchart(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	redis := filepath.Join(app, "charts", "redis")
+	orphan := filepath.Join(app, "charts", "orphan")
+	for dir, chartYaml := range map[string]string{
+		app:    "name: app\ndependencies:\n- name: redis\n  version: 0.1.0\n",
+		redis:  "name: redis\n",
+		orphan: "name: orphan\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\n"+chartYaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linted := false
+	for _, r := range results {
+		var undeclared *support.Message
+		for i, msg := range r.Result.Messages {
+			if msg.RuleID == rules.RuleChartDependencies && strings.Contains(msg.Err.Error(), "isn't declared") {
+				undeclared = &r.Result.Messages[i]
+			}
+		}
+		if r.Path != orphan {
+			if undeclared != nil {
+				t.Errorf("Unexpected warning for %s: %s", r.Scope, undeclared.Err)
+			}
+			continue
+		}
+		linted = true
+		if r.Scope != "charts/orphan" {
+			t.Errorf("Expected the undeclared subchart to be scoped as charts/orphan, got %s", r.Scope)
+		}
+		if undeclared == nil || undeclared.Severity != support.WarningSev {
+			t.Errorf("Expected a warning for the undeclared subchart, got %v", r.Result.Messages)
+		}
+	}
+	if !linted {
+		t.Error("Expected the undeclared subchart to be linted")
+	}
+}
+
+func TestLintScopedChartsWithSubchartDir(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	for _, dir := range []string{app, filepath.Join(app, "vendor", "redis"), filepath.Join(app, "vendor", "redis", "vendor", "common"), filepath.Join(app, "charts", "postgresql")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nversion: 0.1.0\nname: "+filepath.Base(dir)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.SubchartDir = "vendor"
+	charts, _, err := testLint.ScopedCharts([]string{app})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []string
+	for _, c := range charts {
+		scopes = append(scopes, c.Scope)
+	}
+	if expected := []string{".", "vendor/redis", "vendor/redis/vendor/common"}; !reflect.DeepEqual(scopes, expected) {
+		t.Errorf("Expected the scopes %v, got %v", expected, scopes)
+	}
+
+	testLint.SubchartDir = "deps"
+	if _, _, err := testLint.ScopedCharts([]string{app}); err == nil || !strings.Contains(err.Error(), "subchart directory deps doesn't exist in chart") {
+		t.Errorf("Expected an error for the missing subchart directory, got %v", err)
+	}
+	testLint.SubchartDir = "../vendor"
+	if _, _, err := testLint.ScopedCharts([]string{app}); err == nil || !strings.Contains(err.Error(), "must be a relative path within the charts") {
+		t.Errorf("Expected an error for a subchart directory outside the chart, got %v", err)
+	}
+}
+
+func TestLintRunScopedWithMaxFindings(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	var deps strings.Builder
+	for i := 0; i < 6; i++ {
+		sub := filepath.Join(app, "charts", fmt.Sprintf("sub%d", i))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		// Without an icon nor a values.yaml, each subchart has two infos.
+		if err := os.WriteFile(filepath.Join(sub, "Chart.yaml"), []byte(fmt.Sprintf("apiVersion: v2\nname: sub%d\nversion: 0.1.0\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&deps, "- name: sub%d\n  version: 0.1.0\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(app, "Chart.yaml"), []byte("apiVersion: v2\nname: app\nversion: 0.1.0\ndependencies:\n"+deps.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	all := NewLint()
+	all.WithSubcharts = true
+	all.SkipRoot = true
+	expected, err := all.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.SkipRoot = true
+	testLint.Workers = 4
+	testLint.MaxFindings = 3
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, dropped, total := 0, 0, 0
+	for i, r := range results {
+		// The findings are kept in the order of the charts, whichever is
+		// linted first.
+		want := expected[i].Result.Messages
+		if len(want) > testLint.MaxFindings-kept {
+			want = want[:testLint.MaxFindings-kept]
+		}
+		if len(r.Result.Messages) != len(want) || (len(want) > 0 && !reflect.DeepEqual(r.Result.Messages, want)) {
+			t.Errorf("Expected %s to keep %v, got %v", r.Scope, want, r.Result.Messages)
+		}
+		kept += len(r.Result.Messages)
+		dropped += r.Result.Dropped
+		total += len(expected[i].Result.Messages)
+	}
+	if kept != testLint.MaxFindings || kept+dropped != total {
+		t.Errorf("Expected %d findings kept and %d dropped, got %d and %d", testLint.MaxFindings, total-testLint.MaxFindings, kept, dropped)
+	}
+}
+
+// writeScopedArchives writes a chart named app depending on the given number
+// of subcharts, each packaged in its charts directory, and returns the chart
+// directory.
+func writeScopedArchives(b *testing.B, subcharts int) string {
+	b.Helper()
+	app := filepath.Join(b.TempDir(), "app")
+	charts := filepath.Join(app, "charts")
+	if err := os.MkdirAll(charts, 0755); err != nil {
+		b.Fatal(err)
+	}
+	md := &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "0.1.0"}
+	for i := 0; i < subcharts; i++ {
+		name := fmt.Sprintf("sub%d", i)
+		sub := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: "0.1.0"},
+			Values:   map[string]interface{}{"port": 8080},
+			Templates: []*chart.File{{
+				Name: "templates/service.yaml",
+				Data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: {{ .Release.Name }}\n"),
+			}},
+		}
+		if _, err := chartutil.Save(sub, charts); err != nil {
+			b.Fatal(err)
+		}
+		md.Dependencies = append(md.Dependencies, &chart.Dependency{Name: name, Version: "0.1.0"})
+	}
+	if err := chartutil.SaveChartfile(filepath.Join(app, chartutil.ChartfileName), md); err != nil {
+		b.Fatal(err)
+	}
+	return app
+}
+
+func benchmarkLintRunScoped(b *testing.B, withChartCache bool) {
+	app := writeScopedArchives(b, 20)
+	testLint := NewLint()
+	if !withChartCache {
+		testLint.ChartCache = nil
+	}
+	testLint.WithSubcharts = true
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := testLint.RunScoped([]string{app}, values); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLintRunScopedManySubcharts(b *testing.B) {
+	benchmarkLintRunScoped(b, false)
+}
+
+func BenchmarkLintRunScopedManySubchartsWithChartCache(b *testing.B) {
+	benchmarkLintRunScoped(b, true)
+}
+
+func TestLintRunScopedWithRulesBundleAndRulesConfig(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	for name, content := range map[string]string{
+		"Chart.yaml":         "apiVersion: v2\nname: app\nversion: 0.1.0\n",
+		"templates/pod.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: app\nspec:\n  containers:\n  - name: app\n    image: docker.io/library/nginx:1.25\n",
+	} {
+		filename := filepath.Join(app, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundle, err := lint.ParseRulesBundle([]byte("rules:\n  image-registry:\n    allowed:\n    - registry.example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	testLint.RulesBundle = bundle
+	// The rules config can't allow the registry the bundle doesn't.
+	testLint.RulesConfig = &rules.RulesConfig{ImageRegistry: &rules.ImageRegistryConfig{Allowed: []string{"docker.io"}}}
+	results, err := testLint.RunScoped([]string{app}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	var registries int
+	t.Logf("%v %v", results[0].Result.Messages, results[0].Result.Errors)
+	for _, msg := range results[0].Result.Messages {
+		if msg.RuleID == rules.RuleImageRegistry {
+			registries++
+		}
+	}
+	if registries != 1 {
+		t.Errorf("Expected 1 image-registry finding, got %d in %v", registries, results[0].Result.Messages)
+	}
+}
+
+func TestLintRunScopedFilesRootedAtSubchart(t *testing.T) {
+	dir := t.TempDir()
+	policies := filepath.Join(dir, "policies.yaml")
+	if err := os.WriteFile(policies, []byte("policies:\n- name: sub-config\n  expression: \"object.kind != 'ConfigMap' || object.data.conf == 'from-sub'\"\n  severity: error\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := rules.LoadPolicies(policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "parent", Version: "0.1.0", Dependencies: []*chart.Dependency{{Name: "sub", Version: "0.1.0"}}},
+		Files:    []*chart.File{{Name: "config/app.conf", Data: []byte("from-parent")}},
+	}
+	if err := chartutil.SaveDir(parent, dir); err != nil {
+		t.Fatal(err)
+	}
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sub\ndata:\n  conf: {{ .Files.Get \"config/app.conf\" | quote }}\n"),
+		}},
+		Files: []*chart.File{{Name: "config/app.conf", Data: []byte("from-sub")}},
+	}
+	if err := chartutil.SaveDir(sub, filepath.Join(dir, "parent", "charts")); err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	testLint.WithSubcharts = true
+	testLint.SkipRoot = true
+	testLint.Policies = ps
+	results, err := testLint.RunScoped([]string{filepath.Join(dir, "parent")}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Scope != "charts/sub" {
+		t.Fatalf("Expected the result of charts/sub, got %v", results)
+	}
+	// The policy fails unless the template read the file of the subchart.
+	for _, msg := range results[0].Result.Messages {
+		if msg.Severity > support.InfoSev {
+			t.Errorf("Unexpected message: %s", msg)
+		}
+	}
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package action
 
 import (
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 )
 
 var (
@@ -77,7 +82,7 @@ func TestLintChart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace, nil)
+			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace)
 			switch {
 			case err != nil && !tt.err:
 				t.Errorf("%s", err)
@@ -157,3 +162,84 @@ func TestLint_ChartWithWarnings(t *testing.T) {
 		}
 	})
 }
+
+func TestLint_ValidateCRDsWithoutCluster(t *testing.T) {
+	testLint := NewLint()
+	testLint.ValidateCRDs = true
+	result := testLint.Run([]string{chart1MultipleChartLint}, values)
+	if len(result.Errors) != 1 || result.TotalChartsLinted != 0 {
+		t.Fatalf("Expected a single error and no linted chart, got %v", result.Errors)
+	}
+	if !strings.Contains(result.Errors[0].Error(), "CRD validation is enabled, but no Kubernetes cluster is configured") {
+		t.Errorf("Unexpected error: %s", result.Errors[0])
+	}
+}
+
+func TestLint_EnableLookupWithoutCluster(t *testing.T) {
+	for name, config := range map[string]*Configuration{
+		"no configuration":      nil,
+		"no cluster connection": actionConfigFixture(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			testLint := NewLint()
+			testLint.EnableLookup = true
+			testLint.Config = config
+			result := testLint.Run([]string{chart1MultipleChartLint}, values)
+			if len(result.Errors) != 1 || result.TotalChartsLinted != 0 {
+				t.Fatalf("Expected a single error and no linted chart, got %v", result.Errors)
+			}
+			if !strings.Contains(result.Errors[0].Error(), "lookup is enabled, but no Kubernetes cluster is configured") {
+				t.Errorf("Unexpected error: %s", result.Errors[0])
+			}
+		})
+	}
+}
+
+// countingRenderCache records how often rendered templates were stored.
+type countingRenderCache struct {
+	engine.RenderCache
+	puts int
+}
+
+func (c *countingRenderCache) Put(key string, rendered map[string]string) error {
+	c.puts++
+	return c.RenderCache.Put(key, rendered)
+}
+
+func TestLintRenderCacheSharedWithInstall(t *testing.T) {
+	path, err := chartutil.Create("web", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &countingRenderCache{RenderCache: engine.NewMemoryRenderCache()}
+
+	client := NewLint()
+	client.Namespace = "spaced"
+	client.ReleaseName = "web"
+	client.Config = &Configuration{RenderCache: cache}
+	if result := client.Run([]string{path}, values); len(result.Errors) != 0 {
+		t.Fatalf("Unexpected lint errors: %v", result.Errors)
+	}
+	if cache.puts != 1 {
+		t.Fatalf("Expected the lint to store its render, got %d", cache.puts)
+	}
+
+	// A dry-run install for the release, as `helm template` runs, reuses
+	// the render of the lint.
+	instAction := installAction(t)
+	instAction.cfg.RenderCache = cache
+	instAction.ClientOnly = true
+	instAction.DryRun = true
+	instAction.ReleaseName = "web"
+	chrt, err := loader.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := instAction.Run(chrt, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.puts != 1 || !strings.Contains(rel.Manifest, "name: web") {
+		t.Errorf("Expected the install to reuse the render of the lint, got %d renders and manifest %s", cache.puts, rel.Manifest)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// chartProgress records the chart a load running against a timeout is at, so
+// that the timeout error names the chart which stalled.
+type chartProgress struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (p *chartProgress) at(path string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.path = path
+}
+
+func (p *chartProgress) current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.path
+}
+
+// withTimeout runs fn and, when timeout is positive, fails once the timeout
+// expires with an error naming the chart fn was at, as doing reports it
+// ("loading", "linting"). File system calls can't be interrupted, so fn keeps
+// running in the background after a timeout, and the caller must not use
+// what it sets then.
+func withTimeout(timeout time.Duration, doing string, fn func(progress *chartProgress) error) error {
+	if timeout <= 0 {
+		return fn(nil)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	progress := &chartProgress{}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(progress)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Errorf("timed out after %s %s chart %s", timeout, doing, progress.current())
+	}
+}
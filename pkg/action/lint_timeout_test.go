@@ -0,0 +1,49 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	defer close(stalled)
+	err := withTimeout(10*time.Millisecond, "loading", func(progress *chartProgress) error {
+		progress.at("charts/app")
+		progress.at("charts/app/charts/slow")
+		<-stalled
+		return nil
+	})
+	if err == nil || err.Error() != "timed out after 10ms loading chart charts/app/charts/slow" {
+		t.Errorf("Expected a timeout naming the stalled chart, got %v", err)
+	}
+
+	failed := errors.New("failed")
+	if err := withTimeout(time.Minute, "loading", func(*chartProgress) error { return failed }); err != failed {
+		t.Errorf("Expected the error of the load, got %v", err)
+	}
+	// Without a timeout, fn runs directly.
+	if err := withTimeout(0, "loading", func(progress *chartProgress) error {
+		progress.at("charts/app")
+		return nil
+	}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
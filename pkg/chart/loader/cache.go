@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mitchellh/copystructure"
+
+	"helm.sh/helm/v3/internal/sympath"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// Cache loads charts like Load, keeping the charts it loaded to return them
+// again while their files are unchanged. It is safe for concurrent use. A nil
+// Cache loads every chart.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	stamp uint64
+	chart *chart.Chart
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{entries: map[string]cacheEntry{}}
+}
+
+// Load loads the chart name like Load, or returns it from the cache if the
+// files it was loaded from have the same sizes and modification times. Each
+// call returns a copy of the chart, which the caller may modify, as rendering
+// it does. The charts of external loaders aren't cached.
+func (c *Cache) Load(name string) (*chart.Chart, error) {
+	if c == nil {
+		return Load(name)
+	}
+	if _, ok := externalLoaderFor(name); ok {
+		return Load(name)
+	}
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return Load(name)
+	}
+	stamp, err := chartStamp(key)
+	if err != nil {
+		return Load(name)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.stamp == stamp {
+		return copyChart(entry.chart)
+	}
+
+	loaded, err := Load(name)
+	if err != nil {
+		return loaded, err
+	}
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{stamp: stamp, chart: loaded}
+	c.mu.Unlock()
+	return copyChart(loaded)
+}
+
+// chartStamp hashes the paths, sizes and modification times of the files of
+// the chart at path, a directory or an archive, so a change to any of them
+// changes it.
+func chartStamp(path string) (uint64, error) {
+	h := fnv.New64a()
+	err := sympath.Walk(path, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", name, fi.Size(), fi.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64(), err
+}
+
+// copyChart copies c and its subcharts, with the metadata and values which
+// processing the dependencies of a chart modifies. The files are shared.
+func copyChart(c *chart.Chart) (*chart.Chart, error) {
+	cp := *c
+	if c.Metadata != nil {
+		md := *c.Metadata
+		md.Dependencies = copyDependencies(c.Metadata.Dependencies)
+		cp.Metadata = &md
+	}
+	if c.Lock != nil {
+		lock := *c.Lock
+		lock.Dependencies = copyDependencies(c.Lock.Dependencies)
+		cp.Lock = &lock
+	}
+	if c.Values != nil {
+		values, err := copystructure.Copy(c.Values)
+		if err != nil {
+			return nil, err
+		}
+		cp.Values = values.(map[string]interface{})
+	}
+	cp.Templates = append([]*chart.File(nil), c.Templates...)
+	cp.Files = append([]*chart.File(nil), c.Files...)
+	cp.Raw = append([]*chart.File(nil), c.Raw...)
+
+	deps := make([]*chart.Chart, 0, len(c.Dependencies()))
+	for _, dep := range c.Dependencies() {
+		d, err := copyChart(dep)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	cp.SetDependencies(deps...)
+	return &cp, nil
+}
+
+func copyDependencies(deps []*chart.Dependency) []*chart.Dependency {
+	if deps == nil {
+		return nil
+	}
+	cp := make([]*chart.Dependency, len(deps))
+	for i, d := range deps {
+		if d == nil {
+			continue
+		}
+		dep := *d
+		cp[i] = &dep
+	}
+	return cp
+}
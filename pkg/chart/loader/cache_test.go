@@ -0,0 +1,145 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCacheChart writes a chart named app with the given number of
+// subcharts into a new directory, and returns the chart directory.
+func writeCacheChart(t testing.TB, subcharts int) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "app")
+	files := map[string]string{
+		"Chart.yaml":                "apiVersion: v2\nname: app\nversion: 0.1.0\ndependencies:\n- name: sub0\n  version: 0.1.0\n",
+		"values.yaml":               "replicas: 1\n",
+		"templates/deployment.yaml": "kind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n",
+	}
+	for i := 0; i < subcharts; i++ {
+		sub := fmt.Sprintf("charts/sub%d/", i)
+		files[sub+"Chart.yaml"] = fmt.Sprintf("apiVersion: v2\nname: sub%d\nversion: 0.1.0\n", i)
+		files[sub+"values.yaml"] = "port: 8080\n"
+		files[sub+"templates/service.yaml"] = "kind: Service\nmetadata:\n  name: {{ .Release.Name }}\n"
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCacheLoad(t *testing.T) {
+	dir := writeCacheChart(t, 2)
+	cache := NewCache()
+
+	c, err := cache.Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+	if c.Name() != "app" || len(c.Dependencies()) != 2 {
+		t.Fatalf("Expected chart app with 2 subcharts, got %s with %d", c.Name(), len(c.Dependencies()))
+	}
+	// Processing the dependencies modifies the chart, which mustn't change
+	// the one kept in the cache.
+	c.Values["replicas"] = 3
+	c.Metadata.Dependencies[0].Enabled = true
+	c.SetDependencies()
+
+	again, err := cache.Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+	if again == c {
+		t.Error("Expected a copy of the cached chart")
+	}
+	if again.Values["replicas"] != float64(1) {
+		t.Errorf("Expected the cached values to be unchanged, got replicas %v", again.Values["replicas"])
+	}
+	if again.Metadata.Dependencies[0].Enabled {
+		t.Error("Expected the cached dependencies to be unchanged")
+	}
+	if len(again.Dependencies()) != 2 || again.Dependencies()[0].Parent() != again {
+		t.Errorf("Expected the copy to have 2 subcharts of its own, got %d", len(again.Dependencies()))
+	}
+}
+
+func TestCacheLoadChangedChart(t *testing.T) {
+	dir := writeCacheChart(t, 1)
+	cache := NewCache()
+	if _, err := cache.Load(dir); err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+
+	values := filepath.Join(dir, "charts", "sub0", "values.yaml")
+	if err := os.WriteFile(values, []byte("port: 9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The file may be rewritten within the resolution of the modification
+	// time, which is set apart explicitly.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(values, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := cache.Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+	if port := c.Dependencies()[0].Values["port"]; port != float64(9090) {
+		t.Errorf("Expected the changed chart to be loaded again, got port %v", port)
+	}
+}
+
+func TestCacheLoadWithoutCache(t *testing.T) {
+	var cache *Cache
+	c, err := cache.Load("testdata/frobnitz")
+	if err != nil {
+		t.Fatalf("Failed to load testdata: %s", err)
+	}
+	verifyFrobnitz(t, c)
+}
+
+func BenchmarkLoadManySubcharts(b *testing.B) {
+	dir := writeCacheChart(b, 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Load(dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCacheLoadManySubcharts(b *testing.B) {
+	dir := writeCacheChart(b, 50)
+	cache := NewCache()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.Load(dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
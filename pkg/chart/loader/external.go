@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// ExternalLoader converts charts stored in another format, such as the
+// charts handled by a loader plugin, into standard chart directories.
+type ExternalLoader struct {
+	// Name identifies the loader in error messages.
+	Name string
+	// Patterns are globs matched against the base name of a chart path,
+	// e.g. *.mychart.
+	Patterns []string
+	// Convert writes the chart at path into dir as a standard chart
+	// directory, with Chart.yaml at its root.
+	Convert func(path, dir string) error
+}
+
+var (
+	externalLoadersMu sync.RWMutex
+	externalLoaders   []ExternalLoader
+)
+
+// RegisterExternalLoader makes Load and Loader delegate the charts matching
+// the patterns of l to it. Loaders are tried in the order of registration.
+func RegisterExternalLoader(l ExternalLoader) {
+	externalLoadersMu.Lock()
+	defer externalLoadersMu.Unlock()
+	externalLoaders = append(externalLoaders, l)
+}
+
+// externalLoaderFor returns the registered loader handling the chart at name.
+func externalLoaderFor(name string) (ExternalLoader, bool) {
+	externalLoadersMu.RLock()
+	defer externalLoadersMu.RUnlock()
+	base := filepath.Base(name)
+	for _, l := range externalLoaders {
+		for _, pattern := range l.Patterns {
+			if ok, _ := filepath.Match(pattern, base); ok {
+				return l, true
+			}
+		}
+	}
+	return ExternalLoader{}, false
+}
+
+// IsExternal reports whether a registered external loader handles the chart
+// at name.
+func IsExternal(name string) bool {
+	_, ok := externalLoaderFor(name)
+	return ok
+}
+
+// ConvertExternal converts the chart at name into dir with the registered
+// external loader handling it. It returns false if no loader handles the
+// chart.
+func ConvertExternal(name, dir string) (bool, error) {
+	l, ok := externalLoaderFor(name)
+	if !ok {
+		return false, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return true, errors.Wrap(err, "unable to create directory to convert chart")
+	}
+	if err := l.Convert(name, dir); err != nil {
+		return true, errors.Wrapf(err, "loader %q failed to convert %s", l.Name, name)
+	}
+	return true, nil
+}
+
+// externalChartLoader loads a chart through an external loader.
+type externalChartLoader struct {
+	name   string
+	loader ExternalLoader
+}
+
+// Load converts the chart in a temporary directory and loads it from there.
+func (l externalChartLoader) Load() (*chart.Chart, error) {
+	dir, err := os.MkdirTemp("", "helm-loader")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create temp dir to convert chart")
+	}
+	defer os.RemoveAll(dir)
+
+	if err := l.loader.Convert(l.name, dir); err != nil {
+		return nil, errors.Wrapf(err, "loader %q failed to convert %s", l.loader.Name, l.name)
+	}
+	return LoadDir(dir)
+}
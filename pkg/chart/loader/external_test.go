@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExternalLoader(t *testing.T) {
+	var converted string
+	RegisterExternalLoader(ExternalLoader{
+		Name:     "fake",
+		Patterns: []string{"*.fake"},
+		Convert: func(path, dir string) error {
+			if filepath.Base(path) == "broken.fake" {
+				return errors.New("unsupported format")
+			}
+			converted = path
+			data, err := os.ReadFile("testdata/albatross/Chart.yaml")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dir, "Chart.yaml"), data, 0644)
+		},
+	})
+
+	if IsExternal("testdata/albatross") {
+		t.Error("expected testdata/albatross not to be handled by the external loader")
+	}
+	if !IsExternal("testdata/albatross.fake") {
+		t.Error("expected testdata/albatross.fake to be handled by the external loader")
+	}
+
+	c, err := Load("testdata/albatross.fake")
+	if err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+	if converted != "testdata/albatross.fake" {
+		t.Errorf("expected the loader to convert testdata/albatross.fake, got %q", converted)
+	}
+	if c.Name() != "albatross" {
+		t.Errorf("expected chart name albatross, got %q", c.Name())
+	}
+
+	if _, err := Load("testdata/broken.fake"); err == nil {
+		t.Error("expected an error from the external loader")
+	}
+
+	dir := t.TempDir()
+	ok, err := ConvertExternal("testdata/albatross.fake", filepath.Join(dir, "albatross"))
+	if !ok || err != nil {
+		t.Fatalf("expected the chart to be converted, got %t, %v", ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "albatross", "Chart.yaml")); err != nil {
+		t.Errorf("expected Chart.yaml in the converted chart: %s", err)
+	}
+	if ok, _ := ConvertExternal("testdata/albatross", dir); ok {
+		t.Error("expected testdata/albatross not to be converted")
+	}
+}
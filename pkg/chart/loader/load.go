@@ -35,7 +35,12 @@ type ChartLoader interface {
 }
 
 // Loader returns a new ChartLoader appropriate for the given chart name
+//
+// Charts matching the patterns of a registered ExternalLoader are delegated to it.
 func Loader(name string) (ChartLoader, error) {
+	if l, ok := externalLoaderFor(name); ok {
+		return externalChartLoader{name: name, loader: l}, nil
+	}
 	fi, err := os.Stat(name)
 	if err != nil {
 		return nil, err
@@ -20,6 +20,7 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -37,47 +38,117 @@ type Options struct {
 	FileValues    []string // --set-file
 	JSONValues    []string // --set-json
 	LiteralValues []string // --set-literal
+	// MergeStrategy controls how the files specified via -f/--values are
+	// combined. It defaults to MergeReplaceArrays.
+	MergeStrategy MergeStrategy // --merge-strategy
+}
+
+// MergeStrategy controls how the files specified via -f/--values are combined.
+// Tables are always merged key by key, the strategies differ in the handling
+// of lists.
+type MergeStrategy string
+
+const (
+	// MergeReplaceArrays replaces lists: a list set in a later file replaces
+	// the list set by earlier files as a whole.
+	MergeReplaceArrays MergeStrategy = "replace-arrays"
+	// MergeDeep merges lists element by element: the element at index i of
+	// the later list is merged into the element at index i of the earlier
+	// list if both are tables, and replaces it otherwise. Elements beyond the
+	// end of the shorter list are kept from the longer one.
+	MergeDeep MergeStrategy = "deep"
+)
+
+// OverriddenKey describes a values key that was set by more than one file
+// specified via -f/--values. Files lists the files in merge order, so the
+// last entry is the one whose value won. TypeChanged is set when the key is a
+// table in some of the files and not in others.
+type OverriddenKey struct {
+	Key         string
+	Files       []string
+	TypeChanged bool
+}
+
+// keyProvenance records the files which set a values key, and whether the key
+// is a table in each of them.
+type keyProvenance struct {
+	files  []string
+	tables []bool
 }
 
 // MergeValues merges values from files specified via -f/--values and directly
 // via --set-json, --set, --set-string, or --set-file, marshaling them to YAML
 func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, error) {
+	base, _, err := opts.MergeValuesWithProvenance(p)
+	return base, err
+}
+
+// MergeValuesWithProvenance behaves like MergeValues, but additionally reports
+// the keys which were overridden while merging the files specified via
+// -f/--values.
+//
+// Nested tables are merged key by key, so a table present in two files is not
+// an override unless the same leaf key is set in both, or the key is a table in
+// one file and not in another. A file specified more than once is tracked once,
+// at its last position, as that is where its values win.
+func (opts *Options) MergeValuesWithProvenance(p getter.Providers) (map[string]interface{}, []OverriddenKey, error) {
 	base := map[string]interface{}{}
+	provenance := map[string]*keyProvenance{}
+	last := map[string]int{}
+	for i, filePath := range opts.ValueFiles {
+		last[filePath] = i
+	}
+
+	var deep bool
+	switch opts.MergeStrategy {
+	case "", MergeReplaceArrays:
+	case MergeDeep:
+		deep = true
+	default:
+		return nil, nil, errors.Errorf("unknown merge strategy %q, must be one of: %s, %s", opts.MergeStrategy, MergeReplaceArrays, MergeDeep)
+	}
 
 	// User specified a values files via -f/--values
-	for _, filePath := range opts.ValueFiles {
+	for i, filePath := range opts.ValueFiles {
 		currentMap := map[string]interface{}{}
 
 		bytes, err := readFile(filePath, p)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
-			return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+			return nil, nil, errors.Wrapf(err, "failed to parse %s", filePath)
+		}
+		if last[filePath] == i {
+			trackProvenance(provenance, "", currentMap, filePath)
 		}
 		// Merge with the previous map
-		base = mergeMaps(base, currentMap)
+		if deep {
+			base = mergeMapsDeep(base, currentMap)
+		} else {
+			base = mergeMaps(base, currentMap)
+		}
 	}
 
 	// User specified a value via --set-json
 	for _, value := range opts.JSONValues {
 		if err := strvals.ParseJSON(value, base); err != nil {
-			return nil, errors.Errorf("failed parsing --set-json data %s", value)
+			return nil, nil, errors.Errorf("failed parsing --set-json data %s", value)
 		}
 	}
 
 	// User specified a value via --set
 	for _, value := range opts.Values {
 		if err := strvals.ParseInto(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set data")
 		}
 	}
 
 	// User specified a value via --set-string
 	for _, value := range opts.StringValues {
 		if err := strvals.ParseIntoString(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-string data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-string data")
 		}
 	}
 
@@ -91,18 +162,60 @@ func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, er
 			return string(bytes), err
 		}
 		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-file data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-file data")
 		}
 	}
 
 	// User specified a value via --set-literal
 	for _, value := range opts.LiteralValues {
 		if err := strvals.ParseLiteralInto(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-literal data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-literal data")
 		}
 	}
 
-	return base, nil
+	return base, overriddenKeys(provenance), nil
+}
+
+// trackProvenance records, for every key in values, the file that set it and
+// whether the key is a table in it.
+func trackProvenance(provenance map[string]*keyProvenance, prefix string, values map[string]interface{}, file string) {
+	for k, v := range values {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		kp, ok := provenance[key]
+		if !ok {
+			kp = &keyProvenance{}
+			provenance[key] = kp
+		}
+		m, table := v.(map[string]interface{})
+		kp.files = append(kp.files, file)
+		kp.tables = append(kp.tables, table)
+		if table {
+			trackProvenance(provenance, key, m, file)
+		}
+	}
+}
+
+func overriddenKeys(provenance map[string]*keyProvenance) []OverriddenKey {
+	var keys []OverriddenKey
+	for k, kp := range provenance {
+		var tables int
+		for _, table := range kp.tables {
+			if table {
+				tables++
+			}
+		}
+		switch {
+		case tables > 0 && tables < len(kp.tables):
+			keys = append(keys, OverriddenKey{Key: k, Files: kp.files, TypeChanged: true})
+		case tables == 0 && len(kp.files) > 1:
+			keys = append(keys, OverriddenKey{Key: k, Files: kp.files})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
 }
 
 func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
@@ -124,6 +237,46 @@ func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
 	return out
 }
 
+// mergeMapsDeep merges b into a like mergeMaps, but also merges lists
+// element by element, see MergeDeep.
+func mergeMapsDeep(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if av, ok := out[k]; ok {
+			out[k] = mergeValuesDeep(av, v)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func mergeValuesDeep(a, b interface{}) interface{} {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		if av, ok := a.(map[string]interface{}); ok {
+			return mergeMapsDeep(av, bv)
+		}
+	case []interface{}:
+		if av, ok := a.([]interface{}); ok {
+			out := make([]interface{}, len(av))
+			copy(out, av)
+			for i, v := range bv {
+				if i < len(out) {
+					out[i] = mergeValuesDeep(out[i], v)
+				} else {
+					out = append(out, v)
+				}
+			}
+			return out
+		}
+	}
+	return b
+}
+
 // readFile load a file from stdin, the local directory, or a remote file with a url.
 func readFile(filePath string, p getter.Providers) ([]byte, error) {
 	if strings.TrimSpace(filePath) == "-" {
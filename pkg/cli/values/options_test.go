@@ -17,6 +17,8 @@ limitations under the License.
 package values
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -86,3 +88,140 @@ func TestReadFile(t *testing.T) {
 		t.Errorf("Expected error when has special strings")
 	}
 }
+
+func TestMergeValuesWithProvenance(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": "image:\n  repository: nginx\n  tag: \"1.0\"\nreplicas: 1\n",
+		"b.yaml": "image:\n  tag: \"2.0\"\n",
+		"c.yaml": "image:\n  tag: \"3.0\"\nreplicas: 2\nservice:\n  port: 80\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := &Options{ValueFiles: []string{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "b.yaml"),
+		filepath.Join(dir, "c.yaml"),
+	}}
+	vals, overrides, err := opts.MergeValuesWithProvenance(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag := vals["image"].(map[string]interface{})["tag"]; tag != "3.0" {
+		t.Errorf("Expected the last file to win, got image.tag=%v", tag)
+	}
+
+	expected := []OverriddenKey{
+		{Key: "image.tag", Files: opts.ValueFiles},
+		{Key: "replicas", Files: []string{opts.ValueFiles[0], opts.ValueFiles[2]}},
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected overrides %v, got %v", expected, overrides)
+	}
+}
+
+func TestMergeValuesWithProvenanceTypeChange(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": "a: 1\nimage:\n  tag: \"1.0\"\n",
+		"b.yaml": "a:\n  b: 2\nimage:\n  repository: nginx\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := &Options{ValueFiles: []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}}
+	_, overrides, err := opts.MergeValuesWithProvenance(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// image is a table in both files, so it is merged rather than overridden.
+	expected := []OverriddenKey{{Key: "a", Files: opts.ValueFiles, TypeChanged: true}}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected overrides %v, got %v", expected, overrides)
+	}
+}
+
+func TestMergeValuesWithProvenanceSameFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": "image:\n  tag: \"1.0\"\nreplicas: 1\n",
+		"b.yaml": "replicas: 2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	opts := &Options{ValueFiles: []string{a, b, a}}
+	vals, overrides, err := opts.MergeValuesWithProvenance(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replicas := vals["replicas"]; replicas != 1.0 {
+		t.Errorf("Expected the last file to win, got replicas=%v", replicas)
+	}
+
+	// A file specified twice doesn't override itself, and takes its place
+	// where it is last specified.
+	expected := []OverriddenKey{{Key: "replicas", Files: []string{b, a}}}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected overrides %v, got %v", expected, overrides)
+	}
+}
+
+func TestMergeValuesStrategies(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": "hosts:\n- name: a\n  port: 80\n- name: b\n  port: 81\n",
+		"b.yaml": "hosts:\n- port: 8080\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	valueFiles := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+
+	for _, tt := range []struct {
+		strategy MergeStrategy
+		expected []interface{}
+	}{{
+		strategy: "",
+		expected: []interface{}{map[string]interface{}{"port": float64(8080)}},
+	}, {
+		strategy: MergeReplaceArrays,
+		expected: []interface{}{map[string]interface{}{"port": float64(8080)}},
+	}, {
+		strategy: MergeDeep,
+		expected: []interface{}{
+			map[string]interface{}{"name": "a", "port": float64(8080)},
+			map[string]interface{}{"name": "b", "port": float64(81)},
+		},
+	}} {
+		opts := &Options{ValueFiles: valueFiles, MergeStrategy: tt.strategy}
+		vals, err := opts.MergeValues(getter.Providers{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vals["hosts"], tt.expected) {
+			t.Errorf("Expected hosts %v with strategy %q, got %v", tt.expected, tt.strategy, vals["hosts"])
+		}
+	}
+
+	opts := &Options{ValueFiles: valueFiles, MergeStrategy: "union"}
+	if _, err := opts.MergeValues(getter.Providers{}); err == nil {
+		t.Error("Expected an error for an unknown merge strategy")
+	}
+}
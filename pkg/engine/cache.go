@@ -0,0 +1,184 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// RenderCache stores rendered templates, so that rendering the same chart with
+// the same values again, for example by `helm lint` followed by
+// `helm template`, can reuse the earlier result.
+//
+// Keys are computed by the engine from the chart, the values and the engine
+// options.
+type RenderCache interface {
+	// Get returns the rendered templates stored under key.
+	Get(key string) (map[string]string, bool)
+	// Put stores the rendered templates under key.
+	Put(key string, rendered map[string]string) error
+}
+
+// NewMemoryRenderCache returns a RenderCache which keeps the rendered
+// templates in memory, for reuse within a single process.
+func NewMemoryRenderCache() RenderCache {
+	return &memoryRenderCache{entries: map[string]map[string]string{}}
+}
+
+type memoryRenderCache struct {
+	mu      sync.RWMutex
+	entries map[string]map[string]string
+}
+
+func (c *memoryRenderCache) Get(key string) (map[string]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rendered, ok := c.entries[key]
+	return copyRendered(rendered), ok
+}
+
+func (c *memoryRenderCache) Put(key string, rendered map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = copyRendered(rendered)
+	return nil
+}
+
+func copyRendered(rendered map[string]string) map[string]string {
+	if rendered == nil {
+		return nil
+	}
+	c := make(map[string]string, len(rendered))
+	for k, v := range rendered {
+		c[k] = v
+	}
+	return c
+}
+
+// NewDiskRenderCache returns a RenderCache which stores the rendered templates
+// in dir, so that separate helm invocations can share them.
+func NewDiskRenderCache(dir string) RenderCache {
+	return diskRenderCache{dir: dir}
+}
+
+type diskRenderCache struct {
+	dir string
+}
+
+func (c diskRenderCache) Get(key string) (map[string]string, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var rendered map[string]string
+	if err := json.Unmarshal(data, &rendered); err != nil {
+		return nil, false
+	}
+	return rendered, true
+}
+
+func (c diskRenderCache) Put(key string, rendered map[string]string) error {
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create render cache directory")
+	}
+	// Write to a temporary file first, so concurrent runs never read a
+	// partially written entry.
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+}
+
+// renderCacheVersion is part of every key, so that changes to the engine
+// invalidate templates rendered by older versions.
+const renderCacheVersion = "helm-render-cache/v1"
+
+// cacheKey returns the key the output of rendering chrt with values is stored
+// under.
+func (e Engine) cacheKey(chrt *chart.Chart, values chartutil.Values) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, renderCacheVersion)
+	if err := hashChart(h, chrt); err != nil {
+		return "", err
+	}
+	// LintMode isn't part of the key, as the renders in LintMode and outside
+	// of it share their entries.
+	options := struct {
+		Strict    bool `json:"strict"`
+		EnableDNS bool `json:"enableDNS"`
+	}{e.Strict, e.EnableDNS}
+	enc := json.NewEncoder(h)
+	if err := enc.Encode(options); err != nil {
+		return "", err
+	}
+	// encoding/json sorts map keys, so equal values always hash the same.
+	if err := enc.Encode(values); err != nil {
+		return "", errors.Wrap(err, "unable to hash values")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashChart writes everything of chrt and its dependencies which can affect
+// the rendered templates to h.
+func hashChart(h hash.Hash, chrt *chart.Chart) error {
+	enc := json.NewEncoder(h)
+	if err := enc.Encode(chrt.Metadata); err != nil {
+		return err
+	}
+	if err := enc.Encode(chrt.Values); err != nil {
+		return err
+	}
+	for _, files := range [][]*chart.File{chrt.Templates, chrt.Files} {
+		for _, f := range files {
+			io.WriteString(h, f.Name+"\x00")
+			h.Write(f.Data)
+			io.WriteString(h, "\x00")
+		}
+	}
+	for _, dep := range chrt.Dependencies() {
+		io.WriteString(h, "dependency\x00")
+		if err := hashChart(h, dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// countingCache records how often rendered templates were stored.
+type countingCache struct {
+	RenderCache
+	puts int
+}
+
+func (c *countingCache) Put(key string, rendered map[string]string) error {
+	c.puts++
+	return c.RenderCache.Put(key, rendered)
+}
+
+func TestRenderCache(t *testing.T) {
+	for name, cache := range map[string]RenderCache{
+		"memory": NewMemoryRenderCache(),
+		"disk":   NewDiskRenderCache(t.TempDir()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := &chart.Chart{
+				Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+				Templates: []*chart.File{
+					{Name: "templates/test", Data: []byte("{{.Values.outer}}")},
+				},
+			}
+			vals := chartutil.Values{"Values": map[string]interface{}{"outer": "spouter"}}
+			counting := &countingCache{RenderCache: cache}
+			e := Engine{Cache: counting}
+
+			render := func(e Engine, vals chartutil.Values) string {
+				t.Helper()
+				out, err := e.Render(c, vals)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return out["moby/templates/test"]
+			}
+
+			if out := render(e, vals); out != "spouter" {
+				t.Errorf("Expected %q, got %q", "spouter", out)
+			}
+			if out := render(e, vals); out != "spouter" || counting.puts != 1 {
+				t.Errorf("Expected the second render to be served from the cache, got %q after %d renders", out, counting.puts)
+			}
+
+			render(e, chartutil.Values{"Values": map[string]interface{}{"outer": "ahab"}})
+			if counting.puts != 2 {
+				t.Error("Expected changed values to be rendered again")
+			}
+
+			e.LintMode = true
+			if out := render(e, vals); out != "spouter" || counting.puts != 2 {
+				t.Errorf("Expected the render in lint mode to be served from the cache, got %q after %d renders", out, counting.puts)
+			}
+
+			c.Templates[0].Data = []byte("{{.Values.outer | upper}}")
+			if out := render(e, vals); out != "SPOUTER" || counting.puts != 3 {
+				t.Errorf("Expected a changed template to be rendered again, got %q", out)
+			}
+			e.LintMode = false
+			if out := render(e, vals); out != "SPOUTER" || counting.puts != 3 {
+				t.Errorf("Expected the render in lint mode to be served from the cache, got %q after %d renders", out, counting.puts)
+			}
+
+			// A render in lint mode which lets a missing required value
+			// through differs from the one outside of it.
+			c.Templates[0].Data = []byte(`{{required "missing is required" .Values.missing}}`)
+			e.LintMode = true
+			render(e, vals)
+			if counting.puts != 3 {
+				t.Error("Expected the render in lint mode letting a required value through not to be stored")
+			}
+			e.LintMode = false
+			if _, err := e.Render(c, vals); err == nil {
+				t.Error("Expected the render outside of lint mode to fail on the missing required value")
+			}
+		})
+	}
+}
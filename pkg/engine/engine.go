@@ -40,10 +40,23 @@ type Engine struct {
 	Strict bool
 	// In LintMode, some 'required' template values may be missing, so don't fail
 	LintMode bool
+	// LintLookup allows the lookup function to query the cluster in LintMode.
+	// Without it, lookup always returns an empty result when linting.
+	LintLookup bool
 	// optional provider of clients to talk to the Kubernetes API
 	clientProvider *ClientProvider
 	// EnableDNS tells the engine to allow DNS lookups when rendering templates
 	EnableDNS bool
+	// Cache, when set, is consulted before rendering and stores the rendered
+	// templates. It is not used while lookup can query a cluster, as the
+	// output then depends on the state of the cluster. Renders in LintMode
+	// share the entries of the renders outside of it: a render outside of
+	// LintMode is the same in it, and a render in LintMode is only stored if
+	// no missing required value or fail was let through.
+	Cache RenderCache
+	// relaxed, when set, records that a render in LintMode let a missing
+	// required value or a fail through.
+	relaxed *bool
 }
 
 // New creates a new instance of Engine using the passed in rest config.
@@ -74,8 +87,40 @@ func New(config *rest.Config) Engine {
 // section contains a value named "bar", that value will be passed on to the
 // bar chart during render time.
 func (e Engine) Render(chrt *chart.Chart, values chartutil.Values) (map[string]string, error) {
+	var key string
+	if e.Cache != nil && !e.lookupEnabled() {
+		shared := e
+		shared.LintMode = false
+		var err error
+		if key, err = shared.cacheKey(chrt, values); err != nil {
+			log.Printf("[WARNING] not using the render cache: %s", err)
+		} else if rendered, ok := e.Cache.Get(key); ok {
+			return rendered, nil
+		}
+	}
+
+	e.relaxed = new(bool)
 	tmap := allTemplates(chrt, values)
-	return e.render(tmap)
+	rendered, err := e.render(tmap)
+	if err == nil && key != "" && !*e.relaxed {
+		if err := e.Cache.Put(key, rendered); err != nil {
+			log.Printf("[WARNING] unable to write the render cache: %s", err)
+		}
+	}
+	return rendered, err
+}
+
+// relax records that a render in LintMode let a missing required value or a
+// fail through, so it differs from a render outside of LintMode.
+func (e Engine) relax() {
+	if e.relaxed != nil {
+		*e.relaxed = true
+	}
+}
+
+// lookupEnabled reports whether the lookup function queries a cluster.
+func (e Engine) lookupEnabled() bool {
+	return (!e.LintMode || e.LintLookup) && e.clientProvider != nil
 }
 
 // Render takes a chart, optional values, and value overrides, and attempts to
@@ -204,6 +249,7 @@ func (e Engine) initFunMap(t *template.Template) {
 			if e.LintMode {
 				// Don't fail on missing required values when linting
 				log.Printf("[INFO] Missing required value: %s", warn)
+				e.relax()
 				return "", nil
 			}
 			return val, errors.Errorf(warnWrap(warn))
@@ -212,6 +258,7 @@ func (e Engine) initFunMap(t *template.Template) {
 				if e.LintMode {
 					// Don't fail on missing required values when linting
 					log.Printf("[INFO] Missing required value: %s", warn)
+					e.relax()
 					return "", nil
 				}
 				return val, errors.Errorf(warnWrap(warn))
@@ -225,14 +272,15 @@ func (e Engine) initFunMap(t *template.Template) {
 		if e.LintMode {
 			// Don't fail when linting
 			log.Printf("[INFO] Fail: %s", msg)
+			e.relax()
 			return "", nil
 		}
 		return "", errors.New(warnWrap(msg))
 	}
 
-	// If we are not linting and have a cluster connection, provide a Kubernetes-backed
-	// implementation.
-	if !e.LintMode && e.clientProvider != nil {
+	// If we are not linting, or are explicitly asked to do lookups while linting,
+	// and have a cluster connection, provide a Kubernetes-backed implementation.
+	if e.lookupEnabled() {
 		funcMap["lookup"] = newLookupFunction(*e.clientProvider)
 	}
 
@@ -383,6 +383,55 @@ func TestRenderWithClientProvider(t *testing.T) {
 	}
 }
 
+func TestRenderLintModeLookup(t *testing.T) {
+	var provider ClientProvider = &testClientProvider{
+		t: t,
+		scheme: map[string]kindProps{
+			"v1/Namespace": {
+				gvr: schema.GroupVersionResource{
+					Version:  "v1",
+					Resource: "namespaces",
+				},
+			},
+		},
+		objects: []runtime.Object{
+			makeUnstructured("v1", "Namespace", "default", ""),
+		},
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:    "moby",
+			Version: "1.2.3",
+		},
+		Templates: []*chart.File{
+			{Name: "templates/lookup", Data: []byte(`{{ (lookup "v1" "Namespace" "" "default").metadata }}`)},
+		},
+		Values: map[string]interface{}{},
+	}
+	v, err := chartutil.CoalesceValues(c, map[string]interface{}{"Values": map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Failed to coalesce values: %s", err)
+	}
+
+	for _, tt := range []struct {
+		lintLookup bool
+		want       string
+	}{
+		{false, ""},
+		{true, "map[name:default]"},
+	} {
+		e := Engine{LintMode: true, LintLookup: tt.lintLookup, clientProvider: &provider}
+		out, err := e.Render(c, v)
+		if err != nil {
+			t.Fatalf("Failed to render templates: %s", err)
+		}
+		if got := out["moby/templates/lookup"]; got != tt.want {
+			t.Errorf("With LintLookup=%t, expected %q, got %q", tt.lintLookup, tt.want, got)
+		}
+	}
+}
+
 func TestRenderWithClientProvider_error(t *testing.T) {
 	c := &chart.Chart{
 		Metadata: &chart.Metadata{
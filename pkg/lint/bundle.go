@@ -0,0 +1,120 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint // import "helm.sh/helm/v3/pkg/lint"
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// RulesBundle is a set of rule configurations managed centrally by an
+// organization, so that the charts of all of its teams are linted alike.
+//
+// A bundle is a YAML file clear-signed with gpg --clearsign, with the
+// following format:
+//
+//	severities:
+//	  run-as-root: error
+//	ignores:
+//	- revision-history-limit templates/legacy-*.yaml
+//	rules:
+//	  image-registry:
+//	    allowed:
+//	    - registry.example.com
+//	policies:
+//	- name: require-team-label
+//	  expression: "has(object.metadata.labels) && 'team' in object.metadata.labels"
+type RulesBundle struct {
+	// Severities overrides the severity of the findings of rules, by rule
+	// ID. The severities are info, warning or error.
+	Severities map[string]string `json:"severities,omitempty"`
+	// Ignores are rules to ignore, in the format of the lines of an ignore
+	// file.
+	Ignores []string `json:"ignores,omitempty"`
+	// Rules configures the rules which are off without settings.
+	Rules *rules.RulesConfig `json:"rules,omitempty"`
+	// Policies are CEL policies evaluated against every rendered object, as
+	// in a policy file.
+	Policies []rules.Policy `json:"policies,omitempty"`
+
+	severities map[string]int
+	ignores    []ignoreRule
+}
+
+// LoadRulesBundle verifies that the bundle in data is signed by one of the
+// keys in keyring, and parses it.
+func LoadRulesBundle(data []byte, keyring string) (*RulesBundle, error) {
+	sig, err := provenance.NewFromKeyring(keyring, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load the keyring to verify the rules bundle")
+	}
+	content, _, err := sig.VerifyMessage(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to verify the signature of the rules bundle")
+	}
+	return ParseRulesBundle(content)
+}
+
+// ParseRulesBundle parses a bundle without verifying its signature.
+func ParseRulesBundle(data []byte) (*RulesBundle, error) {
+	b := &RulesBundle{}
+	if err := yaml.UnmarshalStrict(data, b); err != nil {
+		return nil, errors.Wrap(err, "unable to parse rules bundle")
+	}
+
+	b.severities = make(map[string]int, len(b.Severities))
+	for rule, name := range b.Severities {
+		switch name {
+		case "info":
+			b.severities[rule] = support.InfoSev
+		case "warning":
+			b.severities[rule] = support.WarningSev
+		case "error":
+			b.severities[rule] = support.ErrorSev
+		default:
+			return nil, errors.Errorf("invalid rules bundle: rule %q has unknown severity %q, must be one of: info, warning, error", rule, name)
+		}
+	}
+
+	ignores, err := parseIgnoreFile([]byte(strings.Join(b.Ignores, "\n")))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ignores in rules bundle")
+	}
+	b.ignores = ignores
+
+	if err := rules.CompilePolicies(b.Policies); err != nil {
+		return nil, errors.Wrap(err, "invalid policy in rules bundle")
+	}
+	return b, nil
+}
+
+// overrideSeverities sets the severity of the messages of the rules in
+// severities.
+func overrideSeverities(messages []support.Message, severities map[string]int) []support.Message {
+	for i, msg := range messages {
+		if sev, ok := severities[msg.RuleID]; ok && msg.RuleID != "" {
+			messages[i].Severity = sev
+		}
+	}
+	return messages
+}
@@ -0,0 +1,132 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestParseRulesBundle(t *testing.T) {
+	tests := []struct {
+		name   string
+		bundle string
+		err    string
+	}{{
+		name: "valid",
+		bundle: `severities:
+  crd-install-hook: error
+ignores:
+- release-time templates/deployment.yaml
+rules:
+  image-registry:
+    allowed:
+    - registry.example.com
+policies:
+- name: named
+  expression: "has(object.metadata.name)"
+`,
+	}, {
+		name:   "unknown severity",
+		bundle: "severities:\n  crd-hook: fatal\n",
+		err:    `rule "crd-hook" has unknown severity "fatal"`,
+	}, {
+		name:   "invalid ignore",
+		bundle: "ignores:\n- release-time templates/deployment.yaml extra\n",
+		err:    "invalid ignores in rules bundle",
+	}, {
+		name:   "invalid policy",
+		bundle: "policies:\n- name: broken\n  expression: \"object.\"\n",
+		err:    "invalid policy in rules bundle",
+	}, {
+		name:   "unknown field",
+		bundle: "severity: {}\n",
+		err:    "unable to parse rules bundle",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRulesBundle([]byte(tt.bundle))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRunAllWithRulesBundle(t *testing.T) {
+	bundle, err := ParseRulesBundle([]byte(`severities:
+  crd-install-hook: error
+ignores:
+- release-time templates/deployment.yaml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linter := RunAll("rules/testdata/v3-fail", values, namespace, WithRulesBundle(bundle))
+	var crdHooks int
+	for _, msg := range linter.Messages {
+		switch msg.RuleID {
+		case rules.RuleReleaseTime:
+			t.Errorf("Expected the ignored finding to be removed, got %s", msg)
+		case rules.RuleCRDHook:
+			crdHooks++
+			if msg.Severity != support.ErrorSev {
+				t.Errorf("Expected the severity of %s to be overridden", msg)
+			}
+		}
+	}
+	if crdHooks != 2 {
+		t.Errorf("Expected 2 crd-hook findings, got %d", crdHooks)
+	}
+	if linter.HighestSeverity != support.ErrorSev {
+		t.Errorf("Expected the overridden severity to be the highest, got %d", linter.HighestSeverity)
+	}
+}
+
+func TestRunAllWithRulesBundleAndRulesConfig(t *testing.T) {
+	bundle, err := ParseRulesBundle([]byte(`rules:
+  image-registry:
+    allowed:
+    - registry.example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rules config can't allow the registry the bundle doesn't.
+	config := &rules.RulesConfig{ImageRegistry: &rules.ImageRegistryConfig{Allowed: []string{"docker.io"}}}
+
+	linter := RunAll("rules/testdata/v3-fail", values, namespace, WithRulesBundle(bundle), WithRulesConfig(config))
+	var registries int
+	for _, msg := range linter.Messages {
+		if msg.RuleID == rules.RuleImageRegistry {
+			registries++
+		}
+	}
+	if registries != 1 {
+		t.Errorf("Expected 1 image-registry finding, got %d in %v", registries, linter.Messages)
+	}
+}
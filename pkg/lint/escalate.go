@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// escalate raises the severity of the findings of every rule which occurs
+// more often than its threshold by one level, up to an error, and notes the
+// escalation in the message.
+func escalate(messages []support.Message, thresholds map[string]int) []support.Message {
+	if len(thresholds) == 0 {
+		return messages
+	}
+	counts := map[string]int{}
+	for _, msg := range messages {
+		counts[msg.RuleID]++
+	}
+	escalated := make([]support.Message, len(messages))
+	for i, msg := range messages {
+		threshold, ok := thresholds[msg.RuleID]
+		if ok && msg.RuleID != "" && counts[msg.RuleID] > threshold && msg.Severity < support.ErrorSev {
+			msg.Severity++
+			msg.Err = errors.Errorf("%s (escalated: rule %q was found %d times, more than the threshold of %d)", msg.Err, msg.RuleID, counts[msg.RuleID], threshold)
+		}
+		escalated[i] = msg
+	}
+	return escalated
+}
+
+// highestSeverity returns the highest severity of messages.
+func highestSeverity(messages []support.Message) int {
+	highest := support.UnknownSev
+	for _, msg := range messages {
+		if msg.Severity > highest {
+			highest = msg.Severity
+		}
+	}
+	return highest
+}
@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestEscalate(t *testing.T) {
+	messages := []support.Message{
+		{Severity: support.InfoSev, Path: "templates/a.yaml", RuleID: "resource-limits", Err: errors.New("a")},
+		{Severity: support.InfoSev, Path: "templates/b.yaml", RuleID: "resource-limits", Err: errors.New("b")},
+		{Severity: support.ErrorSev, Path: "templates/c.yaml", RuleID: "resource-limits", Err: errors.New("c")},
+		{Severity: support.InfoSev, Path: "templates/d.yaml", RuleID: "run-as-root", Err: errors.New("d")},
+		{Severity: support.InfoSev, Path: "Chart.yaml", Err: errors.New("icon is recommended")},
+	}
+	escalated := escalate(messages, map[string]int{"resource-limits": 2, "run-as-root": 1, "": 0})
+
+	want := []struct {
+		severity int
+		text     string
+	}{
+		{support.WarningSev, `a (escalated: rule "resource-limits" was found 3 times, more than the threshold of 2)`},
+		{support.WarningSev, `b (escalated: rule "resource-limits" was found 3 times, more than the threshold of 2)`},
+		{support.ErrorSev, "c"},
+		{support.InfoSev, "d"},
+		{support.InfoSev, "icon is recommended"},
+	}
+	for i, w := range want {
+		if escalated[i].Severity != w.severity || escalated[i].Err.Error() != w.text {
+			t.Errorf("Expected message %d to be %d %q, got %d %q", i, w.severity, w.text, escalated[i].Severity, escalated[i].Err)
+		}
+	}
+	if messages[0].Severity != support.InfoSev {
+		t.Error("Expected the original messages to be left unchanged")
+	}
+	if highestSeverity(escalated) != support.ErrorSev {
+		t.Errorf("Expected the highest severity to be an error, got %d", highestSeverity(escalated))
+	}
+}
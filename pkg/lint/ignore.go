@@ -0,0 +1,165 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// IgnoreFileName is the name of the files listing the lint findings to ignore.
+//
+// Each line of an ignore file names a rule ID, optionally followed by a glob
+// matched against the path of the finding relative to the chart. Without a
+// glob, the rule is ignored for the whole chart. The rule ID '*' matches the
+// findings of every rule, with or without an ID. A line starting with
+// 'message:' instead ignores the findings whose message contains the rest of
+// the line. Blank lines and lines starting with '#' are skipped:
+//
+//	# legacy templates still use the old APIs
+//	deprecated-api templates/legacy-*.yaml
+//	run-as-root
+//	* templates/vendored/*
+//	message: icon is recommended
+//
+// Ignore files are looked up in the chart directory and all of its parents up
+// to the root of the enclosing git repository, so that ignores at the root of
+// a repository apply to every chart in it. All files found are merged.
+const IgnoreFileName = ".helmlintignore"
+
+// anyRule is the rule ID of the ignores matching every rule.
+const anyRule = "*"
+
+// messagePrefix starts the lines of an ignore file matching the findings by
+// their message.
+const messagePrefix = "message:"
+
+type ignoreRule struct {
+	ruleID string
+	glob   string
+	// message, when set, is a substring of the messages ignored, whatever
+	// their rule.
+	message string
+}
+
+// IgnoreFiles returns the paths of the ignore files which may apply to the
+// chart in chartDir, from the repository root down to the chart. The files
+// don't need to exist.
+func IgnoreFiles(chartDir string) []string {
+	var files []string
+	for dir := chartDir; ; dir = filepath.Dir(dir) {
+		files = append([]string{filepath.Join(dir, IgnoreFileName)}, files...)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || filepath.Dir(dir) == dir {
+			return files
+		}
+	}
+}
+
+// loadIgnoreFiles reads the ignore files applying to the chart in chartDir.
+func loadIgnoreFiles(chartDir string) ([]ignoreRule, error) {
+	var ignores []ignoreRule
+	for _, filename := range IgnoreFiles(chartDir) {
+		data, err := os.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules, err := parseIgnoreFile(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid ignore file %s", filename)
+		}
+		ignores = append(ignores, rules...)
+	}
+	return ignores, nil
+}
+
+func parseIgnoreFile(data []byte) ([]ignoreRule, error) {
+	var ignores []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if message, ok := strings.CutPrefix(text, messagePrefix); ok {
+			message = strings.TrimSpace(message)
+			if message == "" {
+				return nil, errors.Errorf("line %d: expected the text of the messages to ignore after %q", line, messagePrefix)
+			}
+			ignores = append(ignores, ignoreRule{message: message})
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, errors.Errorf("line %d: expected a rule ID and an optional path, got %q", line, text)
+		}
+		rule := ignoreRule{ruleID: fields[0]}
+		if len(fields) == 2 {
+			if _, err := path.Match(fields[1], ""); err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			rule.glob = fields[1]
+		}
+		ignores = append(ignores, rule)
+	}
+	return ignores, scanner.Err()
+}
+
+// filterIgnored removes the messages matched by ignores.
+func filterIgnored(messages []support.Message, ignores []ignoreRule) []support.Message {
+	if len(ignores) == 0 {
+		return messages
+	}
+	var kept []support.Message
+	for _, msg := range messages {
+		if !ignored(msg, ignores) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+func ignored(msg support.Message, ignores []ignoreRule) bool {
+	for _, rule := range ignores {
+		if rule.message != "" {
+			if msg.Err != nil && strings.Contains(msg.Err.Error(), rule.message) {
+				return true
+			}
+			continue
+		}
+		if rule.ruleID != anyRule && (msg.RuleID == "" || rule.ruleID != msg.RuleID) {
+			continue
+		}
+		if rule.glob == "" {
+			return true
+		}
+		if ok, _ := path.Match(rule.glob, msg.Path); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestLoadIgnoreFilesLayered(t *testing.T) {
+	outside := t.TempDir()
+	repo := filepath.Join(outside, "repo")
+	chartDir := filepath.Join(repo, "charts", "app")
+	for _, dir := range []string{filepath.Join(repo, ".git"), chartDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		// Above the repository root, so it must not be read.
+		filepath.Join(outside, IgnoreFileName):        "outside",
+		filepath.Join(repo, IgnoreFileName):           "# organization wide\nrun-as-root\n",
+		filepath.Join(repo, "charts", IgnoreFileName): "\ndeprecated-api templates/legacy-*.yaml\n",
+		filepath.Join(chartDir, IgnoreFileName):       "node-label-typo\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ignores, err := loadIgnoreFiles(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ignoreRule{
+		{ruleID: "run-as-root"},
+		{ruleID: "deprecated-api", glob: "templates/legacy-*.yaml"},
+		{ruleID: "node-label-typo"},
+	}
+	if !reflect.DeepEqual(ignores, want) {
+		t.Errorf("Expected ignores %v, got %v", want, ignores)
+	}
+}
+
+func TestLoadIgnoreFilesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte("deprecated-api templates/ extra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadIgnoreFiles(dir); err == nil {
+		t.Error("Expected an error for a malformed ignore file")
+	}
+}
+
+func TestFilterIgnored(t *testing.T) {
+	ignores := []ignoreRule{
+		{ruleID: "run-as-root"},
+		{ruleID: "deprecated-api", glob: "templates/legacy-*.yaml"},
+	}
+	messages := []support.Message{
+		{Severity: support.WarningSev, Path: "templates/app.yaml", RuleID: "run-as-root", Err: errors.New("root")},
+		{Severity: support.WarningSev, Path: "templates/legacy-hpa.yaml", RuleID: "deprecated-api", Err: errors.New("old")},
+		{Severity: support.WarningSev, Path: "templates/hpa.yaml", RuleID: "deprecated-api", Err: errors.New("old")},
+		{Severity: support.InfoSev, Path: "Chart.yaml", Err: errors.New("icon is recommended")},
+	}
+	kept := filterIgnored(messages, ignores)
+	if len(kept) != 2 || kept[0].Path != "templates/hpa.yaml" || kept[1].Path != "Chart.yaml" {
+		t.Errorf("Unexpected messages after filtering: %v", kept)
+	}
+}
+
+func TestFilterIgnoredByPathAndMessage(t *testing.T) {
+	ignores, err := parseIgnoreFile([]byte("# vendored templates aren't ours\n* templates/vendored/*\n\nmessage: icon is recommended\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []support.Message{
+		{Severity: support.WarningSev, Path: "templates/vendored/app.yaml", RuleID: "run-as-root", Err: errors.New("root")},
+		{Severity: support.ErrorSev, Path: "templates/vendored/app.yaml", Err: errors.New("unable to parse YAML")},
+		{Severity: support.WarningSev, Path: "templates/app.yaml", RuleID: "run-as-root", Err: errors.New("root")},
+		{Severity: support.InfoSev, Path: "Chart.yaml", RuleID: "icon", Err: errors.New("icon is recommended")},
+	}
+	kept := filterIgnored(messages, ignores)
+	if len(kept) != 1 || kept[0].Path != "templates/app.yaml" {
+		t.Errorf("Unexpected messages after filtering: %v", kept)
+	}
+
+	if _, err := parseIgnoreFile([]byte("message:  \n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error for an ignore without a message, got %v", err)
+	}
+}
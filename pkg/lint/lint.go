@@ -19,7 +19,11 @@ package lint // import "helm.sh/helm/v3/pkg/lint"
 import (
 	"path/filepath"
 
+	"k8s.io/client-go/rest"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
@@ -31,13 +35,258 @@ func All(basedir string, values map[string]interface{}, namespace string, _ bool
 
 // AllWithKubeVersion runs all the available linters on the given base directory, allowing to specify the kubernetes version.
 func AllWithKubeVersion(basedir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) support.Linter {
+	return RunAll(basedir, values, namespace, WithKubeVersion(kubeVersion))
+}
+
+type linterOptions struct {
+	KubeVersion         *chartutil.KubeVersion
+	Policies            []rules.Policy
+	ReportUnusedIgnores bool
+	LookupConfig        *rest.Config
+	RenderCache         engine.RenderCache
+	ReleaseName         string
+	Snapshot            string
+	UpdateSnapshot      bool
+	EscalateThresholds  map[string]int
+	RulesConfig         *rules.RulesConfig
+	StrictRender        bool
+	SchemaStrict        bool
+	ExpandValues        bool
+	RulesBundle         *RulesBundle
+	ConventionsOnly     bool
+	Style               bool
+	Questions           bool
+	RenderedCount       bool
+	ClusterCRDs         rules.CRDSchemas
+	APIVersions         chartutil.VersionSet
+	SkipRules           []string
+	ChartCache          *loader.Cache
+}
+
+// LinterOption configures a linting run started with RunAll.
+type LinterOption func(lint *linterOptions)
+
+// WithKubeVersion sets the Kubernetes version used for capabilities and deprecation checks.
+func WithKubeVersion(kubeVersion *chartutil.KubeVersion) LinterOption {
+	return func(lint *linterOptions) {
+		lint.KubeVersion = kubeVersion
+	}
+}
+
+// WithAPIVersions sets the API versions the templates see as served by the
+// cluster, in place of the default ones.
+func WithAPIVersions(versions chartutil.VersionSet) LinterOption {
+	return func(lint *linterOptions) {
+		lint.APIVersions = versions
+	}
+}
+
+// WithPolicies sets the CEL policies evaluated against every rendered object.
+func WithPolicies(policies []rules.Policy) LinterOption {
+	return func(lint *linterOptions) {
+		lint.Policies = policies
+	}
+}
+
+// WithReportUnusedIgnores reports ignore comments in templates which don't suppress any finding.
+func WithReportUnusedIgnores(report bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ReportUnusedIgnores = report
+	}
+}
+
+// WithLookupConfig connects the lookup template function to the cluster described by config.
+func WithLookupConfig(config *rest.Config) LinterOption {
+	return func(lint *linterOptions) {
+		lint.LookupConfig = config
+	}
+}
+
+// WithClusterCRDs validates the rendered custom resources against the schemas
+// of the CustomResourceDefinitions installed in a cluster.
+func WithClusterCRDs(schemas rules.CRDSchemas) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ClusterCRDs = schemas
+	}
+}
+
+// WithRenderCache consults cache before rendering the chart, and stores the
+// rendered templates in it.
+func WithRenderCache(cache engine.RenderCache) LinterOption {
+	return func(lint *linterOptions) {
+		lint.RenderCache = cache
+	}
+}
+
+// WithReleaseName sets the name of the release the chart is rendered for.
+// Unset, it is "test-release".
+func WithReleaseName(name string) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ReleaseName = name
+	}
+}
+
+// WithSnapshot compares the rendered templates with the snapshot stored in
+// filename. With update, the snapshot is rewritten instead.
+func WithSnapshot(filename string, update bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.Snapshot = filename
+		lint.UpdateSnapshot = update
+	}
+}
+
+// WithEscalateThresholds raises the severity of the findings of a rule by one
+// level when the rule is found more often in a chart than its threshold.
+// Thresholds are keyed by rule ID.
+func WithEscalateThresholds(thresholds map[string]int) LinterOption {
+	return func(lint *linterOptions) {
+		lint.EscalateThresholds = thresholds
+	}
+}
+
+// WithRulesConfig configures the rules which are off without settings.
+func WithRulesConfig(config *rules.RulesConfig) LinterOption {
+	return func(lint *linterOptions) {
+		lint.RulesConfig = config
+	}
+}
+
+// WithStrictRender fails the render on references to missing values.
+func WithStrictRender(strict bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.StrictRender = strict
+	}
+}
+
+// WithSchemaStrict reports each value violating the schema of the chart as
+// an error of its own, naming the JSON path of the value and the reason.
+func WithSchemaStrict(strict bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.SchemaStrict = strict
+	}
+}
+
+// WithExpandValueTemplates runs the string values containing template syntax
+// through `tpl` before rendering.
+func WithExpandValueTemplates(expand bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ExpandValues = expand
+	}
+}
+
+// WithRulesBundle applies the rule configurations of a bundle. Policies and
+// settings passed with the other options are used along with those of the
+// bundle. The rules config is layered over the bundle's with
+// rules.MergeRulesConfig, so it can only make the rules stricter.
+func WithRulesBundle(bundle *RulesBundle) LinterOption {
+	return func(lint *linterOptions) {
+		lint.RulesBundle = bundle
+	}
+}
+
+// WithConventionsOnly only runs the rules about the Helm conventions of a
+// chart, such as its metadata, values, template sources, the names of its
+// defined templates and its NOTES.txt, and skips the rules about the rendered
+// manifests. Nothing is rendered.
+func WithConventionsOnly(conventionsOnly bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ConventionsOnly = conventionsOnly
+	}
+}
+
+// WithRenderedCount reports the number of objects the templates of the chart
+// render, by kind, as an info.
+func WithRenderedCount(count bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.RenderedCount = count
+	}
+}
+
+// WithChartCache loads the chart from cache, which keeps it for the other
+// lint runs of the chart while its files are unchanged.
+func WithChartCache(cache *loader.Cache) LinterOption {
+	return func(lint *linterOptions) {
+		lint.ChartCache = cache
+	}
+}
+
+// WithQuestions checks the questions file of charts targeting Rancher
+// against their values.
+func WithQuestions(questions bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.Questions = questions
+	}
+}
+
+// WithStyle runs the style rule, which checks the layout of the YAML files
+// of a chart. It also runs when the rules config configures it.
+func WithStyle(style bool) LinterOption {
+	return func(lint *linterOptions) {
+		lint.Style = style
+	}
+}
+
+// RunAll runs all the available linters on the given base directory, using the given options.
+func RunAll(basedir string, values map[string]interface{}, namespace string, options ...LinterOption) support.Linter {
 	// Using abs path to get directory context
 	chartDir, _ := filepath.Abs(basedir)
 
+	lo := linterOptions{}
+	for _, option := range options {
+		option(&lo)
+	}
+
+	policies, rulesConfig := lo.Policies, lo.RulesConfig
+	if b := lo.RulesBundle; b != nil {
+		policies = append(append([]rules.Policy{}, b.Policies...), policies...)
+		rulesConfig = rules.MergeRulesConfig(b.Rules, rulesConfig)
+	}
+
 	linter := support.Linter{ChartDir: chartDir}
 	rules.Chartfile(&linter)
-	rules.ValuesWithOverrides(&linter, values)
-	rules.TemplatesWithKubeVersion(&linter, values, namespace, kubeVersion)
-	rules.Dependencies(&linter)
+	rules.ValuesWithOptions(&linter, values, rules.ValuesOptions{SchemaStrict: lo.SchemaStrict})
+	rules.TemplatesWithOptions(&linter, values, namespace, rules.TemplateOptions{
+		KubeVersion:         lo.KubeVersion,
+		Policies:            policies,
+		ReportUnusedIgnores: lo.ReportUnusedIgnores,
+		LookupConfig:        lo.LookupConfig,
+		RenderCache:         lo.RenderCache,
+		ReleaseName:         lo.ReleaseName,
+		Snapshot:            lo.Snapshot,
+		UpdateSnapshot:      lo.UpdateSnapshot,
+		RulesConfig:         rulesConfig,
+		StrictRender:        lo.StrictRender,
+		ExpandValues:        lo.ExpandValues,
+		ConventionsOnly:     lo.ConventionsOnly,
+		ClusterCRDs:         lo.ClusterCRDs,
+		APIVersions:         lo.APIVersions,
+		RenderedCount:       lo.RenderedCount,
+		ChartCache:          lo.ChartCache,
+	})
+	rules.DependenciesWithOptions(&linter, rules.DependencyOptions{ChartCache: lo.ChartCache})
+	if lo.Style || (rulesConfig != nil && rulesConfig.Style != nil) {
+		var style *rules.StyleConfig
+		if rulesConfig != nil {
+			style = rulesConfig.Style
+		}
+		rules.Style(&linter, style)
+	}
+	if lo.Questions {
+		rules.Questions(&linter)
+	}
+
+	ignores, err := loadIgnoreFiles(chartDir)
+	if linter.RunLinterRule(support.ErrorSev, IgnoreFileName, err) {
+		if lo.RulesBundle != nil {
+			ignores = append(ignores, lo.RulesBundle.ignores...)
+		}
+		linter.Messages = filterIgnored(linter.Messages, ignores)
+	}
+	linter.Messages = SkipRules(linter.Messages, lo.SkipRules)
+	if lo.RulesBundle != nil {
+		linter.Messages = overrideSeverities(linter.Messages, lo.RulesBundle.severities)
+	}
+	linter.Messages = escalate(linter.Messages, lo.EscalateThresholds)
+	linter.HighestSeverity = highestSeverity(linter.Messages)
 	return linter
 }
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -125,15 +126,18 @@ func TestHelmCreateChart(t *testing.T) {
 	}
 
 	// Note: we test with strict=true here, even though others have
-	// strict = false.
+	// strict = false. The scaffold keeps the default revision history of
+	// its Deployment, which is only reported as info.
 	m := All(createdChart, values, namespace, true).Messages
-	if ll := len(m); ll != 1 {
-		t.Errorf("All should have had exactly 1 error. Got %d", ll)
+	if ll := len(m); ll != 2 {
+		t.Errorf("All should have had exactly 2 errors. Got %d", ll)
 		for i, msg := range m {
 			t.Logf("Message %d: %s", i, msg.Error())
 		}
 	} else if msg := m[0].Err.Error(); !strings.Contains(msg, "icon is recommended") {
 		t.Errorf("Unexpected lint error: %s", msg)
+	} else if m[1].RuleID != rules.RuleRevisionHistory || m[1].Severity != support.InfoSev {
+		t.Errorf("Unexpected lint error: %s", m[1].Error())
 	}
 }
 
@@ -0,0 +1,129 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// utilizationMetric is a metric of a HorizontalPodAutoscaler targeting the
+// average utilization of a resource, which is relative to the requests of the
+// containers.
+type utilizationMetric struct {
+	resource string
+	// container, when set, is the only container the metric is measured on.
+	container string
+}
+
+// validateHPAMetrics checks that a HorizontalPodAutoscaler has metrics to
+// scale on and, for the metrics targeting the utilization of a resource, that
+// the containers of the workload it scales request the resource. Without
+// requests, the utilization can't be computed and the HPA doesn't scale.
+// Workloads which are not rendered by the chart are not checked.
+func validateHPAMetrics(obj renderedObject, index objectIndex) error {
+	if obj.GetKind() != "HorizontalPodAutoscaler" {
+		return nil
+	}
+	var problems []string
+	var metrics []utilizationMetric
+	if strings.HasPrefix(obj.GetAPIVersion(), "autoscaling/v1") {
+		// autoscaling/v1 only scales on the CPU utilization, 80% unless set.
+		metrics = append(metrics, utilizationMetric{resource: "cpu"})
+	} else {
+		specs, _, _ := unstructured.NestedSlice(obj.Object, "spec", "metrics")
+		if len(specs) == 0 {
+			problems = append(problems, "has no metrics")
+		}
+		for _, spec := range specs {
+			if m, ok := hpaUtilizationMetric(spec); ok {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+
+	targetKind, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "kind")
+	targetName, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "name")
+	if target, ok := index.get(targetKind, targetName); ok && len(metrics) > 0 {
+		if tmpl, ok := target.podTemplate(); ok {
+			for _, m := range metrics {
+				if missing := containersWithoutRequest(tmpl.Spec.Containers, m); len(missing) > 0 {
+					problems = append(problems, fmt.Sprintf("scales %s on its %s utilization, but container(s) %s don't request %s", target, m.resource, strings.Join(missing, ", "), m.resource))
+				}
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s %s", obj, strings.Join(problems, " and "))
+}
+
+// hpaUtilizationMetric returns the resource and container of a metric of a
+// HorizontalPodAutoscaler targeting an average utilization, in the formats of
+// autoscaling/v2 and its betas.
+func hpaUtilizationMetric(spec interface{}) (utilizationMetric, bool) {
+	metric, ok := spec.(map[string]interface{})
+	if !ok {
+		return utilizationMetric{}, false
+	}
+	field := ""
+	switch metric["type"] {
+	case "Resource":
+		field = "resource"
+	case "ContainerResource":
+		field = "containerResource"
+	default:
+		return utilizationMetric{}, false
+	}
+	source, ok := metric[field].(map[string]interface{})
+	if !ok {
+		return utilizationMetric{}, false
+	}
+	targetType, _, _ := unstructured.NestedString(source, "target", "type")
+	_, beta := source["targetAverageUtilization"]
+	if targetType != "Utilization" && !beta {
+		return utilizationMetric{}, false
+	}
+	m := utilizationMetric{}
+	m.resource, _, _ = unstructured.NestedString(source, "name")
+	m.container, _, _ = unstructured.NestedString(source, "container")
+	return m, m.resource != ""
+}
+
+// containersWithoutRequest returns the names of the containers a
+// utilization metric is measured on which don't request its resource. A
+// container setting only a limit requests it too, as Kubernetes defaults the
+// request to the limit.
+func containersWithoutRequest(containers []corev1.Container, m utilizationMetric) []string {
+	var missing []string
+	for _, c := range containers {
+		if m.container != "" && c.Name != m.container {
+			continue
+		}
+		_, requested := c.Resources.Requests[corev1.ResourceName(m.resource)]
+		_, limited := c.Resources.Limits[corev1.ResourceName(m.resource)]
+		if !requested && !limited {
+			missing = append(missing, c.Name)
+		}
+	}
+	return missing
+}
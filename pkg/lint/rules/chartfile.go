@@ -31,15 +31,30 @@ import (
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
+// Identifiers of the Chart.yaml rules. Like those of the template rules,
+// they can be given to --skip-rules or used in ignore files.
+const (
+	RuleChartfile         = "chartfile"
+	RuleChartName         = "chart-name"
+	RuleChartAPIVersion   = "chart-api-version"
+	RuleChartVersion      = "chart-version"
+	RuleAppVersion        = "app-version"
+	RuleMaintainers       = "maintainers"
+	RuleChartSources      = "sources"
+	RuleChartIcon         = "icon"
+	RuleChartType         = "chart-type"
+	RuleChartDependencies = "chart-dependencies"
+)
+
 // Chartfile runs a set of linter rules related to Chart.yaml file
 func Chartfile(linter *support.Linter) {
 	chartFileName := "Chart.yaml"
 	chartPath := filepath.Join(linter.ChartDir, chartFileName)
 
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartYamlNotDirectory(chartPath))
+	linter.RunLinterRuleWithID(RuleChartfile, support.ErrorSev, chartFileName, validateChartYamlNotDirectory(chartPath))
 
 	chartFile, err := chartutil.LoadChartfile(chartPath)
-	validChartFile := linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartYamlFormat(err))
+	validChartFile := linter.RunLinterRuleWithID(RuleChartfile, support.ErrorSev, chartFileName, validateChartYamlFormat(err))
 
 	// Guard clause. Following linter rules require a parsable ChartFile
 	if !validChartFile {
@@ -50,20 +65,20 @@ func Chartfile(linter *support.Linter) {
 	// errors would already be caught in the above load function
 	chartFileForTypeCheck, _ := loadChartFileForTypeCheck(chartPath)
 
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartName(chartFile))
+	linter.RunLinterRuleWithID(RuleChartName, support.ErrorSev, chartFileName, validateChartName(chartFile))
 
 	// Chart metadata
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartAPIVersion(chartFile))
-
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartVersionType(chartFileForTypeCheck))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartVersion(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartAppVersionType(chartFileForTypeCheck))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartMaintainer(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartSources(chartFile))
-	linter.RunLinterRule(support.InfoSev, chartFileName, validateChartIconPresence(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartIconURL(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartType(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartDependencies(chartFile))
+	linter.RunLinterRuleWithID(RuleChartAPIVersion, support.ErrorSev, chartFileName, validateChartAPIVersion(chartFile))
+
+	linter.RunLinterRuleWithID(RuleChartVersion, support.ErrorSev, chartFileName, validateChartVersionType(chartFileForTypeCheck))
+	linter.RunLinterRuleWithID(RuleChartVersion, support.ErrorSev, chartFileName, validateChartVersion(chartFile))
+	linter.RunLinterRuleWithID(RuleAppVersion, support.ErrorSev, chartFileName, validateChartAppVersionType(chartFileForTypeCheck))
+	linter.RunLinterRuleWithID(RuleMaintainers, support.ErrorSev, chartFileName, validateChartMaintainer(chartFile))
+	linter.RunLinterRuleWithID(RuleChartSources, support.ErrorSev, chartFileName, validateChartSources(chartFile))
+	linter.RunLinterRuleWithID(RuleChartIcon, support.InfoSev, chartFileName, validateChartIconPresence(chartFile))
+	linter.RunLinterRuleWithID(RuleChartIcon, support.ErrorSev, chartFileName, validateChartIconURL(chartFile))
+	linter.RunLinterRuleWithID(RuleChartType, support.ErrorSev, chartFileName, validateChartType(chartFile))
+	linter.RunLinterRuleWithID(RuleChartDependencies, support.ErrorSev, chartFileName, validateChartDependencies(chartFile))
 }
 
 func validateChartVersionType(data map[string]interface{}) error {
@@ -0,0 +1,354 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// RulesConfig holds the settings of the rules which can be configured. The
+// rules which need settings, such as image-registry, are off without them.
+// In the file, the settings are keyed by rule ID.
+type RulesConfig struct {
+	// ImageRegistry enables the image-registry rule.
+	ImageRegistry *ImageRegistryConfig `json:"image-registry,omitempty"`
+	// Style enables the style rule.
+	Style *StyleConfig `json:"style,omitempty"`
+	// OwnerReferences configures the owner-references rule.
+	OwnerReferences *OwnerReferencesConfig `json:"owner-references,omitempty"`
+	// StartupProbe configures the startup-probe rule.
+	StartupProbe *StartupProbeConfig `json:"startup-probe,omitempty"`
+	// CRFieldTypes enables the cr-field-types rule.
+	CRFieldTypes *CRFieldTypesConfig `json:"cr-field-types,omitempty"`
+	// DocumentSeparators enables the document-separators rule.
+	DocumentSeparators *DocumentSeparatorsConfig `json:"document-separators,omitempty"`
+	// ResourceKinds enables the resource-kinds rule.
+	ResourceKinds *ResourceKindsConfig `json:"resource-kinds,omitempty"`
+	// Score sets the weights of the quality score printed by `helm lint
+	// --score`. It is not a rule.
+	Score *ScoreConfig `json:"score,omitempty"`
+}
+
+// ImageRegistryConfig configures the image-registry rule.
+type ImageRegistryConfig struct {
+	// Allowed lists the registries container images may be pulled from,
+	// e.g. registry.example.com. An entry may include a path, such as
+	// registry.example.com/mirror, to only allow the repositories below it.
+	Allowed []string `json:"allowed"`
+}
+
+// StyleConfig configures the style rule.
+type StyleConfig struct {
+	// Indent is the number of spaces each indentation level of the YAML
+	// files uses. It defaults to 2.
+	Indent int `json:"indent,omitempty"`
+}
+
+// OwnerReferencesConfig configures the owner-references rule.
+type OwnerReferencesConfig struct {
+	// Allowed lists the objects which may set ownerReferences, as
+	// Kind/name, e.g. ConfigMap/operator-state.
+	Allowed []string `json:"allowed"`
+}
+
+// StartupProbeConfig configures the startup-probe rule.
+type StartupProbeConfig struct {
+	// InitialDelay is the initialDelaySeconds of a liveness probe from which
+	// a container should rather have a startup probe. It defaults to 60.
+	InitialDelay int32 `json:"initial-delay,omitempty"`
+}
+
+// CRFieldTypesConfig configures the cr-field-types rule.
+type CRFieldTypesConfig struct {
+	// Fields lists the fields of custom resources and the type of the values
+	// they expect.
+	Fields []CRFieldType `json:"fields"`
+}
+
+// CRFieldType is the type of the value expected at a field of a custom
+// resource.
+type CRFieldType struct {
+	// APIVersion, when set, restricts the field to the resources of this
+	// group and version, e.g. cert-manager.io/v1.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Kind is the kind of the resources, e.g. Certificate.
+	Kind string `json:"kind"`
+	// Path is the dotted path of the field, e.g. spec.duration. A "[*]"
+	// suffix on a segment matches every item of a list, and a "*" segment
+	// every key of a map.
+	Path string `json:"path"`
+	// Type is the expected type of the value: quantity, duration or int.
+	Type string `json:"type"`
+}
+
+// crFieldTypes are the types a CRFieldType may expect.
+var crFieldTypes = []string{"quantity", "duration", "int"}
+
+// DocumentSeparatorsConfig configures the document-separators rule.
+type DocumentSeparatorsConfig struct {
+	// Leading, when set, requires every rendered template to start with a
+	// document separator, if "always", or not to, if "never".
+	Leading string `json:"leading,omitempty"`
+}
+
+// ResourceKindsConfig configures the resource-kinds rule. The entries are
+// apiVersion/kind, e.g. rbac.authorization.k8s.io/v1/ClusterRoleBinding, and
+// may use glob patterns, such as policy/*/PodSecurityPolicy for every version.
+type ResourceKindsConfig struct {
+	// Allowed, when set, lists the only kinds the charts may render.
+	Allowed []string `json:"allowed,omitempty"`
+	// Denied lists the kinds the charts must not render.
+	Denied []string `json:"denied,omitempty"`
+}
+
+// ScoreConfig sets the weights of the quality score.
+type ScoreConfig struct {
+	// Severities are the points a finding of each severity, info, warning
+	// or error, costs.
+	Severities map[string]float64 `json:"severities,omitempty"`
+	// Rules are the factors the cost of the findings of each rule is
+	// multiplied with, keyed by rule ID.
+	Rules map[string]float64 `json:"rules,omitempty"`
+}
+
+// LoadRulesConfig reads the rules configuration from filename.
+//
+// The file is YAML with the following format:
+//
+//	image-registry:
+//	  allowed:
+//	  - registry.example.com
+//	style:
+//	  indent: 2
+//	owner-references:
+//	  allowed:
+//	  - ConfigMap/operator-state
+//	startup-probe:
+//	  initial-delay: 60
+//	cr-field-types:
+//	  fields:
+//	  - kind: Certificate
+//	    apiVersion: cert-manager.io/v1
+//	    path: spec.duration
+//	    type: duration
+//	document-separators:
+//	  leading: never
+//	resource-kinds:
+//	  denied:
+//	  - rbac.authorization.k8s.io/v1/ClusterRoleBinding
+//	score:
+//	  severities:
+//	    warning: 10
+//	  rules:
+//	    deprecated-api: 2
+func LoadRulesConfig(filename string) (*RulesConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read rules config")
+	}
+	config := &RulesConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse rules config %s", filename)
+	}
+	if config.ImageRegistry != nil && len(config.ImageRegistry.Allowed) == 0 {
+		return nil, errors.Errorf("invalid rules config %s: %s needs at least one allowed registry", filename, RuleImageRegistry)
+	}
+	if config.Style != nil && config.Style.Indent < 0 {
+		return nil, errors.Errorf("invalid rules config %s: the indent of %s must be positive", filename, RuleStyle)
+	}
+	if config.OwnerReferences != nil {
+		for _, allowed := range config.OwnerReferences.Allowed {
+			if kind, name, ok := strings.Cut(allowed, "/"); !ok || kind == "" || name == "" {
+				return nil, errors.Errorf("invalid rules config %s: %s entry %q must be Kind/name", filename, RuleOwnerReferences, allowed)
+			}
+		}
+	}
+	if config.StartupProbe != nil && config.StartupProbe.InitialDelay < 0 {
+		return nil, errors.Errorf("invalid rules config %s: the initial delay of %s must not be negative", filename, RuleStartupProbe)
+	}
+	if config.CRFieldTypes != nil {
+		for _, field := range config.CRFieldTypes.Fields {
+			if field.Kind == "" || field.Path == "" {
+				return nil, errors.Errorf("invalid rules config %s: every %s field needs a kind and a path", filename, RuleCRFieldTypes)
+			}
+			if !slices.Contains(crFieldTypes, field.Type) {
+				return nil, errors.Errorf("invalid rules config %s: unknown %s type %q for %s %s, must be one of: %s", filename, RuleCRFieldTypes, field.Type, field.Kind, field.Path, strings.Join(crFieldTypes, ", "))
+			}
+		}
+	}
+	if config.DocumentSeparators != nil {
+		switch config.DocumentSeparators.Leading {
+		case "", "always", "never":
+		default:
+			return nil, errors.Errorf("invalid rules config %s: unknown leading separator setting %q of %s, must be always or never", filename, config.DocumentSeparators.Leading, RuleDocSeparators)
+		}
+	}
+	if config.ResourceKinds != nil {
+		if config.ResourceKinds.Allowed != nil && len(config.ResourceKinds.Allowed) == 0 {
+			return nil, errors.Errorf("invalid rules config %s: %s needs at least one allowed kind", filename, RuleResourceKinds)
+		}
+		for _, entry := range append(slices.Clone(config.ResourceKinds.Allowed), config.ResourceKinds.Denied...) {
+			if i := strings.LastIndex(entry, "/"); i <= 0 || i == len(entry)-1 {
+				return nil, errors.Errorf("invalid rules config %s: %s entry %q must be apiVersion/kind", filename, RuleResourceKinds, entry)
+			}
+			if _, err := path.Match(entry, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid rules config %s: %s entry %q", filename, RuleResourceKinds, entry)
+			}
+		}
+	}
+	if config.Score != nil {
+		for severity, weight := range config.Score.Severities {
+			if severity != "info" && severity != "warning" && severity != "error" {
+				return nil, errors.Errorf("invalid rules config %s: unknown score severity %q, must be one of: info, warning, error", filename, severity)
+			}
+			if weight < 0 {
+				return nil, errors.Errorf("invalid rules config %s: the score weight of %s must not be negative", filename, severity)
+			}
+		}
+		for rule, weight := range config.Score.Rules {
+			if weight < 0 {
+				return nil, errors.Errorf("invalid rules config %s: the score weight of rule %q must not be negative", filename, rule)
+			}
+		}
+	}
+	return config, nil
+}
+
+// LocalRulesConfigFile is the rules configuration of a chart, relative to
+// the chart directory. It applies to the chart in addition to the
+// configuration it is linted with.
+const LocalRulesConfigFile = "ci/lint-rules.yaml"
+
+// LoadLocalRulesConfig reads the rules configuration of the chart in
+// chartDir. It returns nil if the chart has none.
+func LoadLocalRulesConfig(chartDir string) (*RulesConfig, error) {
+	filename := filepath.Join(chartDir, LocalRulesConfigFile)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return LoadRulesConfig(filename)
+}
+
+// MergeRulesConfig layers the local configuration of a chart over the
+// configuration applying to its whole scope, such as the one of its parent
+// chart. The local configuration can only make the rules stricter:
+//
+//   - image-registry: only the registries allowed by both are allowed. A
+//     local entry is kept if it lies within a registry allowed globally.
+//   - owner-references: only the objects allowed by both may set
+//     ownerReferences, so a local config can't allow any object by itself.
+//   - startup-probe: the lowest initial delay wins.
+//   - cr-field-types: the fields of both are checked.
+//   - resource-kinds: the kinds denied by either are denied. Only the
+//     local allowed entries which the global ones match are allowed.
+//   - style: the rule runs if either enables it. The global indent wins.
+//   - document-separators: the rule runs if either enables it. The global
+//     leading separator setting wins, if it has one.
+//   - score: the global weights win, as they don't change any finding.
+func MergeRulesConfig(global, local *RulesConfig) *RulesConfig {
+	if local == nil {
+		return global
+	}
+	if global == nil {
+		global = &RulesConfig{}
+	}
+	merged := *global
+
+	switch {
+	case local.ImageRegistry == nil:
+	case global.ImageRegistry == nil:
+		merged.ImageRegistry = local.ImageRegistry
+	default:
+		allowed := []string{}
+		for _, a := range local.ImageRegistry.Allowed {
+			if registryAllowed(strings.TrimSuffix(a, "/")+"/", global.ImageRegistry.Allowed) {
+				allowed = append(allowed, a)
+			}
+		}
+		merged.ImageRegistry = &ImageRegistryConfig{Allowed: allowed}
+	}
+
+	if global.OwnerReferences != nil && local.OwnerReferences != nil {
+		allowed := []string{}
+		for _, a := range local.OwnerReferences.Allowed {
+			for _, g := range global.OwnerReferences.Allowed {
+				if a == g {
+					allowed = append(allowed, a)
+					break
+				}
+			}
+		}
+		merged.OwnerReferences = &OwnerReferencesConfig{Allowed: allowed}
+	}
+
+	if local.StartupProbe != nil && (global.StartupProbe == nil || local.StartupProbe.initialDelay() < global.StartupProbe.initialDelay()) {
+		merged.StartupProbe = local.StartupProbe
+	}
+
+	switch {
+	case local.CRFieldTypes == nil:
+	case global.CRFieldTypes == nil:
+		merged.CRFieldTypes = local.CRFieldTypes
+	default:
+		fields := append(slices.Clone(global.CRFieldTypes.Fields), local.CRFieldTypes.Fields...)
+		merged.CRFieldTypes = &CRFieldTypesConfig{Fields: fields}
+	}
+
+	if global.Style == nil {
+		merged.Style = local.Style
+	}
+
+	switch {
+	case local.ResourceKinds == nil:
+	case global.ResourceKinds == nil:
+		merged.ResourceKinds = local.ResourceKinds
+	default:
+		kinds := &ResourceKindsConfig{
+			Allowed: global.ResourceKinds.Allowed,
+			Denied:  append(slices.Clone(global.ResourceKinds.Denied), local.ResourceKinds.Denied...),
+		}
+		switch {
+		case local.ResourceKinds.Allowed == nil:
+		case global.ResourceKinds.Allowed == nil:
+			kinds.Allowed = local.ResourceKinds.Allowed
+		default:
+			kinds.Allowed = []string{}
+			for _, a := range local.ResourceKinds.Allowed {
+				if kindMatches(a, global.ResourceKinds.Allowed) {
+					kinds.Allowed = append(kinds.Allowed, a)
+				}
+			}
+		}
+		merged.ResourceKinds = kinds
+	}
+
+	if local.DocumentSeparators != nil && (global.DocumentSeparators == nil || global.DocumentSeparators.Leading == "") {
+		merged.DocumentSeparators = local.DocumentSeparators
+	}
+
+	if global.Score == nil {
+		merged.Score = local.Score
+	}
+	return &merged
+}
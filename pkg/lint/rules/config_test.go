@@ -0,0 +1,219 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRulesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		allowed []string
+		err     string
+	}{{
+		name:    "image registry",
+		content: "image-registry:\n  allowed:\n  - registry.example.com\n",
+		allowed: []string{"registry.example.com"},
+	}, {
+		name:    "empty",
+		content: "",
+	}, {
+		name:    "unknown rule",
+		content: "no-such-rule: {}\n",
+		err:     "unable to parse rules config",
+	}, {
+		name:    "no allowed registries",
+		content: "image-registry:\n  allowed: []\n",
+		err:     "image-registry needs at least one allowed registry",
+	}, {
+		name:    "negative style indent",
+		content: "style:\n  indent: -2\n",
+		err:     "the indent of style must be positive",
+	}, {
+		name:    "invalid owner references entry",
+		content: "owner-references:\n  allowed:\n  - operator-state\n",
+		err:     `owner-references entry "operator-state" must be Kind/name`,
+	}, {
+		name:    "negative startup probe delay",
+		content: "startup-probe:\n  initial-delay: -1\n",
+		err:     "the initial delay of startup-probe must not be negative",
+	}, {
+		name:    "unknown leading document separator setting",
+		content: "document-separators:\n  leading: sometimes\n",
+		err:     `unknown leading separator setting "sometimes" of document-separators`,
+	}, {
+		name:    "no allowed resource kinds",
+		content: "resource-kinds:\n  allowed: []\n",
+		err:     "resource-kinds needs at least one allowed kind",
+	}, {
+		name:    "resource kind without an apiVersion",
+		content: "resource-kinds:\n  denied:\n  - ClusterRoleBinding\n",
+		err:     `resource-kinds entry "ClusterRoleBinding" must be apiVersion/kind`,
+	}, {
+		name:    "cr field without a path",
+		content: "cr-field-types:\n  fields:\n  - kind: Certificate\n    type: duration\n",
+		err:     "every cr-field-types field needs a kind and a path",
+	}, {
+		name:    "unknown cr field type",
+		content: "cr-field-types:\n  fields:\n  - kind: Certificate\n    path: spec.duration\n    type: days\n",
+		err:     `unknown cr-field-types type "days" for Certificate spec.duration`,
+	}, {
+		name:    "unknown score severity",
+		content: "score:\n  severities:\n    fatal: 50\n",
+		err:     `unknown score severity "fatal"`,
+	}, {
+		name:    "negative score weight",
+		content: "score:\n  rules:\n    deprecated-api: -1\n",
+		err:     `the score weight of rule "deprecated-api" must not be negative`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "rules.yaml")
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadRulesConfig(filename)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.allowed == nil {
+				if config.ImageRegistry != nil {
+					t.Errorf("Expected the image-registry rule to be off, got %v", config.ImageRegistry)
+				}
+				return
+			}
+			if config.ImageRegistry == nil || strings.Join(config.ImageRegistry.Allowed, ",") != strings.Join(tt.allowed, ",") {
+				t.Errorf("Expected allowed registries %v, got %v", tt.allowed, config.ImageRegistry)
+			}
+		})
+	}
+}
+
+func TestMergeRulesConfig(t *testing.T) {
+	global := &RulesConfig{
+		ImageRegistry:   &ImageRegistryConfig{Allowed: []string{"registry.example.com"}},
+		OwnerReferences: &OwnerReferencesConfig{Allowed: []string{"ConfigMap/state", "Secret/state"}},
+		Style:           &StyleConfig{Indent: 4},
+	}
+	local := &RulesConfig{
+		ImageRegistry:   &ImageRegistryConfig{Allowed: []string{"registry.example.com/team", "docker.io"}},
+		OwnerReferences: &OwnerReferencesConfig{Allowed: []string{"Secret/state", "Job/migrate"}},
+		Style:           &StyleConfig{Indent: 2},
+		Score:           &ScoreConfig{Severities: map[string]float64{"warning": 10}},
+	}
+
+	merged := MergeRulesConfig(global, local)
+	if got := strings.Join(merged.ImageRegistry.Allowed, ","); got != "registry.example.com/team" {
+		t.Errorf("Expected only the registries allowed by both, got %s", got)
+	}
+	if got := strings.Join(merged.OwnerReferences.Allowed, ","); got != "Secret/state" {
+		t.Errorf("Expected only the objects allowed by both, got %s", got)
+	}
+	if merged.Style.Indent != 4 {
+		t.Errorf("Expected the global indent to win, got %d", merged.Style.Indent)
+	}
+	if merged.Score != local.Score {
+		t.Errorf("Expected the local score weights without global ones, got %v", merged.Score)
+	}
+	if len(global.ImageRegistry.Allowed) != 1 {
+		t.Errorf("Expected the global config to be left unchanged, got %v", global.ImageRegistry.Allowed)
+	}
+
+	merged = MergeRulesConfig(nil, local)
+	if merged.OwnerReferences != nil {
+		t.Errorf("Expected a local config not to allow ownerReferences by itself, got %v", merged.OwnerReferences)
+	}
+	if merged.ImageRegistry != local.ImageRegistry {
+		t.Errorf("Expected the local image-registry config to enable the rule, got %v", merged.ImageRegistry)
+	}
+	if merged.StartupProbe != nil {
+		t.Errorf("Expected no startup-probe config, got %v", merged.StartupProbe)
+	}
+
+	merged = MergeRulesConfig(&RulesConfig{StartupProbe: &StartupProbeConfig{InitialDelay: 30}}, &RulesConfig{StartupProbe: &StartupProbeConfig{InitialDelay: 90}})
+	if merged.StartupProbe.InitialDelay != 30 {
+		t.Errorf("Expected the lowest initial delay to win, got %d", merged.StartupProbe.InitialDelay)
+	}
+	merged = MergeRulesConfig(nil, &RulesConfig{StartupProbe: &StartupProbeConfig{InitialDelay: 30}})
+	if merged.StartupProbe.InitialDelay != 30 {
+		t.Errorf("Expected a local initial delay below the default to apply, got %d", merged.StartupProbe.InitialDelay)
+	}
+	merged = MergeRulesConfig(
+		&RulesConfig{CRFieldTypes: &CRFieldTypesConfig{Fields: []CRFieldType{{Kind: "Certificate", Path: "spec.duration", Type: "duration"}}}},
+		&RulesConfig{CRFieldTypes: &CRFieldTypesConfig{Fields: []CRFieldType{{Kind: "Cluster", Path: "spec.storage.size", Type: "quantity"}}}},
+	)
+	if len(merged.CRFieldTypes.Fields) != 2 {
+		t.Errorf("Expected the cr-field-types fields of both configs, got %v", merged.CRFieldTypes.Fields)
+	}
+	merged = MergeRulesConfig(
+		&RulesConfig{DocumentSeparators: &DocumentSeparatorsConfig{}},
+		&RulesConfig{DocumentSeparators: &DocumentSeparatorsConfig{Leading: "never"}},
+	)
+	if merged.DocumentSeparators.Leading != "never" {
+		t.Errorf("Expected the local leading separator setting without a global one, got %q", merged.DocumentSeparators.Leading)
+	}
+	merged = MergeRulesConfig(
+		&RulesConfig{DocumentSeparators: &DocumentSeparatorsConfig{Leading: "always"}},
+		&RulesConfig{DocumentSeparators: &DocumentSeparatorsConfig{Leading: "never"}},
+	)
+	if merged.DocumentSeparators.Leading != "always" {
+		t.Errorf("Expected the global leading separator setting to win, got %q", merged.DocumentSeparators.Leading)
+	}
+	merged = MergeRulesConfig(
+		&RulesConfig{ResourceKinds: &ResourceKindsConfig{Allowed: []string{"v1/*", "apps/v1/*"}, Denied: []string{"v1/Secret"}}},
+		&RulesConfig{ResourceKinds: &ResourceKindsConfig{Allowed: []string{"v1/ConfigMap", "batch/v1/Job"}, Denied: []string{"apps/v1/DaemonSet"}}},
+	)
+	if got := strings.Join(merged.ResourceKinds.Allowed, ","); got != "v1/ConfigMap" {
+		t.Errorf("Expected only the local kinds allowed globally, got %s", got)
+	}
+	if got := strings.Join(merged.ResourceKinds.Denied, ","); got != "v1/Secret,apps/v1/DaemonSet" {
+		t.Errorf("Expected the kinds denied by either config, got %s", got)
+	}
+	if MergeRulesConfig(global, nil) != global {
+		t.Error("Expected the global config without a local one")
+	}
+}
+
+func TestLoadLocalRulesConfig(t *testing.T) {
+	dir := t.TempDir()
+	config, err := LoadLocalRulesConfig(dir)
+	if err != nil || config != nil {
+		t.Fatalf("Expected no config for a chart without %s, got %v, %v", LocalRulesConfigFile, config, err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "ci"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LocalRulesConfigFile), []byte("style: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = LoadLocalRulesConfig(dir)
+	if err != nil || config == nil || config.Style == nil {
+		t.Errorf("Expected the style rule to be configured, got %v, %v", config, err)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// notesFile is the name of the template rendered as the notes of a release.
+const notesFile = "NOTES.txt"
+
+// defineName matches the names of the templates defined in a template file.
+var defineName = regexp.MustCompile(`{{-?\s*define\s+"([^"]+)"`)
+
+// validateHelperNames checks that the templates defined in the template file
+// of c are prefixed with the name of the chart, e.g. "mychart.fullname".
+// Defined templates are global to a chart and its subcharts, so a name like
+// "fullname" may be overridden by one defined in another of them. Only the
+// charts which share their templates are checked: library charts and the
+// charts with dependencies.
+func validateHelperNames(data []byte, c *chart.Chart) error {
+	if c.Name() == "" || (!strings.EqualFold(c.Metadata.Type, "library") && len(c.Metadata.Dependencies) == 0 && len(c.Dependencies()) == 0) {
+		return nil
+	}
+	prefix := c.Name() + "."
+	var unprefixed []string
+	for _, m := range defineName.FindAllSubmatch(data, -1) {
+		if name := string(m[1]); !strings.HasPrefix(name, prefix) {
+			unprefixed = append(unprefixed, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(unprefixed) == 0 {
+		return nil
+	}
+	return errors.Errorf("defined template(s) %s are not prefixed with %q; template names are shared with the subcharts and parents of the chart, so they may clash", strings.Join(unprefixed, ", "), prefix)
+}
+
+// validateNotesFile checks that the notes of a chart are in the file Helm
+// shows them from, templates/NOTES.txt. A NOTES.txt file outside of the
+// templates directory is not rendered, one in a subdirectory of it is not
+// shown, and a template with a differently cased name is rendered as a
+// manifest.
+func validateNotesFile(fileName string) error {
+	base := path.Base(fileName)
+	switch {
+	case fileName == path.Join("templates", notesFile):
+		return nil
+	case !strings.HasPrefix(fileName, "templates/") && base == notesFile:
+		return errors.Errorf("%s is outside of the templates directory, so it is not shown as the notes of the release; move it to templates/%s", fileName, notesFile)
+	case !strings.HasPrefix(fileName, "templates/"):
+		return nil
+	case base == notesFile:
+		return errors.Errorf("%s is not shown as the notes of the release, only templates/%s is", fileName, notesFile)
+	case strings.EqualFold(base, notesFile):
+		return errors.Errorf("%s is rendered as a manifest rather than shown as the notes of the release; name it %s", fileName, notesFile)
+	}
+	return nil
+}
+
+// chartFileNames returns the names of the template and other files of c.
+func chartFileNames(c *chart.Chart) []string {
+	names := make([]string, 0, len(c.Templates)+len(c.Files))
+	for _, f := range c.Templates {
+		names = append(names, f.Name)
+	}
+	for _, f := range c.Files {
+		names = append(names, f.Name)
+	}
+	return names
+}
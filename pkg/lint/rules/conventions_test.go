@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestValidateHelperNames(t *testing.T) {
+	helpers := []byte(`{{- define "app.name" -}}app{{- end }}
+{{ define "fullname" }}{{ include "app.name" . }}{{ end }}
+{{- define "labels" -}}{{- end }}
+`)
+	prefixed := []byte(`{{- define "app.name" -}}app{{- end }}
+{{- define "app.labels" -}}{{- end }}
+`)
+	library := &chart.Metadata{Name: "app", Type: "library"}
+	tests := []struct {
+		name     string
+		metadata *chart.Metadata
+		data     []byte
+		want     string
+	}{
+		{"chart with dependencies", &chart.Metadata{Name: "app", Dependencies: []*chart.Dependency{{Name: "redis"}}}, helpers, `"fullname", "labels" are not prefixed with "app."`},
+		{"library chart", library, helpers, `"fullname", "labels" are not prefixed with "app."`},
+		{"chart sharing no templates", &chart.Metadata{Name: "app"}, helpers, ""},
+		{"prefixed templates", library, prefixed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHelperNames(tt.data, &chart.Chart{Metadata: tt.metadata})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %s, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateNotesFile(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{"templates/NOTES.txt", ""},
+		{"templates/configmap.yaml", ""},
+		{"README.md", ""},
+		{"NOTES.txt", "is outside of the templates directory"},
+		{"files/NOTES.txt", "is outside of the templates directory"},
+		{"templates/extra/NOTES.txt", "only templates/NOTES.txt is"},
+		{"templates/notes.txt", "is rendered as a manifest"},
+	}
+	for _, tt := range tests {
+		err := validateNotesFile(tt.fileName)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", tt.fileName, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected an error for %s containing %s, got %v", tt.fileName, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// CRDSchemas holds the OpenAPI v3 schemas, as JSON, of the custom resources
+// served by a cluster, by the group, version and kind they validate.
+type CRDSchemas map[schema.GroupVersionKind][]byte
+
+// NewCRDSchemas collects the schemas of every served version of the given
+// CustomResourceDefinitions. Versions without a schema are recorded with an
+// empty one, so their resources are known to be installed.
+func NewCRDSchemas(crds []apiextensionsv1.CustomResourceDefinition) (CRDSchemas, error) {
+	schemas := CRDSchemas{}
+	for _, crd := range crds {
+		for _, version := range crd.Spec.Versions {
+			if !version.Served {
+				continue
+			}
+			gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}
+			data := []byte("{}")
+			if version.Schema != nil && version.Schema.OpenAPIV3Schema != nil {
+				var err error
+				if data, err = json.Marshal(version.Schema.OpenAPIV3Schema); err != nil {
+					return nil, errors.Wrapf(err, "unable to read the schema of %s", crd.Name)
+				}
+			}
+			schemas[gvk] = data
+		}
+	}
+	return schemas, nil
+}
+
+// isBuiltinKind reports whether the group, version and kind is served by
+// Kubernetes itself rather than by a CustomResourceDefinition.
+func isBuiltinKind(gvk schema.GroupVersionKind) bool {
+	return kscheme.Scheme.Recognizes(gvk) || gvk.Group == apiextensionsv1.GroupName
+}
+
+// validateClusterCRDSchema checks that a custom resource matches the schema
+// of its CustomResourceDefinition in the cluster. Resources whose definition
+// isn't installed are left to validateClusterCRDInstalled.
+func validateClusterCRDSchema(obj renderedObject, schemas CRDSchemas) error {
+	schemaJSON, ok := schemas[obj.GroupVersionKind()]
+	if !ok {
+		return nil
+	}
+	objJSON, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaJSON), gojsonschema.NewBytesLoader(objJSON))
+	if err != nil {
+		return errors.Wrapf(err, "unable to validate %s against the schema installed in the cluster", obj)
+	}
+	if result.Valid() {
+		return nil
+	}
+	var sb strings.Builder
+	for _, desc := range result.Errors() {
+		sb.WriteString("\n- " + desc.String())
+	}
+	return errors.Errorf("%s doesn't match the schema of %s installed in the cluster:%s", obj, obj.GroupVersionKind().GroupKind(), sb.String())
+}
+
+// validateClusterCRDInstalled notes the custom resources which aren't
+// validated against a schema, because the cluster doesn't serve their group,
+// version and kind.
+func validateClusterCRDInstalled(obj renderedObject, schemas CRDSchemas) error {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" || isBuiltinKind(gvk) {
+		return nil
+	}
+	if _, ok := schemas[gvk]; ok {
+		return nil
+	}
+	return errors.Errorf("%s was not validated: no CustomResourceDefinition installed in the cluster serves %s %s", obj, obj.GetAPIVersion(), gvk.Kind)
+}
+
+// validateCRFieldTypes checks that the fields of a custom resource listed in
+// the rules config hold values of the expected type, which catches mistakes
+// in resources whose CustomResourceDefinition isn't at hand. Fields which
+// aren't set are skipped.
+func validateCRFieldTypes(obj renderedObject, config *CRFieldTypesConfig) error {
+	if config == nil {
+		return nil
+	}
+	var invalid []string
+	for _, field := range config.Fields {
+		if obj.GetKind() != field.Kind || (field.APIVersion != "" && obj.GetAPIVersion() != field.APIVersion) {
+			continue
+		}
+		for _, v := range lookupFields(obj.Object, strings.Split(field.Path, "."), "") {
+			if !hasFieldType(v.value, field.Type) {
+				invalid = append(invalid, fmt.Sprintf("%s is %s, which is not %s", v.path, formatFieldValue(v.value), fieldTypeDescriptions[field.Type]))
+			}
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s sets fields to values of the wrong type: %s", obj, strings.Join(invalid, "; "))
+}
+
+var fieldTypeDescriptions = map[string]string{
+	"quantity": "a quantity such as 512Mi",
+	"duration": "a duration such as 1h30m",
+	"int":      "an integer",
+}
+
+type fieldValue struct {
+	path  string
+	value interface{}
+}
+
+// lookupFields returns the values at the path of a CRFieldType below
+// current, with the path of each, list indexes and map keys included.
+func lookupFields(current interface{}, segments []string, prefix string) []fieldValue {
+	if len(segments) == 0 {
+		return []fieldValue{{path: prefix, value: current}}
+	}
+	table, ok := current.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if prefix != "" {
+		prefix += "."
+	}
+	name, isList := strings.CutSuffix(segments[0], "[*]")
+	var keys []string
+	if name == "*" {
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	} else if _, ok := table[name]; ok {
+		keys = []string{name}
+	}
+	var values []fieldValue
+	for _, key := range keys {
+		if !isList {
+			values = append(values, lookupFields(table[key], segments[1:], prefix+key)...)
+			continue
+		}
+		items, _ := table[key].([]interface{})
+		for i, item := range items {
+			values = append(values, lookupFields(item, segments[1:], fmt.Sprintf("%s%s[%d]", prefix, key, i))...)
+		}
+	}
+	return values
+}
+
+// hasFieldType reports whether a value decoded by decodeObjects is of a
+// CRFieldType type.
+func hasFieldType(value interface{}, fieldType string) bool {
+	switch fieldType {
+	case "quantity":
+		switch v := value.(type) {
+		case int64, float64:
+			return true
+		case string:
+			_, err := resource.ParseQuantity(v)
+			return err == nil
+		}
+	case "duration":
+		if v, ok := value.(string); ok {
+			_, err := time.ParseDuration(v)
+			return err == nil
+		}
+	case "int":
+		_, ok := value.(int64)
+		return ok
+	}
+	return false
+}
+
+func formatFieldValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestValidateClusterCRDs(t *testing.T) {
+	crd := apiextensionsv1.CustomResourceDefinition{}
+	crd.Name = "widgets.example.com"
+	crd.Spec.Group = "example.com"
+	crd.Spec.Names.Kind = "Widget"
+	crd.Spec.Versions = []apiextensionsv1.CustomResourceDefinitionVersion{
+		{
+			Name:   "v1",
+			Served: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"spec": {
+							Type:     "object",
+							Required: []string{"size"},
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"size": {Type: "integer"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{Name: "v1alpha1", Served: false},
+	}
+	schemas, err := NewCRDSchemas([]apiextensionsv1.CustomResourceDefinition{crd})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objs := decodeObjects("templates/test.yaml", `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: valid
+spec:
+  size: 3
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: invalid
+spec:
+  size: large
+---
+apiVersion: example.com/v1alpha1
+kind: Widget
+metadata:
+  name: unserved
+---
+apiVersion: other.example.com/v1
+kind: Gadget
+metadata:
+  name: missing
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: builtin
+`)
+	expected := map[string]string{
+		"invalid":  `Widget "invalid" doesn't match the schema of Widget.example.com installed in the cluster:`,
+		"unserved": `serves example.com/v1alpha1 Widget`,
+		"missing":  `serves other.example.com/v1 Gadget`,
+	}
+	for _, obj := range objs {
+		err := validateClusterCRDSchema(obj, schemas)
+		if err == nil {
+			err = validateClusterCRDInstalled(obj, schemas)
+		}
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %s, got %v", want, obj, err)
+		}
+	}
+}
+
+func TestValidateCRFieldTypes(t *testing.T) {
+	config := &CRFieldTypesConfig{Fields: []CRFieldType{
+		{APIVersion: "cert-manager.io/v1", Kind: "Certificate", Path: "spec.duration", Type: "duration"},
+		{Kind: "Cluster", Path: "spec.storage.size", Type: "quantity"},
+		{Kind: "Cluster", Path: "spec.instances", Type: "int"},
+		{Kind: "Cluster", Path: "spec.pools[*].resources.*", Type: "quantity"},
+	}}
+	objs := decodeObjects("templates/test.yaml", `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: valid
+spec:
+  duration: 2160h
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: invalid-duration
+spec:
+  duration: 90 days
+---
+apiVersion: cert-manager.io/v1alpha2
+kind: Certificate
+metadata:
+  name: other-version
+spec:
+  duration: 90 days
+---
+apiVersion: postgresql.example.com/v1
+kind: Cluster
+metadata:
+  name: unset
+spec: {}
+---
+apiVersion: postgresql.example.com/v1
+kind: Cluster
+metadata:
+  name: invalid
+spec:
+  instances: "3"
+  storage:
+    size: 10 Gi
+  pools:
+  - resources:
+      cpu: 500m
+      memory: 1Gb
+  - resources:
+      cpu: 2
+`)
+	expected := map[string]string{
+		"invalid-duration": `Certificate "invalid-duration" sets fields to values of the wrong type: spec.duration is "90 days", which is not a duration such as 1h30m`,
+		"invalid": `Cluster "invalid" sets fields to values of the wrong type: spec.storage.size is "10 Gi", which is not a quantity such as 512Mi; ` +
+			`spec.instances is "3", which is not an integer; spec.pools[0].resources.memory is "1Gb", which is not a quantity such as 512Mi`,
+	}
+	for _, obj := range objs {
+		err := validateCRFieldTypes(obj, config)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != want {
+			t.Errorf("Expected %q for %s, got %v", want, obj, err)
+		}
+	}
+	if err := validateCRFieldTypes(objs[1], nil); err != nil {
+		t.Errorf("Expected the rule to be off without a config, got %v", err)
+	}
+}
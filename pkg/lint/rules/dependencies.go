@@ -31,14 +31,31 @@ import (
 //
 // See https://github.com/helm/helm/issues/7910
 func Dependencies(linter *support.Linter) {
-	c, err := loader.LoadDir(linter.ChartDir)
+	DependenciesWithOptions(linter, DependencyOptions{})
+}
+
+// DependencyOptions holds the optional settings used when linting the
+// dependencies of a chart.
+type DependencyOptions struct {
+	// ChartCache loads the chart, to reuse it across lint runs.
+	ChartCache *loader.Cache
+}
+
+// DependenciesWithOptions lints the dependencies of the chart in the Linter
+// using the given options.
+func DependenciesWithOptions(linter *support.Linter, opts DependencyOptions) {
+	load := loader.LoadDir
+	if opts.ChartCache != nil {
+		load = opts.ChartCache.Load
+	}
+	c, err := load(linter.ChartDir)
 	if !linter.RunLinterRule(support.ErrorSev, "", validateChartFormat(err)) {
 		return
 	}
 
-	linter.RunLinterRule(support.ErrorSev, linter.ChartDir, validateDependencyInMetadata(c))
-	linter.RunLinterRule(support.ErrorSev, linter.ChartDir, validateDependenciesUnique(c))
-	linter.RunLinterRule(support.WarningSev, linter.ChartDir, validateDependencyInChartsDir(c))
+	linter.RunLinterRuleWithID(RuleChartDependencies, support.ErrorSev, linter.ChartDir, validateDependencyInMetadata(c))
+	linter.RunLinterRuleWithID(RuleDependencyAlias, support.ErrorSev, linter.ChartDir, validateDependenciesUnique(c))
+	linter.RunLinterRuleWithID(RuleChartDependencies, support.WarningSev, linter.ChartDir, validateDependencyInChartsDir(c))
 }
 
 func validateChartFormat(chartError error) error {
@@ -82,17 +99,34 @@ func validateDependencyInMetadata(c *chart.Chart) (err error) {
 	return err
 }
 
+// validateDependenciesUnique checks that every dependency is scoped under its
+// own key in the values, and that no alias is the name of another
+// dependency, which makes it ambiguous which chart the key refers to.
 func validateDependenciesUnique(c *chart.Chart) (err error) {
 	dependencies := map[string]*chart.Dependency{}
+	names := map[string]bool{}
 	shadowing := []string{}
+	seen := map[string]bool{}
+	shadow := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			shadowing = append(shadowing, key)
+		}
+	}
 
+	for _, dep := range c.Metadata.Dependencies {
+		names[dep.Name] = true
+	}
 	for _, dep := range c.Metadata.Dependencies {
 		key := dep.Name
 		if dep.Alias != "" {
 			key = dep.Alias
+			if dep.Alias != dep.Name && names[dep.Alias] {
+				shadow(key)
+			}
 		}
 		if dependencies[key] != nil {
-			shadowing = append(shadowing, key)
+			shadow(key)
 		}
 		dependencies[key] = dep
 	}
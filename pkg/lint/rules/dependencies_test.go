@@ -128,6 +128,23 @@ func TestValidateDependenciesUnique(t *testing.T) {
 				},
 			},
 		}},
+		{chart.Chart{
+			Metadata: &chart.Metadata{
+				Name:       "badchart",
+				Version:    "0.1.0",
+				APIVersion: "v2",
+				Dependencies: []*chart.Dependency{
+					{
+						Name:  "foo",
+						Alias: "bar",
+					},
+					{
+						Name:  "bar",
+						Alias: "baz",
+					},
+				},
+			},
+		}},
 	}
 
 	for _, tt := range tests {
@@ -135,6 +152,22 @@ func TestValidateDependenciesUnique(t *testing.T) {
 			t.Errorf("chart should have been flagged for dependency shadowing")
 		}
 	}
+
+	unique := chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "goodchart",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{
+				{Name: "foo", Alias: "foo"},
+				{Name: "foo", Alias: "bar"},
+				{Name: "baz"},
+			},
+		},
+	}
+	if err := validateDependenciesUnique(&unique); err != nil {
+		t.Errorf("Unexpected error for unique dependencies: %s", err)
+	}
 }
 
 func TestDependencies(t *testing.T) {
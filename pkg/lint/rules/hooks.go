@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// longLivedKinds are the kinds of objects which are usually meant to keep
+// running for the lifetime of a release.
+var longLivedKinds = map[string]bool{
+	"Deployment":              true,
+	"StatefulSet":             true,
+	"DaemonSet":               true,
+	"ReplicaSet":              true,
+	"ReplicationController":   true,
+	"Service":                 true,
+	"Ingress":                 true,
+	"HorizontalPodAutoscaler": true,
+	"PodDisruptionBudget":     true,
+}
+
+// validateHookIntent checks for long-lived objects annotated as hooks.
+//
+// Hooks are not part of the release manifest, so they are neither upgraded
+// nor deleted with the release, which is rarely what is intended for such
+// objects.
+func validateHookIntent(obj renderedObject) error {
+	events := hookEvents(obj)
+	if len(events) == 0 || !longLivedKinds[obj.GetKind()] {
+		return nil
+	}
+	return errors.Errorf("%s is a %s hook. Hooks are not managed as part of the release, so it won't be upgraded or deleted with it. Make sure this is intended", obj, strings.Join(events, ","))
+}
+
+// validateResourcePolicy notes the objects Helm keeps when the release is
+// uninstalled or they are removed from the chart, and reports resource
+// policies which Helm ignores: values other than keep, and keep on hooks,
+// which aren't deleted with the release anyway and are still deleted by
+// their hook-delete-policy.
+func validateResourcePolicy(obj renderedObject) error {
+	policy, ok := obj.GetAnnotations()[kube.ResourcePolicyAnno]
+	if !ok {
+		return nil
+	}
+	if policy != kube.KeepPolicy {
+		return errors.Errorf("%s sets %s to %q, which Helm ignores. The only resource policy is %s", obj, kube.ResourcePolicyAnno, policy, kube.KeepPolicy)
+	}
+	if events := hookEvents(obj); len(events) > 0 {
+		if deletePolicy := obj.GetAnnotations()[release.HookDeleteAnnotation]; deletePolicy != "" {
+			return errors.Errorf("%s is a %s hook with the resource policy %s, but the hook delete policy %s still deletes it. Hooks ignore the resource policy", obj, strings.Join(events, ","), kube.KeepPolicy, deletePolicy)
+		}
+		return errors.Errorf("%s is a %s hook with the resource policy %s, which has no effect: hooks are not deleted with the release anyway", obj, strings.Join(events, ","), kube.KeepPolicy)
+	}
+	return errors.Errorf("%s has the resource policy %s, so it won't be deleted when the release is uninstalled or it is removed from the chart. Make sure this is intended", obj, kube.KeepPolicy)
+}
+
+// hookEvents returns the valid hook events an object is annotated with.
+// Events unknown to Helm 3, like crd-install, are left out.
+func hookEvents(obj renderedObject) []string {
+	var events []string
+	for _, e := range strings.Split(obj.GetAnnotations()[release.HookAnnotation], ",") {
+		switch e = strings.TrimSpace(e); release.HookEvent(e) {
+		case release.HookPreInstall, release.HookPostInstall, release.HookPreDelete, release.HookPostDelete,
+			release.HookPreUpgrade, release.HookPostUpgrade, release.HookPreRollback, release.HookPostRollback,
+			release.HookTest, "test-success":
+			events = append(events, e)
+		}
+	}
+	return events
+}
@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"testing"
+)
+
+func TestValidateHookIntent(t *testing.T) {
+	tests := []struct {
+		manifest string
+		wantErr  string
+	}{
+		{
+			manifest: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  annotations:\n    helm.sh/hook: pre-install\n",
+			wantErr:  `Deployment "web" is a pre-install hook. Hooks are not managed as part of the release, so it won't be upgraded or deleted with it. Make sure this is intended`,
+		},
+		{
+			manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  annotations:\n    helm.sh/hook: pre-upgrade\n",
+		},
+		{
+			manifest: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		},
+		{
+			manifest: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  annotations:\n    helm.sh/hook: crd-install\n",
+		},
+	}
+	for _, tt := range tests {
+		err := validateHookIntent(mustDecodeObject(t, tt.manifest))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateResourcePolicy(t *testing.T) {
+	tests := []struct {
+		manifest string
+		wantErr  string
+	}{
+		{
+			manifest: "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: data\n  annotations:\n    helm.sh/resource-policy: keep\n",
+			wantErr:  `PersistentVolumeClaim "data" has the resource policy keep, so it won't be deleted when the release is uninstalled or it is removed from the chart. Make sure this is intended`,
+		},
+		{
+			manifest: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: token\n  annotations:\n    helm.sh/resource-policy: retain\n",
+			wantErr:  `Secret "token" sets helm.sh/resource-policy to "retain", which Helm ignores. The only resource policy is keep`,
+		},
+		{
+			manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  annotations:\n    helm.sh/hook: pre-upgrade\n    helm.sh/hook-delete-policy: hook-succeeded\n    helm.sh/resource-policy: keep\n",
+			wantErr:  `Job "migrate" is a pre-upgrade hook with the resource policy keep, but the hook delete policy hook-succeeded still deletes it. Hooks ignore the resource policy`,
+		},
+		{
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: seed\n  annotations:\n    helm.sh/hook: post-install\n    helm.sh/resource-policy: keep\n",
+			wantErr:  `ConfigMap "seed" is a post-install hook with the resource policy keep, which has no effect: hooks are not deleted with the release anyway`,
+		},
+		{
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		},
+	}
+	for _, tt := range tests {
+		err := validateResourcePolicy(mustDecodeObject(t, tt.manifest))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+		}
+	}
+}
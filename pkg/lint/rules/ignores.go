@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ignoreCommentSearch matches comments like `# helm-lint:ignore rule-a, rule-b`.
+var ignoreCommentSearch = regexp.MustCompile(`#\s*helm-lint:ignore\s+([^\r\n]+)`)
+
+// templateIgnores holds the rules suppressed by ignore comments in the
+// chart's template sources.
+//
+// An ignore comment suppresses the findings of the listed rules for the
+// template it is written in, including the findings about the objects that
+// template renders.
+type templateIgnores struct {
+	// rules maps a template path to the suppressed rule IDs and whether
+	// they have suppressed any finding yet.
+	rules map[string]map[string]bool
+}
+
+func parseTemplateIgnores(templates []*chart.File) templateIgnores {
+	ignores := templateIgnores{rules: map[string]map[string]bool{}}
+	for _, t := range templates {
+		for _, match := range ignoreCommentSearch.FindAllSubmatch(t.Data, -1) {
+			for _, id := range strings.FieldsFunc(string(match[1]), func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+				if ignores.rules[t.Name] == nil {
+					ignores.rules[t.Name] = map[string]bool{}
+				}
+				ignores.rules[t.Name][id] = false
+			}
+		}
+	}
+	return ignores
+}
+
+// suppress reports whether ruleID is suppressed for the template at path,
+// marking the ignore comment as used.
+func (i templateIgnores) suppress(path, ruleID string) bool {
+	if _, ok := i.rules[path][ruleID]; ok {
+		i.rules[path][ruleID] = true
+		return true
+	}
+	return false
+}
+
+// reportUnused adds a warning for every ignore comment that suppressed nothing.
+func (i templateIgnores) reportUnused(linter *support.Linter) {
+	paths := make([]string, 0, len(i.rules))
+	for path := range i.rules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		ids := make([]string, 0, len(i.rules[path]))
+		for id, used := range i.rules[path] {
+			if !used {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			linter.RunLinterRuleWithID(RuleUnusedIgnore, support.WarningSev, path, fmt.Errorf("ignore comment for rule %q does not suppress any finding", id))
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestParseTemplateIgnores(t *testing.T) {
+	ignores := parseTemplateIgnores([]*chart.File{
+		{Name: "templates/a.yaml", Data: []byte("# helm-lint:ignore deprecated-api, metadata-name\nkind: Pod\n")},
+		{Name: "templates/b.yaml", Data: []byte("kind: Pod\nmetadata:\n  #helm-lint:ignore top-indent\n")},
+		{Name: "templates/c.yaml", Data: []byte("# helm-lint: nothing to see here\n")},
+	})
+
+	for _, tt := range []struct {
+		path, rule string
+		want       bool
+	}{
+		{"templates/a.yaml", RuleDeprecatedAPI, true},
+		{"templates/a.yaml", RuleMetadataName, true},
+		{"templates/a.yaml", RuleTopIndent, false},
+		{"templates/b.yaml", RuleTopIndent, true},
+		{"templates/c.yaml", RuleTopIndent, false},
+	} {
+		if got := ignores.suppress(tt.path, tt.rule); got != tt.want {
+			t.Errorf("suppress(%q, %q) = %t, want %t", tt.path, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestTemplatesWithIgnoreComments(t *testing.T) {
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "ignores",
+			Version:    "0.1.0",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/ignored.yaml",
+				Data: []byte("# helm-lint:ignore deprecated-api, match-selector\napiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: ignored\nspec: {revisionHistoryLimit: 1}\n"),
+			},
+			{
+				Name: "templates/reported.yaml",
+				Data: []byte("apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: reported\nspec: {revisionHistoryLimit: 1, selector: {matchLabels: {foo: bar}}}\n"),
+			},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	chartDir := filepath.Join(tmpdir, mychart.Name())
+
+	linter := support.Linter{ChartDir: chartDir}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{})
+	if len(linter.Messages) != 1 {
+		t.Fatalf("Expected 1 lint message, got %d: %v", len(linter.Messages), linter.Messages)
+	}
+	if msg := linter.Messages[0]; msg.Path != "templates/reported.yaml" || msg.RuleID != RuleDeprecatedAPI {
+		t.Errorf("Expected a deprecated-api finding for templates/reported.yaml, got %s (%s)", msg, msg.RuleID)
+	}
+
+	mychart.Templates[1].Data = append([]byte("# helm-lint:ignore deprecated-api, metadata-name\n"), mychart.Templates[1].Data...)
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	linter = support.Linter{ChartDir: chartDir}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{ReportUnusedIgnores: true})
+	if len(linter.Messages) != 1 {
+		t.Fatalf("Expected 1 lint message, got %d: %v", len(linter.Messages), linter.Messages)
+	}
+	msg := linter.Messages[0]
+	if msg.RuleID != RuleUnusedIgnore || msg.Path != "templates/reported.yaml" || !strings.Contains(msg.Err.Error(), `"metadata-name"`) {
+		t.Errorf("Expected the unused metadata-name ignore to be reported, got %s", msg)
+	}
+}
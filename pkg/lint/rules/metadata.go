@@ -0,0 +1,76 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// validateNoOwnerReferences checks that an object doesn't set
+// metadata.ownerReferences. The owners are created by other releases or by
+// controllers, and their UIDs differ between clusters and installs, so
+// hardcoded references either block the object or get it garbage collected.
+// Objects allowed by the config are not reported.
+func validateNoOwnerReferences(obj renderedObject, config *OwnerReferencesConfig) error {
+	refs := obj.GetOwnerReferences()
+	if len(refs) == 0 {
+		return nil
+	}
+	if config != nil {
+		ref := obj.GetKind() + "/" + obj.GetName()
+		for _, allowed := range config.Allowed {
+			if allowed == ref {
+				return nil
+			}
+		}
+	}
+	owners := make([]string, 0, len(refs))
+	for _, r := range refs {
+		owners = append(owners, r.Kind+"/"+r.Name)
+	}
+	return errors.Errorf("%s sets metadata.ownerReferences (%s). Owner references are generally managed by controllers and should be omitted from templates", obj, strings.Join(owners, ", "))
+}
+
+// validateResourceKinds checks that the kind of an object, as apiVersion/kind,
+// is not denied by the config and, if the config has an allowlist, that the
+// allowlist matches it.
+func validateResourceKinds(obj renderedObject, config *ResourceKindsConfig) error {
+	if config == nil || obj.GetKind() == "" {
+		return nil
+	}
+	kind := obj.GetAPIVersion() + "/" + obj.GetKind()
+	if kindMatches(kind, config.Denied) {
+		return errors.Errorf("%s has the kind %s, which is denied", obj, kind)
+	}
+	if config.Allowed != nil && !kindMatches(kind, config.Allowed) {
+		return errors.Errorf("%s has the kind %s, which is not allowed", obj, kind)
+	}
+	return nil
+}
+
+// kindMatches reports whether one of patterns matches kind.
+func kindMatches(kind string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, kind); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNoOwnerReferences(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: owned
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: operator
+    uid: 0d1b9b1e-8a7e-4f4e-9a53-2b1f0e6b5d11
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: operator-state
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: operator
+    uid: 0d1b9b1e-8a7e-4f4e-9a53-2b1f0e6b5d11
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: plain
+`)
+	config := &OwnerReferencesConfig{Allowed: []string{"ConfigMap/operator-state"}}
+
+	err := validateNoOwnerReferences(objs[0], config)
+	if err == nil || !strings.Contains(err.Error(), `ConfigMap "owned" sets metadata.ownerReferences (Deployment/operator)`) {
+		t.Errorf("Expected the owner reference to be reported, got %v", err)
+	}
+	if err := validateNoOwnerReferences(objs[1], config); err != nil {
+		t.Errorf("Unexpected error for an allowed object: %s", err)
+	}
+	if err := validateNoOwnerReferences(objs[1], nil); err == nil {
+		t.Error("Expected the owner reference to be reported without a config")
+	}
+	if err := validateNoOwnerReferences(objs[2], nil); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestValidateResourceKinds(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: admin
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: restricted
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+`)
+	config := &ResourceKindsConfig{
+		Allowed: []string{"v1/*", "apps/v1/Deployment", "rbac.authorization.k8s.io/v1/*"},
+		Denied:  []string{"rbac.authorization.k8s.io/v1/ClusterRoleBinding", "policy/*/PodSecurityPolicy"},
+	}
+
+	tests := []struct {
+		obj    int
+		config *ResourceKindsConfig
+		err    string
+	}{
+		{0, config, `ClusterRoleBinding "admin" has the kind rbac.authorization.k8s.io/v1/ClusterRoleBinding, which is denied`},
+		{1, config, `PodSecurityPolicy "restricted" has the kind policy/v1beta1/PodSecurityPolicy, which is denied`},
+		{2, config, ""},
+		{3, config, ""},
+		{3, &ResourceKindsConfig{Allowed: []string{"v1/*"}}, `Deployment "app" has the kind apps/v1/Deployment, which is not allowed`},
+		{3, &ResourceKindsConfig{Denied: []string{"v1/*"}}, ""},
+		{0, nil, ""},
+	}
+	for _, tt := range tests {
+		err := validateResourceKinds(objs[tt.obj], tt.config)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", objs[tt.obj], err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Expected %q, got %v", tt.err, err)
+		}
+	}
+}
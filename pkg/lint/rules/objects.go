@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// externalAnnotation lists objects which a rendered object references, but
+// which are managed outside of the chart, as comma-separated `Kind/name`
+// entries, e.g. `ServiceAccount/builder, Secret/registry-credentials`.
+const externalAnnotation = "helm.sh/lint-external"
+
+// renderedObject is a Kubernetes object rendered from one of the chart's templates.
+type renderedObject struct {
+	unstructured.Unstructured
+	// path is the template the object was rendered from.
+	path string
+}
+
+func (o renderedObject) String() string {
+	return fmt.Sprintf("%s %q", o.GetKind(), o.GetName())
+}
+
+// decodeObjects decodes every non-empty document of a rendered template.
+//
+// Documents that can't be decoded are skipped, they are already reported by
+// validateYamlContent.
+func decodeObjects(path, content string) []renderedObject {
+	var objs []renderedObject
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			break
+		}
+		// utiljson keeps integers as int64 rather than float64, like the
+		// Kubernetes API machinery does.
+		var obj map[string]interface{}
+		if err := utiljson.Unmarshal(raw, &obj); err != nil || len(obj) == 0 {
+			continue
+		}
+		objs = append(objs, renderedObject{Unstructured: unstructured.Unstructured{Object: obj}, path: path})
+	}
+	return objs
+}
+
+// renderedCount summarizes the objects rendered by the templates of a chart:
+// their number and how many there are of each kind, such as
+// "renders 3 Kubernetes objects: Deployment x2, Service x1". Only the
+// documents with an apiVersion and a kind are counted as objects.
+func renderedCount(objects []renderedObject) error {
+	counts := map[string]int{}
+	var kinds []string
+	total := 0
+	for _, obj := range objects {
+		kind := obj.GetKind()
+		if kind == "" || obj.GetAPIVersion() == "" {
+			continue
+		}
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+		total++
+	}
+	if total == 0 {
+		return errors.New("renders no Kubernetes objects")
+	}
+	sort.Strings(kinds)
+	summary := make([]string, len(kinds))
+	for i, kind := range kinds {
+		summary[i] = fmt.Sprintf("%s x%d", kind, counts[kind])
+	}
+	return errors.Errorf("renders %d Kubernetes object(s): %s", total, strings.Join(summary, ", "))
+}
+
+// podTemplate returns the pod template of a workload object, or the Pod
+// itself. It returns false for objects that don't define pods, or whose pod
+// definition can't be decoded.
+func (o renderedObject) podTemplate() (*corev1.PodTemplateSpec, bool) {
+	var fields []string
+	switch o.GetKind() {
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(o.Object, pod); err != nil {
+			return nil, false
+		}
+		return &corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}, true
+	case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "ReplicationController", "PodTemplate":
+		fields = []string{"spec", "template"}
+		if o.GetKind() == "PodTemplate" {
+			fields = []string{"template"}
+		}
+	case "CronJob":
+		fields = []string{"spec", "jobTemplate", "spec", "template"}
+	default:
+		return nil, false
+	}
+
+	tmpl, found, err := unstructured.NestedMap(o.Object, fields...)
+	if !found || err != nil {
+		return nil, false
+	}
+	spec := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tmpl, spec); err != nil {
+		return nil, false
+	}
+	return spec, true
+}
+
+// isExternal reports whether the object declares that the referenced object
+// of the given kind and name is managed outside of the chart. The annotation
+// is read from the object and from its pod template.
+func (o renderedObject) isExternal(kind, name string) bool {
+	annotations := []string{o.GetAnnotations()[externalAnnotation]}
+	if tmpl, ok := o.podTemplate(); ok {
+		annotations = append(annotations, tmpl.Annotations[externalAnnotation])
+	}
+	ref := kind + "/" + name
+	for _, a := range annotations {
+		for _, entry := range strings.Split(a, ",") {
+			if strings.TrimSpace(entry) == ref {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// objectIndex records the objects rendered by a chart by kind and name.
+type objectIndex map[string]map[string]renderedObject
+
+func indexObjects(objs []renderedObject) objectIndex {
+	index := objectIndex{}
+	for _, o := range objs {
+		if index[o.GetKind()] == nil {
+			index[o.GetKind()] = map[string]renderedObject{}
+		}
+		index[o.GetKind()][o.GetName()] = o
+	}
+	return index
+}
+
+// has reports whether an object of the given kind and name is rendered.
+func (i objectIndex) has(kind, name string) bool {
+	_, ok := i[kind][name]
+	return ok
+}
+
+// get returns the rendered object of the given kind and name.
+func (i objectIndex) get(kind, name string) (renderedObject, bool) {
+	o, ok := i[kind][name]
+	return o, ok
+}
@@ -0,0 +1,925 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// allContainers returns the init, regular and ephemeral containers of a pod.
+func allContainers(spec *corev1.PodSpec) []corev1.Container {
+	containers := append([]corev1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, c := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(c.EphemeralContainerCommon))
+	}
+	return containers
+}
+
+// validateNoSharedMountPaths checks that containers of a pod don't mount the
+// same volume at overlapping paths without a subPath, in which case they read
+// and write the same files, which is rarely intended.
+func validateNoSharedMountPaths(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+
+	type mount struct {
+		container string
+		path      string
+	}
+	mounts := map[string][]mount{}
+	var conflicts []string
+	for _, c := range allContainers(&tmpl.Spec) {
+		for _, vm := range c.VolumeMounts {
+			if vm.SubPath != "" || vm.SubPathExpr != "" {
+				continue
+			}
+			p := path.Clean(vm.MountPath)
+			for _, m := range mounts[vm.Name] {
+				if pathsOverlap(m.path, p) {
+					conflicts = append(conflicts, fmt.Sprintf("volume %q is mounted by container %q at %q and by container %q at %q", vm.Name, m.container, m.path, c.Name, p))
+				}
+			}
+			mounts[vm.Name] = append(mounts[vm.Name], mount{container: c.Name, path: p})
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.Errorf("%s has overlapping volume mounts without subPath: %s", obj, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// pathsOverlap reports whether one of the given clean paths contains the other.
+func pathsOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return strings.HasPrefix(b, strings.TrimSuffix(a, "/")+"/")
+}
+
+// wellKnownNodeLabels are the node labels set by Kubernetes itself.
+//
+// See https://kubernetes.io/docs/reference/labels-annotations-taints/
+var wellKnownNodeLabels = []string{
+	"kubernetes.io/arch",
+	"kubernetes.io/hostname",
+	"kubernetes.io/os",
+	"node.kubernetes.io/instance-type",
+	"node.kubernetes.io/windows-build",
+	"topology.kubernetes.io/region",
+	"topology.kubernetes.io/zone",
+	"beta.kubernetes.io/arch",
+	"beta.kubernetes.io/instance-type",
+	"beta.kubernetes.io/os",
+	"failure-domain.beta.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/zone",
+}
+
+// validateNodeLabelKeys checks the node label keys used by the node selector
+// and node affinity of a pod for likely misspellings of well-known labels.
+//
+// Only keys very close to a well-known label are reported, as any other key
+// may be a custom label of the cluster.
+func validateNodeLabelKeys(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+
+	keys := map[string]bool{}
+	for k := range tmpl.Spec.NodeSelector {
+		keys[k] = true
+	}
+	if a := tmpl.Spec.Affinity; a != nil && a.NodeAffinity != nil {
+		var terms []corev1.NodeSelectorTerm
+		if r := a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; r != nil {
+			terms = append(terms, r.NodeSelectorTerms...)
+		}
+		for _, p := range a.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, p.Preference)
+		}
+		for _, term := range terms {
+			for _, expr := range term.MatchExpressions {
+				keys[expr.Key] = true
+			}
+		}
+	}
+
+	var typos []string
+	for key := range keys {
+		if suggestion := nodeLabelSuggestion(key); suggestion != "" {
+			typos = append(typos, fmt.Sprintf("%q (did you mean %q?)", key, suggestion))
+		}
+	}
+	if len(typos) > 0 {
+		sort.Strings(typos)
+		return errors.Errorf("%s selects nodes by unknown label keys: %s", obj, strings.Join(typos, ", "))
+	}
+	return nil
+}
+
+// nodeLabelSuggestion returns the well-known node label which key is likely a
+// misspelling of, or an empty string.
+func nodeLabelSuggestion(key string) string {
+	for _, known := range wellKnownNodeLabels {
+		if key == known {
+			return ""
+		}
+	}
+	for _, known := range wellKnownNodeLabels {
+		if d := editDistance(key, known); d > 0 && d <= 2 {
+			return known
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// validateServiceAccountRef checks that the ServiceAccount used by a pod is
+// rendered by the chart, or declared as external.
+func validateServiceAccountRef(obj renderedObject, index objectIndex) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	name := tmpl.Spec.ServiceAccountName
+	if name == "" || name == "default" || index.has("ServiceAccount", name) || obj.isExternal("ServiceAccount", name) {
+		return nil
+	}
+	return errors.Errorf("%s uses ServiceAccount %q, which is not rendered by the chart. If it is managed outside of the chart, add the annotation %s: ServiceAccount/%s", obj, name, externalAnnotation, name)
+}
+
+// validateImagePullSecrets checks that the imagePullSecrets of a pod are
+// rendered by the chart, or declared as external.
+func validateImagePullSecrets(obj renderedObject, index objectIndex) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, ref := range tmpl.Spec.ImagePullSecrets {
+		if ref.Name == "" || index.has("Secret", ref.Name) || obj.isExternal("Secret", ref.Name) {
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%q", ref.Name))
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s pulls images with the Secret(s) %s, which are not rendered by the chart. If they are managed outside of the chart, add the annotation %s: Secret/name", obj, strings.Join(missing, ", "), externalAnnotation)
+}
+
+// validateClaimAccessModes checks that a Deployment or ReplicaSet running more
+// than one replica doesn't mount a rendered PersistentVolumeClaim which can
+// only be attached to a single node. Replicas scheduled on other nodes would
+// stay pending.
+func validateClaimAccessModes(obj renderedObject, index objectIndex) error {
+	if obj.GetKind() != "Deployment" && obj.GetKind() != "ReplicaSet" {
+		return nil
+	}
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil || !found || replicas < 2 {
+		return nil
+	}
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	for _, v := range tmpl.Spec.Volumes {
+		if v.PersistentVolumeClaim == nil {
+			continue
+		}
+		claim, ok := index.get("PersistentVolumeClaim", v.PersistentVolumeClaim.ClaimName)
+		if !ok {
+			continue
+		}
+		modes, _, _ := unstructured.NestedStringSlice(claim.Object, "spec", "accessModes")
+		if singleNodeAccess(modes) {
+			return errors.Errorf("%s runs %d replicas, but mounts PersistentVolumeClaim %q with access modes %s. Replicas scheduled on different nodes can't attach the volume; use ReadWriteMany or ReadOnlyMany, or a StatefulSet with volumeClaimTemplates", obj, replicas, claim.GetName(), strings.Join(modes, ", "))
+		}
+	}
+	return nil
+}
+
+// validateClaimTemplateNames checks that no PersistentVolumeClaim rendered by
+// the chart has the name of a claim a StatefulSet creates from its
+// volumeClaimTemplates, "<template>-<statefulset>-<ordinal>". The StatefulSet
+// adopts such a claim, so it is unclear which definition applies.
+func validateClaimTemplateNames(obj renderedObject, index objectIndex) error {
+	if obj.GetKind() != "StatefulSet" {
+		return nil
+	}
+	templates, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+	claims := make([]string, 0, len(index["PersistentVolumeClaim"]))
+	for name := range index["PersistentVolumeClaim"] {
+		claims = append(claims, name)
+	}
+	sort.Strings(claims)
+	var overlaps []string
+	for _, t := range templates {
+		tmpl, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(tmpl, "metadata", "name")
+		if name == "" {
+			continue
+		}
+		prefix := name + "-" + obj.GetName() + "-"
+		for _, claim := range claims {
+			ordinal := strings.TrimPrefix(claim, prefix)
+			if ordinal == claim || ordinal == "" || strings.Trim(ordinal, "0123456789") != "" {
+				continue
+			}
+			overlaps = append(overlaps, fmt.Sprintf("%q (from template %q)", claim, name))
+		}
+	}
+	if len(overlaps) > 0 {
+		return errors.Errorf("%s creates PersistentVolumeClaims from volumeClaimTemplates with the same names as the rendered PersistentVolumeClaim(s) %s; the StatefulSet adopts these claims, rename one of them", obj, strings.Join(overlaps, ", "))
+	}
+	return nil
+}
+
+// singleNodeAccess reports whether the access modes only allow mounting the
+// volume on a single node.
+func singleNodeAccess(modes []string) bool {
+	if len(modes) == 0 {
+		return false
+	}
+	for _, m := range modes {
+		switch corev1.PersistentVolumeAccessMode(m) {
+		case corev1.ReadWriteOnce, corev1.ReadWriteOncePod:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateNoExplicitRoot checks that no container of a pod is explicitly
+// configured to run as root, either with runAsUser 0 or with runAsNonRoot
+// false and no other user. Settings of the container take precedence over
+// those of the pod.
+func validateNoExplicitRoot(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var user *int64
+	var nonRoot *bool
+	if sc := tmpl.Spec.SecurityContext; sc != nil {
+		user, nonRoot = sc.RunAsUser, sc.RunAsNonRoot
+	}
+
+	var root []string
+	for _, c := range allContainers(&tmpl.Spec) {
+		containerUser, containerNonRoot := user, nonRoot
+		if sc := c.SecurityContext; sc != nil {
+			if sc.RunAsUser != nil {
+				containerUser = sc.RunAsUser
+			}
+			if sc.RunAsNonRoot != nil {
+				containerNonRoot = sc.RunAsNonRoot
+			}
+		}
+		if containerUser != nil && *containerUser == 0 ||
+			containerUser == nil && containerNonRoot != nil && !*containerNonRoot {
+			root = append(root, fmt.Sprintf("%q", c.Name))
+		}
+	}
+	if len(root) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s explicitly runs container(s) %s as root", obj, strings.Join(root, ", "))
+}
+
+// validateTopologySpreadConstraints checks the topologySpreadConstraints of a
+// pod for values which keep the pods from being scheduled.
+func validateTopologySpreadConstraints(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var problems []string
+	for i, c := range tmpl.Spec.TopologySpreadConstraints {
+		if c.MaxSkew < 1 {
+			problems = append(problems, fmt.Sprintf("constraint %d has maxSkew %d, which must be at least 1", i, c.MaxSkew))
+		}
+		if c.TopologyKey == "" {
+			problems = append(problems, fmt.Sprintf("constraint %d has an empty topologyKey", i))
+		}
+		switch c.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			problems = append(problems, fmt.Sprintf("constraint %d has whenUnsatisfiable %q, which must be %s or %s", i, c.WhenUnsatisfiable, corev1.DoNotSchedule, corev1.ScheduleAnyway))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s has invalid topologySpreadConstraints: %s", obj, strings.Join(problems, "; "))
+}
+
+// hostPortUse is a hostPort declared by a container of a rendered pod.
+type hostPortUse struct {
+	obj       renderedObject
+	container string
+	port      corev1.ContainerPort
+}
+
+func (u hostPortUse) String() string {
+	return fmt.Sprintf("%s container %q", u.obj, u.container)
+}
+
+// collectHostPorts returns the hostPorts declared by the rendered pods, keyed
+// by port and protocol.
+func collectHostPorts(objs []renderedObject) map[string][]hostPortUse {
+	uses := map[string][]hostPortUse{}
+	for _, obj := range objs {
+		tmpl, ok := obj.podTemplate()
+		if !ok {
+			continue
+		}
+		for _, c := range allContainers(&tmpl.Spec) {
+			for _, p := range c.Ports {
+				if p.HostPort == 0 {
+					continue
+				}
+				if p.Protocol == "" {
+					p.Protocol = corev1.ProtocolTCP
+				}
+				key := fmt.Sprintf("%d/%s", p.HostPort, p.Protocol)
+				uses[key] = append(uses[key], hostPortUse{obj: obj, container: c.Name, port: p})
+			}
+		}
+	}
+	return uses
+}
+
+// validateNoSharedHostPorts checks that the hostPorts of a pod aren't declared
+// by other containers of the chart whose pods could be placed on the same
+// node, as they can't run there at the same time. Pods which are kept apart
+// by a required pod anti-affinity on the node hostname are skipped.
+func validateNoSharedHostPorts(obj renderedObject, hostPorts map[string][]hostPortUse) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(hostPorts))
+	for key := range hostPorts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conflicts []string
+	for _, key := range keys {
+		uses := hostPorts[key]
+		for i, u := range uses {
+			if u.obj.path != obj.path || u.obj.GetKind() != obj.GetKind() || u.obj.GetName() != obj.GetName() {
+				continue
+			}
+			var others []string
+			for j, other := range uses {
+				if i == j || !sameHostIPs(u.port, other.port) {
+					continue
+				}
+				otherTmpl, _ := other.obj.podTemplate()
+				if antiAffine(tmpl, otherTmpl) || antiAffine(otherTmpl, tmpl) {
+					continue
+				}
+				others = append(others, other.String())
+			}
+			if len(others) > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("container %q uses hostPort %s, which is also used by %s", u.container, key, strings.Join(others, ", ")))
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s can't be scheduled on the same node as other pods of the chart: %s", obj, strings.Join(conflicts, "; "))
+}
+
+// sameHostIPs reports whether two ports bind the same host address.
+func sameHostIPs(a, b corev1.ContainerPort) bool {
+	unspecified := func(ip string) bool { return ip == "" || ip == "0.0.0.0" || ip == "::" }
+	return unspecified(a.HostIP) || unspecified(b.HostIP) || a.HostIP == b.HostIP
+}
+
+// antiAffine reports whether the pods of tmpl are required not to run on the
+// same node as the pods of other, as far as can be told from the templates.
+func antiAffine(tmpl, other *corev1.PodTemplateSpec) bool {
+	if tmpl.Spec.Affinity == nil || tmpl.Spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range tmpl.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey != "kubernetes.io/hostname" || term.LabelSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(other.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRequestsWithinLimits checks that no container requests more of a
+// resource than its limit, which the API server rejects.
+func validateRequestsWithinLimits(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var problems []string
+	for _, c := range allContainers(&tmpl.Spec) {
+		names := make([]string, 0, len(c.Resources.Requests))
+		for name := range c.Resources.Requests {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			request := c.Resources.Requests[corev1.ResourceName(name)]
+			limit, ok := c.Resources.Limits[corev1.ResourceName(name)]
+			if ok && request.Cmp(limit) > 0 {
+				problems = append(problems, fmt.Sprintf("container %q requests %s %s, more than its limit of %s", c.Name, request.String(), name, limit.String()))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s has resource requests exceeding their limits: %s", obj, strings.Join(problems, "; "))
+}
+
+// defaultContainerAnnotation names the primary container of a pod, used by
+// kubectl when no container is given.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// validateSidecarResources checks that when the primary container of a pod
+// sets resource requests or limits, its init and sidecar containers do too.
+// The primary container is the one named by the default-container
+// annotation, or the first one.
+func validateSidecarResources(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok || len(tmpl.Spec.Containers) == 0 {
+		return nil
+	}
+	primary := tmpl.Spec.Containers[0].Name
+	if name, ok := tmpl.Annotations[defaultContainerAnnotation]; ok {
+		primary = name
+	}
+	var missing []string
+	primarySet := false
+	for _, c := range append(append([]corev1.Container{}, tmpl.Spec.InitContainers...), tmpl.Spec.Containers...) {
+		set := len(c.Resources.Requests) > 0 || len(c.Resources.Limits) > 0
+		if c.Name == primary {
+			primarySet = set
+		} else if !set {
+			missing = append(missing, fmt.Sprintf("%q", c.Name))
+		}
+	}
+	if !primarySet || len(missing) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s sets resources on its container %q, but not on the init or sidecar container(s) %s. Without requests they aren't accounted for when scheduling the pod, and without limits they can use the resources of the node without bounds", obj, primary, strings.Join(missing, ", "))
+}
+
+// validateRevisionHistoryLimit checks that a Deployment or StatefulSet bounds
+// the number of old revisions it keeps. Without revisionHistoryLimit, every
+// upgrade leaves another old ReplicaSet or ControllerRevision behind, up to
+// the default of 10.
+func validateRevisionHistoryLimit(obj renderedObject) error {
+	if obj.GetKind() != "Deployment" && obj.GetKind() != "StatefulSet" {
+		return nil
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "revisionHistoryLimit"); found {
+		return nil
+	}
+	return errors.Errorf("%s does not set revisionHistoryLimit. Set it to bound the number of old revisions kept", obj)
+}
+
+// validateImageRegistry checks that the images of all containers of a pod
+// are pulled from one of the allowed registries. It is off without a config.
+func validateImageRegistry(obj renderedObject, config *ImageRegistryConfig) error {
+	if config == nil {
+		return nil
+	}
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var disallowed []string
+	for _, c := range allContainers(&tmpl.Spec) {
+		named, err := reference.ParseNormalizedNamed(c.Image)
+		if err != nil {
+			continue
+		}
+		if !registryAllowed(named.Name(), config.Allowed) {
+			disallowed = append(disallowed, fmt.Sprintf("container %q uses image %q from %s", c.Name, c.Image, reference.Domain(named)))
+		}
+	}
+	if len(disallowed) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s pulls images from registries which are not allowed: %s. Allowed registries are: %s", obj, strings.Join(disallowed, "; "), strings.Join(config.Allowed, ", "))
+}
+
+// registryAllowed reports whether the fully qualified repository name is
+// below one of the allowed registries.
+func registryAllowed(name string, allowed []string) bool {
+	for _, a := range allowed {
+		a = strings.TrimSuffix(strings.ToLower(a), "/")
+		if strings.HasPrefix(strings.ToLower(name), a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFSGroup checks that pods running containers as a non-root user set
+// fsGroup when the containers write to a PersistentVolumeClaim. Without it,
+// the volume usually keeps the ownership of its root directory and the
+// containers can't write to it.
+func validateFSGroup(obj renderedObject) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var user *int64
+	if sc := tmpl.Spec.SecurityContext; sc != nil {
+		if sc.FSGroup != nil {
+			return nil
+		}
+		user = sc.RunAsUser
+	}
+
+	claims := map[string]bool{}
+	for _, v := range tmpl.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil && !v.PersistentVolumeClaim.ReadOnly {
+			claims[v.Name] = true
+		}
+	}
+	if obj.GetKind() == "StatefulSet" {
+		templates, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+		for _, t := range templates {
+			tmpl, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, _, _ := unstructured.NestedString(tmpl, "metadata", "name"); name != "" {
+				claims[name] = true
+			}
+		}
+	}
+
+	var mounts []string
+	for _, c := range allContainers(&tmpl.Spec) {
+		containerUser := user
+		if c.SecurityContext != nil && c.SecurityContext.RunAsUser != nil {
+			containerUser = c.SecurityContext.RunAsUser
+		}
+		if containerUser == nil || *containerUser == 0 {
+			continue
+		}
+		for _, m := range c.VolumeMounts {
+			if claims[m.Name] && !m.ReadOnly {
+				mounts = append(mounts, fmt.Sprintf("container %q (user %d) mounts %q", c.Name, *containerUser, m.Name))
+			}
+		}
+	}
+	if len(mounts) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s mounts writable PersistentVolumeClaims in containers running as a non-root user, but does not set securityContext.fsGroup: %s. The volume permissions may keep the containers from writing to it", obj, strings.Join(mounts, "; "))
+}
+
+// validateEnvFrom checks the ConfigMaps and Secrets containers load with
+// envFrom: sources must be rendered by the chart unless they are optional or
+// declared as external, and the keys of rendered sources which are also set
+// with env are reported, since env silently takes precedence over them. The
+// binaryData of ConfigMaps is not loaded as environment variables.
+func validateEnvFrom(obj renderedObject, index objectIndex) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	var problems []string
+	missing := false
+	for _, c := range allContainers(&tmpl.Spec) {
+		explicit := map[string]bool{}
+		for _, e := range c.Env {
+			explicit[e.Name] = true
+		}
+		for _, source := range c.EnvFrom {
+			kind, name, optional := "ConfigMap", "", false
+			var keyFields [][]string
+			switch {
+			case source.ConfigMapRef != nil:
+				name = source.ConfigMapRef.Name
+				optional = source.ConfigMapRef.Optional != nil && *source.ConfigMapRef.Optional
+				keyFields = [][]string{{"data"}}
+			case source.SecretRef != nil:
+				kind, name = "Secret", source.SecretRef.Name
+				optional = source.SecretRef.Optional != nil && *source.SecretRef.Optional
+				keyFields = [][]string{{"data"}, {"stringData"}}
+			default:
+				continue
+			}
+			if obj.isExternal(kind, name) {
+				continue
+			}
+			ref, ok := index.get(kind, name)
+			if !ok {
+				if !optional {
+					problems = append(problems, fmt.Sprintf("container %q loads %s %q, which is not rendered by the chart", c.Name, kind, name))
+					missing = true
+				}
+				continue
+			}
+			var overridden []string
+			seen := map[string]bool{}
+			for _, fields := range keyFields {
+				data, _, _ := unstructured.NestedMap(ref.Object, fields...)
+				for key := range data {
+					if explicit[source.Prefix+key] && !seen[key] {
+						seen[key] = true
+						overridden = append(overridden, source.Prefix+key)
+					}
+				}
+			}
+			if len(overridden) > 0 {
+				sort.Strings(overridden)
+				problems = append(problems, fmt.Sprintf("container %q sets %s with env, overriding the keys loaded from %s %q", c.Name, strings.Join(overridden, ", "), kind, name))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if missing {
+		return errors.Errorf("%s: %s. If a source is managed outside of the chart, add the annotation %s: Kind/name", obj, strings.Join(problems, "; "), externalAnnotation)
+	}
+	return errors.Errorf("%s: %s", obj, strings.Join(problems, "; "))
+}
+
+// validateMatchExpressions checks the operators and values of every
+// matchExpressions and matchFields requirement of an object, in label
+// selectors as well as node selector terms, which the API server rejects when
+// they don't agree.
+func validateMatchExpressions(obj renderedObject) error {
+	var problems []string
+	walkRequirements(obj.Object, "", false, func(path string, node bool, req map[string]interface{}) {
+		if problem := requirementProblem(req, node); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s %s", path, problem))
+		}
+	})
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s has invalid match expressions: %s", obj, strings.Join(problems, "; "))
+}
+
+// walkRequirements calls fn with the field path of every requirement found
+// under matchExpressions or matchFields. node tells whether the requirement
+// belongs to a node selector term, which also allows the Gt and Lt
+// operators.
+func walkRequirements(v interface{}, path string, node bool, fn func(path string, node bool, req map[string]interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			childNode := node || key == "nodeAffinity" || key == "nodeSelectorTerms"
+			if reqs, ok := v[key].([]interface{}); ok && (key == "matchExpressions" || key == "matchFields") {
+				for i, req := range reqs {
+					if req, ok := req.(map[string]interface{}); ok {
+						fn(fmt.Sprintf("%s[%d]", child, i), childNode || key == "matchFields", req)
+					}
+				}
+				continue
+			}
+			walkRequirements(v[key], child, childNode, fn)
+		}
+	case []interface{}:
+		for i, item := range v {
+			walkRequirements(item, fmt.Sprintf("%s[%d]", path, i), node, fn)
+		}
+	}
+}
+
+// requirementProblem describes what is wrong with a requirement, or returns
+// an empty string if it is valid.
+func requirementProblem(req map[string]interface{}, node bool) string {
+	key, _ := req["key"].(string)
+	operator, _ := req["operator"].(string)
+	values, _ := req["values"].([]interface{})
+	if key == "" {
+		return "has an empty key"
+	}
+	switch operator {
+	case "In", "NotIn":
+		if len(values) == 0 {
+			return fmt.Sprintf("(key %q) uses operator %s without values", key, operator)
+		}
+	case "Exists", "DoesNotExist":
+		if len(values) != 0 {
+			return fmt.Sprintf("(key %q) uses operator %s with values, which must be empty", key, operator)
+		}
+	case "Gt", "Lt":
+		if !node {
+			return fmt.Sprintf("(key %q) uses operator %s, which is only valid in node selector terms", key, operator)
+		}
+		if len(values) != 1 {
+			return fmt.Sprintf("(key %q) uses operator %s with %d values, it requires exactly one", key, operator, len(values))
+		}
+		if s, ok := values[0].(string); !ok || !isInteger(s) {
+			return fmt.Sprintf("(key %q) uses operator %s with value %v, which must be an integer", key, operator, values[0])
+		}
+	default:
+		return fmt.Sprintf("(key %q) uses unknown operator %q", key, operator)
+	}
+	return ""
+}
+
+func isInteger(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// checksumAnnotationPrefix starts the pod annotations holding a checksum of
+// the configuration of a workload, e.g.
+// `checksum/config: {{ include (print $.Template.BasePath "/configmap.yaml") . | sha256sum }}`.
+// A change of the configuration changes the pod template, which rolls the
+// pods out.
+const checksumAnnotationPrefix = "checksum/"
+
+// validateConfigChecksum checks that a Deployment, StatefulSet or DaemonSet
+// using a ConfigMap or Secret rendered by the chart has a checksum
+// annotation on its pod template. Without one, the pods keep running with
+// the previous configuration when only the ConfigMap or Secret changes.
+func validateConfigChecksum(obj renderedObject, index objectIndex) error {
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet", "DaemonSet":
+	default:
+		return nil
+	}
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	for key := range tmpl.Annotations {
+		if strings.HasPrefix(key, checksumAnnotationPrefix) {
+			return nil
+		}
+	}
+
+	var used []string
+	seen := map[string]bool{}
+	use := func(kind, name string) {
+		ref := kind + "/" + name
+		if name == "" || seen[ref] || !index.has(kind, name) {
+			return
+		}
+		seen[ref] = true
+		used = append(used, ref)
+	}
+	for _, v := range tmpl.Spec.Volumes {
+		switch {
+		case v.ConfigMap != nil:
+			use("ConfigMap", v.ConfigMap.Name)
+		case v.Secret != nil:
+			use("Secret", v.Secret.SecretName)
+		case v.Projected != nil:
+			for _, source := range v.Projected.Sources {
+				if source.ConfigMap != nil {
+					use("ConfigMap", source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					use("Secret", source.Secret.Name)
+				}
+			}
+		}
+	}
+	for _, c := range allContainers(&tmpl.Spec) {
+		for _, source := range c.EnvFrom {
+			if source.ConfigMapRef != nil {
+				use("ConfigMap", source.ConfigMapRef.Name)
+			}
+			if source.SecretRef != nil {
+				use("Secret", source.SecretRef.Name)
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if e.ValueFrom.ConfigMapKeyRef != nil {
+				use("ConfigMap", e.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if e.ValueFrom.SecretKeyRef != nil {
+				use("Secret", e.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	if len(used) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s uses %s rendered by the chart, but its pod template has no %s* annotation, so changing them doesn't roll the pods out. Add an annotation like checksum/config with the sha256sum of the template, or suppress the rule with # helm-lint:ignore %s if the pods reload their configuration", obj, strings.Join(used, ", "), checksumAnnotationPrefix, RuleConfigChecksum)
+}
+
+// defaultStartupProbeDelay is the initialDelaySeconds of a liveness probe
+// from which the startup-probe rule recommends a startup probe, unless the
+// rules config sets another one.
+const defaultStartupProbeDelay = 60
+
+// initialDelay returns the delay set in the config, or the default one.
+func (c *StartupProbeConfig) initialDelay() int32 {
+	if c == nil || c.InitialDelay == 0 {
+		return defaultStartupProbeDelay
+	}
+	return c.InitialDelay
+}
+
+// validateStartupProbes checks that containers whose liveness probe waits
+// long before its first check have a startup probe instead. The delay is a
+// guess of the startup time: a container starting slower is killed before
+// it is ready, while one starting faster isn't checked for that long.
+func validateStartupProbes(obj renderedObject, config *StartupProbeConfig) error {
+	tmpl, ok := obj.podTemplate()
+	if !ok {
+		return nil
+	}
+	threshold := config.initialDelay()
+	var slow []string
+	for _, c := range tmpl.Spec.Containers {
+		if c.LivenessProbe == nil || c.StartupProbe != nil || c.LivenessProbe.InitialDelaySeconds < threshold {
+			continue
+		}
+		slow = append(slow, fmt.Sprintf("%q (%ds)", c.Name, c.LivenessProbe.InitialDelaySeconds))
+	}
+	if len(slow) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s delays the liveness probe of container(s) %s without a startup probe. Add a startupProbe, which holds the liveness probe off only until the container has started", obj, strings.Join(slow, ", "))
+}
@@ -0,0 +1,1258 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// mustDecodeObject decodes a single manifest into a renderedObject.
+func mustDecodeObject(t *testing.T, manifest string) renderedObject {
+	t.Helper()
+	objs := decodeObjects("templates/test.yaml", manifest)
+	if len(objs) != 1 {
+		t.Fatalf("Expected 1 object, got %d", len(objs))
+	}
+	return objs[0]
+}
+
+func TestPodTemplate(t *testing.T) {
+	for _, manifest := range []string{
+		"kind: Pod\nmetadata:\n  name: foo\nspec:\n  containers:\n  - name: app\n",
+		"kind: Deployment\nmetadata:\n  name: foo\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n",
+		"kind: CronJob\nmetadata:\n  name: foo\nspec:\n  jobTemplate:\n    spec:\n      template:\n        spec:\n          containers:\n          - name: app\n",
+	} {
+		tmpl, ok := mustDecodeObject(t, manifest).podTemplate()
+		if !ok || len(tmpl.Spec.Containers) != 1 || tmpl.Spec.Containers[0].Name != "app" {
+			t.Errorf("Expected a pod template with container app in %q", manifest)
+		}
+	}
+	if _, ok := mustDecodeObject(t, "kind: ConfigMap\nmetadata:\n  name: foo\n").podTemplate(); ok {
+		t.Error("Expected ConfigMap not to have a pod template")
+	}
+}
+
+func TestValidateNoSharedMountPaths(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        volumeMounts:
+        - name: data
+          mountPath: /var/data
+        - name: config
+          mountPath: /etc/app
+          subPath: app.conf
+      - name: sidecar
+        volumeMounts:
+        - name: data
+          mountPath: /var/data/cache
+        - name: config
+          mountPath: /etc/app
+          subPath: sidecar.conf
+        - name: other
+          mountPath: /var/data
+`
+	err := validateNoSharedMountPaths(mustDecodeObject(t, manifest))
+	if err == nil {
+		t.Fatal("Expected overlapping mounts to be reported")
+	}
+	if !strings.Contains(err.Error(), `Deployment "web"`) || !strings.Contains(err.Error(), `volume "data" is mounted by container "app" at "/var/data" and by container "sidecar" at "/var/data/cache"`) {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if strings.Contains(err.Error(), "config") || strings.Contains(err.Error(), "other") {
+		t.Errorf("Expected only the data volume to be reported, got: %s", err)
+	}
+
+	manifest = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - name: app
+    volumeMounts:
+    - name: data
+      mountPath: /var/data
+  - name: sidecar
+    volumeMounts:
+    - name: data
+      mountPath: /var/database
+`
+	if err := validateNoSharedMountPaths(mustDecodeObject(t, manifest)); err != nil {
+		t.Errorf("Expected non-overlapping mounts to pass, got: %s", err)
+	}
+}
+
+func TestValidateNodeLabelKeys(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/os: linux
+        topology.kubernetes.io/zome: us-east-1a
+        example.com/pool: blue
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: kubernets.io/arch
+                operator: In
+                values: [amd64]
+          preferredDuringSchedulingIgnoredDuringExecution:
+          - weight: 1
+            preference:
+              matchExpressions:
+              - key: node.kubernetes.io/instance-type
+                operator: Exists
+`
+	err := validateNodeLabelKeys(mustDecodeObject(t, manifest))
+	if err == nil {
+		t.Fatal("Expected misspelled node labels to be reported")
+	}
+	expected := `Deployment "web" selects nodes by unknown label keys: "kubernets.io/arch" (did you mean "kubernetes.io/arch"?), "topology.kubernetes.io/zome" (did you mean "topology.kubernetes.io/zone"?)`
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err)
+	}
+
+	manifest = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  nodeSelector:
+    kubernetes.io/arch: arm64
+    example.com/pool: blue
+`
+	if err := validateNodeLabelKeys(mustDecodeObject(t, manifest)); err != nil {
+		t.Errorf("Expected known and custom labels to pass, got: %s", err)
+	}
+}
+
+func TestValidateServiceAccountRef(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: rendered
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dangling
+spec:
+  template:
+    spec:
+      serviceAccountName: missing
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: external
+  annotations:
+    helm.sh/lint-external: "Secret/foo, ServiceAccount/builder"
+spec:
+  template:
+    spec:
+      serviceAccountName: builder
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: external-template
+spec:
+  serviceAccountName: default
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateServiceAccountRef(obj, index)
+		if obj.GetName() == "dangling" {
+			if err == nil || !strings.Contains(err.Error(), `Deployment "dangling" uses ServiceAccount "missing"`) {
+				t.Errorf("Expected the dangling ServiceAccount reference to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateImagePullSecrets(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: registry
+type: kubernetes.io/dockerconfigjson
+data:
+  .dockerconfigjson: e30=
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dangling
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+      - name: registry
+      - name: missing
+      - name: other
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: external
+spec:
+  template:
+    metadata:
+      annotations:
+        helm.sh/lint-external: Secret/mirror
+    spec:
+      imagePullSecrets:
+      - name: mirror
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: rendered
+spec:
+  imagePullSecrets:
+  - name: registry
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateImagePullSecrets(obj, index)
+		if obj.GetName() == "dangling" {
+			if err == nil || !strings.Contains(err.Error(), `Deployment "dangling" pulls images with the Secret(s) "missing", "other", which are not rendered by the chart`) {
+				t.Errorf("Expected the dangling imagePullSecrets to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateClaimAccessModes(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: rwo
+spec:
+  accessModes: [ReadWriteOnce]
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: rwx
+spec:
+  accessModes: [ReadWriteMany]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: scaled
+spec:
+  replicas: 3
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: rwo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: single
+spec:
+  replicas: 1
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: rwo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shared
+spec:
+  replicas: 3
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: rwx
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: external
+spec:
+  replicas: 3
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: elsewhere
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateClaimAccessModes(obj, index)
+		if obj.GetName() == "scaled" {
+			if err == nil || !strings.Contains(err.Error(), `Deployment "scaled" runs 3 replicas, but mounts PersistentVolumeClaim "rwo"`) {
+				t.Errorf("Expected the ReadWriteOnce claim to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateClaimTemplateNames(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: cache
+spec:
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data-db-0
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data-db-backup
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data-cache
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateClaimTemplateNames(obj, index)
+		if obj.GetName() == "db" {
+			if err == nil || !strings.Contains(err.Error(), `the rendered PersistentVolumeClaim(s) "data-db-0" (from template "data")`) {
+				t.Errorf("Expected the overlapping claim to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateNoExplicitRoot(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: root-user
+spec:
+  containers:
+  - name: app
+    securityContext:
+      runAsUser: 0
+  - name: sidecar
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: root-allowed
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: false
+      containers:
+      - name: app
+      - name: sidecar
+        securityContext:
+          runAsUser: 1000
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: overridden
+spec:
+  securityContext:
+    runAsUser: 0
+  containers:
+  - name: app
+    securityContext:
+      runAsUser: 1000
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: unset
+spec:
+  containers:
+  - name: app
+`)
+	want := map[string]string{
+		"root-user":    `Pod "root-user" explicitly runs container(s) "app" as root`,
+		"root-allowed": `Deployment "root-allowed" explicitly runs container(s) "app" as root`,
+	}
+	for _, obj := range objs {
+		err := validateNoExplicitRoot(obj)
+		if msg, ok := want[obj.GetName()]; ok {
+			if err == nil || err.Error() != msg {
+				t.Errorf("Expected %q, got %v", msg, err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateTopologySpreadConstraints(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      topologySpreadConstraints:
+      - maxSkew: 1
+        topologyKey: topology.kubernetes.io/zone
+        whenUnsatisfiable: DoNotSchedule
+      - maxSkew: 0
+        topologyKey: ""
+        whenUnsatisfiable: ScheduleAnyway
+      - maxSkew: 2
+        topologyKey: kubernetes.io/hostname
+        whenUnsatisfiable: Sometimes
+`
+	err := validateTopologySpreadConstraints(mustDecodeObject(t, manifest))
+	want := `Deployment "web" has invalid topologySpreadConstraints: constraint 1 has maxSkew 0, which must be at least 1; constraint 1 has an empty topologyKey; constraint 2 has whenUnsatisfiable "Sometimes", which must be DoNotSchedule or ScheduleAnyway`
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+
+	valid := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  topologySpreadConstraints:
+  - maxSkew: 1
+    topologyKey: kubernetes.io/hostname
+    whenUnsatisfiable: ScheduleAnyway
+`
+	if err := validateTopologySpreadConstraints(mustDecodeObject(t, valid)); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestValidateNoSharedHostPorts(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      containers:
+      - name: agent
+        ports:
+        - containerPort: 9100
+          hostPort: 9100
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: exporter
+spec:
+  template:
+    spec:
+      containers:
+      - name: exporter
+        ports:
+        - containerPort: 9100
+          hostPort: 9100
+          protocol: TCP
+        - containerPort: 53
+          hostPort: 53
+          protocol: UDP
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dns
+spec:
+  template:
+    metadata:
+      labels:
+        app: dns
+    spec:
+      affinity:
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+          - topologyKey: kubernetes.io/hostname
+            labelSelector:
+              matchLabels:
+                app: dns
+      containers:
+      - name: dns
+        ports:
+        - containerPort: 53
+          hostPort: 53
+          protocol: UDP
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dns-secondary
+spec:
+  template:
+    metadata:
+      labels:
+        app: dns
+    spec:
+      containers:
+      - name: dns
+        ports:
+        - containerPort: 53
+          hostPort: 53
+          protocol: UDP
+`)
+	hostPorts := collectHostPorts(objs)
+	want := map[string]string{
+		"agent":         `DaemonSet "agent" can't be scheduled on the same node as other pods of the chart: container "agent" uses hostPort 9100/TCP, which is also used by Deployment "exporter" container "exporter"`,
+		"exporter":      `Deployment "exporter" can't be scheduled on the same node as other pods of the chart: container "exporter" uses hostPort 53/UDP, which is also used by Deployment "dns" container "dns", Deployment "dns-secondary" container "dns"; container "exporter" uses hostPort 9100/TCP, which is also used by DaemonSet "agent" container "agent"`,
+		"dns":           `Deployment "dns" can't be scheduled on the same node as other pods of the chart: container "dns" uses hostPort 53/UDP, which is also used by Deployment "exporter" container "exporter"`,
+		"dns-secondary": `Deployment "dns-secondary" can't be scheduled on the same node as other pods of the chart: container "dns" uses hostPort 53/UDP, which is also used by Deployment "exporter" container "exporter"`,
+	}
+	for _, obj := range objs {
+		err := validateNoSharedHostPorts(obj, hostPorts)
+		if msg, ok := want[obj.GetName()]; ok {
+			if err == nil || err.Error() != msg {
+				t.Errorf("Expected %q, got %v", msg, err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateRequestsWithinLimits(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        resources:
+          requests:
+            cpu: 500m
+          limits:
+            cpu: "1"
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: "2"
+            memory: 1Gi
+          limits:
+            cpu: 1500m
+            memory: 512Mi
+      - name: sidecar
+        resources:
+          requests:
+            memory: 1000Mi
+          limits:
+            memory: 1Gi
+`
+	err := validateRequestsWithinLimits(mustDecodeObject(t, manifest))
+	want := `Deployment "web" has resource requests exceeding their limits: container "app" requests 2 cpu, more than its limit of 1500m; container "app" requests 1Gi memory, more than its limit of 512Mi`
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+}
+
+func TestValidateSidecarResources(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: forgotten
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 100m
+      - name: proxy
+      - name: logger
+        resources:
+          limits:
+            memory: 64Mi
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: annotated
+spec:
+  template:
+    metadata:
+      annotations:
+        kubectl.kubernetes.io/default-container: app
+    spec:
+      containers:
+      - name: proxy
+      - name: app
+        resources:
+          limits:
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: no-resources
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+      - name: proxy
+`)
+	expected := map[string]string{
+		"forgotten": `Deployment "forgotten" sets resources on its container "app", but not on the init or sidecar container(s) "migrate", "proxy"`,
+		"annotated": `Deployment "annotated" sets resources on its container "app", but not on the init or sidecar container(s) "proxy"`,
+	}
+	for _, obj := range objs {
+		err := validateSidecarResources(obj)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %s, got %v", want, obj, err)
+		}
+	}
+}
+
+func TestValidateRevisionHistoryLimit(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unbounded
+spec: {}
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: bounded
+spec:
+  revisionHistoryLimit: 0
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: other-kind
+spec: {}
+`)
+	for _, obj := range objs {
+		err := validateRevisionHistoryLimit(obj)
+		if obj.GetName() == "unbounded" {
+			if err == nil || !strings.Contains(err.Error(), `Deployment "unbounded" does not set revisionHistoryLimit`) {
+				t.Errorf("Expected the missing revisionHistoryLimit to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateImageRegistry(t *testing.T) {
+	obj := mustDecodeObject(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.example.com/tools/busybox:1.36
+      containers:
+      - name: nginx
+        image: nginx:1.25
+      - name: sidecar
+        image: mirror.example.com/team/proxy@sha256:0123456789012345678901234567890123456789012345678901234567890123
+`)
+
+	if err := validateImageRegistry(obj, nil); err != nil {
+		t.Errorf("Expected the rule to be off without a config, got %s", err)
+	}
+
+	err := validateImageRegistry(obj, &ImageRegistryConfig{Allowed: []string{"registry.example.com", "mirror.example.com/team/"}})
+	if err == nil {
+		t.Fatal("Expected the image from docker.io to be reported")
+	}
+	if !strings.Contains(err.Error(), `container "nginx" uses image "nginx:1.25" from docker.io`) {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if strings.Contains(err.Error(), `"init"`) || strings.Contains(err.Error(), `"sidecar"`) {
+		t.Errorf("Expected images from allowed registries not to be reported, got %s", err)
+	}
+
+	if err := validateImageRegistry(obj, &ImageRegistryConfig{Allowed: []string{"registry.example.com", "mirror.example.com", "docker.io"}}); err != nil {
+		t.Errorf("Expected all registries to be allowed, got %s", err)
+	}
+	if err := validateImageRegistry(obj, &ImageRegistryConfig{Allowed: []string{"registry.example.com", "mirror.example.com/other", "docker.io"}}); err == nil {
+		t.Error("Expected the image outside the allowed path to be reported")
+	}
+}
+
+func TestValidateFSGroup(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: no-fsgroup
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 1000
+      containers:
+      - name: app
+        volumeMounts:
+        - name: data
+          mountPath: /data
+        - name: cache
+          mountPath: /cache
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+      - name: cache
+        emptyDir: {}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: with-fsgroup
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsUser: 1000
+        fsGroup: 1000
+      containers:
+      - name: app
+        volumeMounts:
+        - name: data
+          mountPath: /data
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: read-only
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        securityContext:
+          runAsUser: 1000
+        volumeMounts:
+        - name: data
+          mountPath: /data
+          readOnly: true
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: root
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        volumeMounts:
+        - name: data
+          mountPath: /data
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: claim-template
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+        securityContext:
+          runAsUser: 999
+        volumeMounts:
+        - name: pgdata
+          mountPath: /var/lib/postgresql
+  volumeClaimTemplates:
+  - metadata:
+      name: pgdata
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: malformed-claim-template
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+        securityContext:
+          runAsUser: 999
+        volumeMounts:
+        - name: pgdata
+          mountPath: /var/lib/postgresql
+  volumeClaimTemplates:
+  - oops
+  - metadata:
+      name: pgdata
+`)
+	expected := map[string]string{
+		"no-fsgroup":               `container "app" (user 1000) mounts "data"`,
+		"claim-template":           `container "db" (user 999) mounts "pgdata"`,
+		"malformed-claim-template": `container "db" (user 999) mounts "pgdata"`,
+	}
+	for _, obj := range objs {
+		err := validateFSGroup(obj)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %s to be reported for %s, got %v", want, obj, err)
+		} else if strings.Contains(err.Error(), `"cache"`) {
+			t.Errorf("Expected the emptyDir not to be reported, got %s", err)
+		}
+	}
+}
+
+func TestValidateEnvFrom(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+binaryData:
+  LOG_FORMAT: anNvbg==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+data:
+  PASSWORD: c2VjcmV0
+stringData:
+  PASSWORD: secret
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: colliding
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: PORT
+          value: "9090"
+        - name: DB_PASSWORD
+          value: plain
+        - name: LOG_FORMAT
+          value: text
+        envFrom:
+        - configMapRef:
+            name: settings
+        - secretRef:
+            name: credentials
+          prefix: DB_
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dangling
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: missing
+        - secretRef:
+            name: maybe
+            optional: true
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: external
+  annotations:
+    helm.sh/lint-external: Secret/vault-env
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: LOG_LEVEL
+          value: debug
+        envFrom:
+        - secretRef:
+            name: vault-env
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: clean
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: EXTRA
+          value: "1"
+        envFrom:
+        - configMapRef:
+            name: settings
+`)
+	index := indexObjects(objs)
+	// The binaryData of settings isn't loaded, and the PASSWORD key of
+	// credentials is reported once although both data and stringData set it.
+	expected := map[string][]string{
+		"colliding": {
+			`container "app" sets PORT with env, overriding the keys loaded from ConfigMap "settings"`,
+			`container "app" sets DB_PASSWORD with env, overriding the keys loaded from Secret "credentials"`,
+		},
+		"dangling": {`container "app" loads ConfigMap "missing", which is not rendered by the chart`},
+	}
+	for _, obj := range objs {
+		err := validateEnvFrom(obj, index)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("Expected %s to be reported", obj)
+			continue
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("Expected %q in %q", w, err)
+			}
+		}
+		if strings.Contains(err.Error(), `"maybe"`) {
+			t.Errorf("Expected the optional source not to be reported, got %s", err)
+		}
+	}
+}
+
+func TestValidateMatchExpressions(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: invalid
+spec:
+  selector:
+    matchExpressions:
+    - key: app
+      operator: In
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: cores
+                operator: Gt
+                values: ["many"]
+              matchFields:
+              - key: metadata.name
+                operator: Exists
+                values: ["node-1"]
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+          - topologyKey: kubernetes.io/hostname
+            labelSelector:
+              matchExpressions:
+              - key: tier
+                operator: Lt
+                values: ["1"]
+              - key: zone
+                operator: Equals
+                values: ["a"]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: valid
+spec:
+  selector:
+    matchExpressions:
+    - key: app
+      operator: In
+      values: [web]
+    - key: canary
+      operator: DoesNotExist
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          preferredDuringSchedulingIgnoredDuringExecution:
+          - weight: 1
+            preference:
+              matchExpressions:
+              - key: cores
+                operator: Gt
+                values: ["4"]
+`)
+	if err := validateMatchExpressions(objs[1]); err != nil {
+		t.Errorf("Unexpected error for %s: %s", objs[1], err)
+	}
+	err := validateMatchExpressions(objs[0])
+	if err == nil {
+		t.Fatalf("Expected %s to be reported", objs[0])
+	}
+	for _, want := range []string{
+		`spec.selector.matchExpressions[0] (key "app") uses operator In without values`,
+		`spec.template.spec.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[0].matchExpressions[0] (key "cores") uses operator Gt with value many, which must be an integer`,
+		`nodeSelectorTerms[0].matchFields[0] (key "metadata.name") uses operator Exists with values, which must be empty`,
+		`labelSelector.matchExpressions[0] (key "tier") uses operator Lt, which is only valid in node selector terms`,
+		`labelSelector.matchExpressions[1] (key "zone") uses unknown operator "Equals"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestValidateConfigChecksum(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secret
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: without-checksum
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        envFrom:
+        - secretRef:
+            name: app-secret
+      volumes:
+      - name: config
+        configMap:
+          name: app-config
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: with-checksum
+spec:
+  template:
+    metadata:
+      annotations:
+        checksum/config: 0123abcd
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: app-secret
+              key: password
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: external-config
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+      volumes:
+      - name: config
+        configMap:
+          name: cluster-config
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+      volumes:
+      - name: config
+        configMap:
+          name: app-config
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateConfigChecksum(obj, index)
+		if obj.GetName() == "without-checksum" {
+			if err == nil || !strings.Contains(err.Error(), `uses ConfigMap/app-config, Secret/app-secret rendered by the chart`) {
+				t.Errorf("Expected the missing checksum to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateStartupProbes(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: slow
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        livenessProbe:
+          initialDelaySeconds: 120
+      - name: sidecar
+        livenessProbe:
+          initialDelaySeconds: 10
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: with-startup-probe
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        livenessProbe:
+          initialDelaySeconds: 120
+        startupProbe:
+          failureThreshold: 30
+          periodSeconds: 10
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: moderate
+spec:
+  containers:
+  - name: app
+    livenessProbe:
+      initialDelaySeconds: 45
+`)
+	for _, obj := range objs {
+		err := validateStartupProbes(obj, nil)
+		if obj.GetName() == "slow" {
+			if err == nil || !strings.Contains(err.Error(), `container(s) "app" (120s) without a startup probe`) {
+				t.Errorf("Expected the slow liveness probe to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+
+	// A lower threshold set in the rules config also reports moderate delays.
+	if err := validateStartupProbes(objs[2], &StartupProbeConfig{InitialDelay: 30}); err == nil {
+		t.Errorf("Expected the liveness probe to be reported with a lower threshold")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// Policy is a named CEL expression evaluated against every object rendered
+// by a chart. The object is available to the expression as `object`, and the
+// expression must evaluate to a boolean. A false result is reported as a lint
+// message.
+type Policy struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	// Severity is either "warning" or "error". Defaults to "warning".
+	Severity string `json:"severity,omitempty"`
+	// Message is an optional explanation appended to the lint message.
+	Message string `json:"message,omitempty"`
+
+	severity int
+	program  cel.Program
+}
+
+// policyFile is the format of a file passed to LoadPolicies.
+type policyFile struct {
+	Policies []Policy `json:"policies"`
+}
+
+// LoadPolicies reads and compiles the CEL policies defined in filename.
+//
+// The file is YAML with the following format:
+//
+//	policies:
+//	- name: require-team-label
+//	  expression: "has(object.metadata.labels) && 'team' in object.metadata.labels"
+//	  severity: error
+//	  message: every object must be labeled with its owning team
+func LoadPolicies(filename string) ([]Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read policy file")
+	}
+	var f policyFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse policy file %s", filename)
+	}
+
+	if err := CompilePolicies(f.Policies); err != nil {
+		return nil, errors.Wrapf(err, "invalid policy in %s", filename)
+	}
+	return f.Policies, nil
+}
+
+// CompilePolicies compiles policies which were not read by LoadPolicies, so
+// that they can be evaluated.
+func CompilePolicies(policies []Policy) error {
+	env, err := cel.NewEnv(cel.Variable("object", cel.DynType))
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		if err := policies[i].compile(env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Policy) compile(env *cel.Env) error {
+	if p.Name == "" {
+		return errors.New("policy name is required")
+	}
+	switch p.Severity {
+	case "", "warning":
+		p.severity = support.WarningSev
+	case "error":
+		p.severity = support.ErrorSev
+	default:
+		return errors.Errorf("policy %q: unknown severity %q, must be one of: warning, error", p.Name, p.Severity)
+	}
+
+	ast, issues := env.Compile(p.Expression)
+	if issues != nil && issues.Err() != nil {
+		return errors.Wrapf(issues.Err(), "policy %q", p.Name)
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return errors.Errorf("policy %q: expression must evaluate to a bool, not %s", p.Name, ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return errors.Wrapf(err, "policy %q", p.Name)
+	}
+	p.program = prg
+	return nil
+}
+
+// validatePolicy evaluates the policy against a rendered object.
+func validatePolicy(p Policy, obj renderedObject) error {
+	out, _, err := p.program.Eval(map[string]interface{}{"object": obj.Object})
+	if err != nil {
+		return errors.Wrapf(err, "policy %q could not be evaluated for %s", p.Name, obj)
+	}
+	if ok, isBool := out.Value().(bool); !isBool {
+		return errors.Errorf("policy %q did not evaluate to a bool for %s", p.Name, obj)
+	} else if !ok {
+		msg := fmt.Sprintf("policy %q is not satisfied by %s", p.Name, obj)
+		if p.Message != "" {
+			msg += ": " + p.Message
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
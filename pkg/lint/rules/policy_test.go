@@ -0,0 +1,117 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const policies = `policies:
+- name: require-team-label
+  expression: "has(object.metadata.labels) && 'team' in object.metadata.labels"
+  severity: error
+  message: every object must be labeled with its owning team
+- name: max-replicas
+  expression: "object.kind != 'Deployment' || object.spec.replicas <= 3"
+`
+
+func writePolicies(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadPolicies(t *testing.T) {
+	ps, err := LoadPolicies(writePolicies(t, policies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("Expected 2 policies, got %d", len(ps))
+	}
+	if ps[0].severity != support.ErrorSev || ps[1].severity != support.WarningSev {
+		t.Errorf("Unexpected severities %d and %d", ps[0].severity, ps[1].severity)
+	}
+
+	for _, bad := range []string{
+		"policies:\n- name: broken\n  expression: \"object.kind ==\"\n",
+		"policies:\n- name: not-bool\n  expression: \"'foo'\"\n",
+		"policies:\n- expression: \"true\"\n",
+		"policies:\n- name: bad-severity\n  expression: \"true\"\n  severity: fatal\n",
+	} {
+		if _, err := LoadPolicies(writePolicies(t, bad)); err == nil {
+			t.Errorf("Expected an error loading policies %q", bad)
+		}
+	}
+}
+
+func TestTemplatesWithPolicies(t *testing.T) {
+	ps, err := LoadPolicies(writePolicies(t, policies))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "policies",
+			Version:    "0.1.0",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/deployment.yaml",
+				Data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: foo\nspec:\n  replicas: 5\n  revisionHistoryLimit: 1\n  selector:\n    matchLabels:\n      app: foo\n"),
+			},
+			{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\n  labels:\n    team: platform\n"),
+			},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{Policies: ps})
+	if len(linter.Messages) != 2 {
+		t.Fatalf("Expected 2 lint messages, got %d: %v", len(linter.Messages), linter.Messages)
+	}
+	for i, want := range []string{
+		`policy "require-team-label" is not satisfied by Deployment "foo": every object must be labeled with its owning team`,
+		`policy "max-replicas" is not satisfied by Deployment "foo"`,
+	} {
+		msg := linter.Messages[i]
+		if msg.Path != "templates/deployment.yaml" || !strings.Contains(msg.Err.Error(), want) {
+			t.Errorf("Expected message %d to be %q on templates/deployment.yaml, got %s", i, want, msg)
+		}
+	}
+	if linter.HighestSeverity != support.ErrorSev {
+		t.Errorf("Expected highest severity to be an error, got %d", linter.HighestSeverity)
+	}
+}
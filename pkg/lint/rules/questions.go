@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// questionsFiles are the names of the file in which Rancher reads the
+// questions of a chart's install form.
+var questionsFiles = []string{"questions.yaml", "questions.yml"}
+
+// question is an entry of a questions file. Each question sets the value at
+// the dotted path in Variable.
+type question struct {
+	Variable     string      `json:"variable"`
+	Default      interface{} `json:"default"`
+	Type         string      `json:"type"`
+	Options      []string    `json:"options"`
+	Subquestions []question  `json:"subquestions"`
+}
+
+// Questions checks the questions file of a chart targeting Rancher against
+// its values.yaml: every question must set a value the chart defines, and
+// its default should match the one of values.yaml. Charts without a
+// questions file are skipped.
+func Questions(linter *support.Linter) {
+	for _, name := range questionsFiles {
+		data, err := os.ReadFile(filepath.Join(linter.ChartDir, name))
+		if err != nil {
+			continue
+		}
+		var file struct {
+			Questions []question `json:"questions"`
+		}
+		if !linter.RunLinterRuleWithID(RuleQuestions, support.ErrorSev, name, errors.Wrap(yaml.Unmarshal(data, &file), "unable to parse questions")) {
+			return
+		}
+		values, err := chartutil.ReadValuesFile(filepath.Join(linter.ChartDir, "values.yaml"))
+		if err != nil {
+			// A missing or invalid values.yaml is reported by the values rule.
+			return
+		}
+		for _, q := range flattenQuestions(file.Questions) {
+			if !linter.RunLinterRuleWithID(RuleQuestions, support.WarningSev, name, validateQuestionVariable(q, values)) {
+				continue
+			}
+			linter.RunLinterRuleWithID(RuleQuestions, support.InfoSev, name, validateQuestionDefault(q, values))
+		}
+		return
+	}
+}
+
+// flattenQuestions returns the questions with their subquestions.
+func flattenQuestions(questions []question) []question {
+	var all []question
+	for _, q := range questions {
+		all = append(all, q)
+		all = append(all, flattenQuestions(q.Subquestions)...)
+	}
+	return all
+}
+
+// lookupValue returns the value at a dotted path.
+func lookupValue(values map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		table, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = table[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// validateQuestionVariable checks that a question sets a value defined in
+// values.yaml.
+func validateQuestionVariable(q question, values map[string]interface{}) error {
+	if q.Variable == "" {
+		return errors.New("question has no variable")
+	}
+	if _, ok := lookupValue(values, q.Variable); !ok {
+		return errors.Errorf("question for %q references a value which is not defined in values.yaml", q.Variable)
+	}
+	return nil
+}
+
+// validateQuestionDefault checks that the default of a question is one of
+// its options and matches the value in values.yaml, so the install form
+// starts from the chart's defaults.
+func validateQuestionDefault(q question, values map[string]interface{}) error {
+	if q.Default == nil {
+		return nil
+	}
+	def := fmt.Sprint(q.Default)
+	if q.Type == "enum" && len(q.Options) > 0 && !slices.Contains(q.Options, def) {
+		return errors.Errorf("question for %q defaults to %q, which is not one of its options %s", q.Variable, def, strings.Join(q.Options, ", "))
+	}
+	value, _ := lookupValue(values, q.Variable)
+	if value == nil {
+		return nil
+	}
+	if _, ok := value.(map[string]interface{}); ok {
+		return nil
+	}
+	if v := fmt.Sprint(value); v != def {
+		return errors.Errorf("question for %q defaults to %q, but values.yaml sets %q", q.Variable, def, v)
+	}
+	return nil
+}
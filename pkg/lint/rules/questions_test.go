@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestQuestions(t *testing.T) {
+	chartDir := t.TempDir()
+	values := `image:
+  tag: "1.2"
+replicas: 3
+mode: simple
+`
+	questions := `questions:
+- variable: image.tag
+  default: "1.2"
+- variable: replicas
+  default: 2
+- variable: mode
+  type: enum
+  default: advanced
+  options: [simple, full]
+- variable: persistence.enabled
+  default: "false"
+  subquestions:
+  - variable: image.repository
+`
+	for name, content := range map[string]string{"values.yaml": values, "questions.yaml": questions} {
+		if err := os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linter := support.Linter{ChartDir: chartDir}
+	Questions(&linter)
+	expected := []struct {
+		severity int
+		text     string
+	}{
+		{support.InfoSev, `question for "replicas" defaults to "2", but values.yaml sets "3"`},
+		{support.InfoSev, `question for "mode" defaults to "advanced", which is not one of its options simple, full`},
+		{support.WarningSev, `question for "persistence.enabled" references a value which is not defined in values.yaml`},
+		{support.WarningSev, `question for "image.repository" references a value which is not defined in values.yaml`},
+	}
+	if len(linter.Messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %v", len(expected), linter.Messages)
+	}
+	for i, want := range expected {
+		msg := linter.Messages[i]
+		if msg.Severity != want.severity || msg.Err.Error() != want.text || msg.Path != "questions.yaml" || msg.RuleID != RuleQuestions {
+			t.Errorf("Expected %q with severity %d, got %v", want.text, want.severity, msg)
+		}
+	}
+
+	// Charts without a questions file are skipped.
+	if err := os.Remove(filepath.Join(chartDir, "questions.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	linter = support.Linter{ChartDir: chartDir}
+	Questions(&linter)
+	if len(linter.Messages) != 0 {
+		t.Errorf("Expected no messages without a questions file, got %v", linter.Messages)
+	}
+}
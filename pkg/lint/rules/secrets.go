@@ -0,0 +1,117 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// secretTypeKeys are the keys the API server requires in the Secrets of the
+// well-known types.
+var secretTypeKeys = map[corev1.SecretType][]string{
+	corev1.SecretTypeDockercfg:        {corev1.DockerConfigKey},
+	corev1.SecretTypeDockerConfigJson: {corev1.DockerConfigJsonKey},
+	corev1.SecretTypeSSHAuth:          {corev1.SSHAuthPrivateKey},
+	corev1.SecretTypeTLS:              {corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+}
+
+// validateSecretType checks that a Secret of a well-known type has the keys
+// the API server requires for that type, in data or stringData.
+func validateSecretType(obj renderedObject) error {
+	if obj.GetKind() != "Secret" {
+		return nil
+	}
+	secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+	keys := map[string]bool{}
+	for _, field := range []string{"data", "stringData"} {
+		data, _, _ := unstructured.NestedMap(obj.Object, field)
+		for key := range data {
+			keys[key] = true
+		}
+	}
+
+	switch t := corev1.SecretType(secretType); t {
+	case corev1.SecretTypeBasicAuth:
+		if !keys[corev1.BasicAuthUsernameKey] && !keys[corev1.BasicAuthPasswordKey] {
+			return errors.Errorf("%s of type %s has neither a %s nor a %s key", obj, t, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+		}
+	case corev1.SecretTypeServiceAccountToken:
+		if obj.GetAnnotations()[corev1.ServiceAccountNameKey] == "" {
+			return errors.Errorf("%s of type %s does not set the annotation %s", obj, t, corev1.ServiceAccountNameKey)
+		}
+	default:
+		var missing []string
+		for _, key := range secretTypeKeys[t] {
+			if !keys[key] {
+				missing = append(missing, fmt.Sprintf("%q", key))
+			}
+		}
+		if len(missing) > 0 {
+			return errors.Errorf("%s of type %s is missing the required key(s) %s", obj, t, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// validateNoResidualTemplates checks that the data of a ConfigMap or Secret
+// contains no template delimiters after rendering. They usually mean that
+// the template syntax of a file meant for the consuming application was
+// either processed by Helm or meant to be and escaped wrongly. The data of
+// Secrets is decoded first.
+func validateNoResidualTemplates(obj renderedObject) error {
+	var fields []string
+	switch obj.GetKind() {
+	case "ConfigMap":
+		fields = []string{"data"}
+	case "Secret":
+		fields = []string{"data", "stringData"}
+	default:
+		return nil
+	}
+	var keys []string
+	for _, field := range fields {
+		data, _, _ := unstructured.NestedMap(obj.Object, field)
+		for key, v := range data {
+			value, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if obj.GetKind() == "Secret" && field == "data" {
+				decoded, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					continue
+				}
+				value = string(decoded)
+			}
+			if strings.Contains(value, "{{") || strings.Contains(value, "}}") {
+				keys = append(keys, fmt.Sprintf("%s.%s", field, key))
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return errors.Errorf("%s has template delimiters in %s after rendering. If they are meant for the consuming application, escape them, e.g. with {{ \"{{\" }}, or load the file with .Files.Get", obj, strings.Join(keys, ", "))
+}
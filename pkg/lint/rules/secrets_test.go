@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSecretType(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: tls-missing-key
+type: kubernetes.io/tls
+data:
+  tls.crt: Y2VydA==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: tls
+type: kubernetes.io/tls
+data:
+  tls.crt: Y2VydA==
+stringData:
+  tls.key: key
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: registry
+type: kubernetes.io/dockerconfigjson
+data:
+  config.json: e30=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: basic-auth
+type: kubernetes.io/basic-auth
+stringData:
+  user: admin
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+type: kubernetes.io/service-account-token
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: opaque
+data:
+  anything: e30=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: custom
+type: example.com/custom
+`)
+	expected := map[string]string{
+		"tls-missing-key": `of type kubernetes.io/tls is missing the required key(s) "tls.key"`,
+		"registry":        `of type kubernetes.io/dockerconfigjson is missing the required key(s) ".dockerconfigjson"`,
+		"basic-auth":      `has neither a username nor a password key`,
+		"token":           `does not set the annotation kubernetes.io/service-account.name`,
+	}
+	for _, obj := range objs {
+		err := validateSecretType(obj)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %s, got %v", want, obj, err)
+		}
+	}
+}
+
+func TestValidateNoResidualTemplates(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unescaped
+data:
+  app.conf: |
+    greeting = {{ .Greeting }}
+  plain: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: encoded
+data:
+  config: dXNlciA9IHt7IC5Vc2VyIH19
+stringData:
+  other: "closing }} only"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: clean
+data:
+  app.conf: greeting = hello
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: "{{ not data }}"
+`)
+	expected := map[string]string{
+		"unescaped": `ConfigMap "unescaped" has template delimiters in data.app.conf after rendering`,
+		"encoded":   `Secret "encoded" has template delimiters in data.config, stringData.other after rendering`,
+	}
+	for _, obj := range objs {
+		err := validateNoResidualTemplates(obj)
+		want, ok := expected[obj.GetName()]
+		if !ok {
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %s", obj, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %s, got %v", want, obj, err)
+		}
+	}
+}
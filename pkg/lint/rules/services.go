@@ -0,0 +1,262 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// loadBalancerAnnotationPrefixes are the annotation prefixes used by cloud
+// providers and bare metal load balancer implementations to configure the
+// load balancer of a Service.
+var loadBalancerAnnotationPrefixes = []string{
+	"service.beta.kubernetes.io/",
+	"service.kubernetes.io/",
+	"cloud.google.com/",
+	"networking.gke.io/",
+	"oci.oraclecloud.com/",
+	"load-balancer.hetzner.cloud/",
+	"metallb.universe.tf/",
+	"kube-vip.io/",
+}
+
+// validateLoadBalancerDefault checks that a Service doesn't request a
+// LoadBalancer by default without configuring it for any provider. Charts
+// meant to run anywhere should leave the choice of a load balancer to the
+// user. defaults indexes the objects the chart renders with its own values:
+// a Service which isn't of type LoadBalancer among them gets the type from
+// the user supplied values.
+func validateLoadBalancerDefault(obj renderedObject, defaults objectIndex) error {
+	if !isLoadBalancerService(obj) {
+		return nil
+	}
+	if d, ok := defaults.get("Service", obj.GetName()); !ok || !isLoadBalancerService(d) {
+		return nil
+	}
+	for key := range obj.GetAnnotations() {
+		for _, prefix := range loadBalancerAnnotationPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return nil
+			}
+		}
+	}
+	return errors.Errorf("%s defaults to type LoadBalancer without any load balancer annotations, which provisions a cloud load balancer on install. Consider making the type configurable and defaulting to ClusterIP", obj)
+}
+
+// isLoadBalancerService reports whether obj is a Service of type
+// LoadBalancer.
+func isLoadBalancerService(obj renderedObject) bool {
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	return obj.GetKind() == "Service" && serviceType == "LoadBalancer"
+}
+
+// hasLoadBalancerService reports whether any of objs is a Service of type
+// LoadBalancer.
+func hasLoadBalancerService(objs []renderedObject) bool {
+	for _, obj := range objs {
+		if isLoadBalancerService(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateServiceTargetPorts checks that every targetPort of a Service is a
+// containerPort, by number or by name, of the rendered pods it selects.
+// Services selecting none of the rendered pods are skipped, as their
+// backends are managed elsewhere.
+func validateServiceTargetPorts(obj renderedObject, objs []renderedObject) error {
+	svc, ports, ok := selectedContainerPorts(obj, objs)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	for _, p := range svc.Spec.Ports {
+		target := serviceTargetPort(p)
+		if !hasContainerPort(ports, target) {
+			problems = append(problems, fmt.Sprintf("targetPort %s", target.String()))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s routes to %s, which no container of the selected pods declares as a containerPort", obj, strings.Join(problems, ", "))
+}
+
+// validateServicePortProtocols checks that every port of a Service uses the
+// protocol of the containerPorts it routes to, TCP when unset on either side.
+// Ports routing to no containerPort are left to validateServiceTargetPorts.
+func validateServicePortProtocols(obj renderedObject, objs []renderedObject) error {
+	svc, ports, ok := selectedContainerPorts(obj, objs)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	for _, p := range svc.Spec.Ports {
+		target := serviceTargetPort(p)
+		protocol := portProtocol(p.Protocol)
+		var targetProtocols []string
+		matched := false
+		for _, cp := range ports {
+			if !isContainerPort(cp, target) {
+				continue
+			}
+			if portProtocol(cp.Protocol) == protocol {
+				matched = true
+				break
+			}
+			targetProtocols = append(targetProtocols, string(portProtocol(cp.Protocol)))
+		}
+		if !matched && len(targetProtocols) > 0 {
+			problems = append(problems, fmt.Sprintf("port %d (%s) targets containerPort %s (%s)", p.Port, protocol, target.String(), strings.Join(targetProtocols, ", ")))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s routes to containerPorts declared with another protocol: %s", obj, strings.Join(problems, "; "))
+}
+
+// selectedContainerPorts returns the Service obj and the containerPorts of the
+// rendered pods it selects. It returns false for other objects, and for
+// Services selecting none of the rendered pods.
+func selectedContainerPorts(obj renderedObject, objs []renderedObject) (*corev1.Service, []corev1.ContainerPort, bool) {
+	if obj.GetKind() != "Service" {
+		return nil, nil, false
+	}
+	var svc corev1.Service
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &svc); err != nil || len(svc.Spec.Selector) == 0 {
+		return nil, nil, false
+	}
+
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	var ports []corev1.ContainerPort
+	selected := false
+	for _, o := range objs {
+		tmpl, ok := o.podTemplate()
+		if !ok || !selector.Matches(labels.Set(tmpl.Labels)) {
+			continue
+		}
+		selected = true
+		for _, c := range tmpl.Spec.Containers {
+			ports = append(ports, c.Ports...)
+		}
+	}
+	return &svc, ports, selected
+}
+
+// serviceTargetPort returns the targetPort of a Service port, which defaults
+// to the port itself.
+func serviceTargetPort(p corev1.ServicePort) intstr.IntOrString {
+	if p.TargetPort.Type == intstr.Int && p.TargetPort.IntVal == 0 {
+		return intstr.FromInt32(p.Port)
+	}
+	return p.TargetPort
+}
+
+// portProtocol returns the protocol of a port, which defaults to TCP.
+func portProtocol(protocol corev1.Protocol) corev1.Protocol {
+	if protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return protocol
+}
+
+func hasContainerPort(ports []corev1.ContainerPort, target intstr.IntOrString) bool {
+	for _, p := range ports {
+		if isContainerPort(p, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func isContainerPort(p corev1.ContainerPort, target intstr.IntOrString) bool {
+	return target.Type == intstr.String && p.Name == target.StrVal ||
+		target.Type == intstr.Int && p.ContainerPort == target.IntVal
+}
+
+// validateIngressPathType checks that every path of a networking.k8s.io/v1
+// Ingress sets pathType, which the API server requires. Older Ingress API
+// versions had no pathType and are skipped.
+func validateIngressPathType(obj renderedObject) error {
+	if obj.GetKind() != "Ingress" || obj.GetAPIVersion() != "networking.k8s.io/v1" {
+		return nil
+	}
+	ing := &networkingv1.Ingress{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ing); err != nil {
+		return nil
+	}
+	var missing []string
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			if p.PathType == nil {
+				if rule.Host != "" {
+					missing = append(missing, fmt.Sprintf("%q of host %q", p.Path, rule.Host))
+				} else {
+					missing = append(missing, fmt.Sprintf("%q", p.Path))
+				}
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s does not set pathType for path(s) %s. networking.k8s.io/v1 requires one of Exact, Prefix or ImplementationSpecific", obj, strings.Join(missing, ", "))
+}
+
+// validateWebhookServices checks that the webhooks of a
+// ValidatingWebhookConfiguration or MutatingWebhookConfiguration call
+// Services rendered by the chart, or declared as external. The API server
+// fails the requests sent to a webhook whose Service doesn't exist.
+func validateWebhookServices(obj renderedObject, index objectIndex) error {
+	if obj.GetKind() != "ValidatingWebhookConfiguration" && obj.GetKind() != "MutatingWebhookConfiguration" {
+		return nil
+	}
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	var dangling []string
+	for _, w := range webhooks {
+		webhook, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		service, _, _ := unstructured.NestedString(webhook, "clientConfig", "service", "name")
+		if service == "" || index.has("Service", service) || obj.isExternal("Service", service) {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(webhook, "name")
+		dangling = append(dangling, fmt.Sprintf("webhook %q calls Service %q", name, service))
+	}
+	if len(dangling) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s has webhooks calling Services which are not rendered by the chart: %s. If they are managed outside of the chart, add the annotation %s: Service/name", obj, strings.Join(dangling, ", "), externalAnnotation)
+}
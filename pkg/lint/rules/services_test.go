@@ -0,0 +1,336 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLoadBalancerDefault(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: plain
+spec:
+  type: LoadBalancer
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: annotated
+  annotations:
+    service.beta.kubernetes.io/aws-load-balancer-type: nlb
+spec:
+  type: LoadBalancer
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: cluster-ip
+spec:
+  type: ClusterIP
+`)
+	defaults := indexObjects(objs)
+	// Without the user supplied values, the chart renders plain as a
+	// ClusterIP Service.
+	userSet := indexObjects(decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: plain
+spec:
+  type: ClusterIP
+`))
+	for _, obj := range objs {
+		err := validateLoadBalancerDefault(obj, defaults)
+		if obj.GetName() == "plain" {
+			if err == nil || !strings.Contains(err.Error(), `Service "plain" defaults to type LoadBalancer`) {
+				t.Errorf("Expected the LoadBalancer default to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+		if err := validateLoadBalancerDefault(obj, userSet); err != nil {
+			t.Errorf("Unexpected error for %s set by the user: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateServiceTargetPorts(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: app
+        ports:
+        - name: http
+          containerPort: 8080
+        - containerPort: 9090
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: matching
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+    targetPort: http
+  - port: 9090
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mismatched
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+    targetPort: web
+  - port: 81
+    targetPort: 8081
+  - port: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  selector:
+    app: elsewhere
+  ports:
+  - port: 80
+`)
+	for _, obj := range objs {
+		err := validateServiceTargetPorts(obj, objs)
+		if obj.GetName() == "mismatched" {
+			want := `Service "mismatched" routes to targetPort web, targetPort 8081, which no container of the selected pods declares as a containerPort`
+			if err == nil || err.Error() != want {
+				t.Errorf("Expected %q, got %v", want, err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateServicePortProtocols(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: dns
+spec:
+  template:
+    metadata:
+      labels:
+        app: dns
+    spec:
+      containers:
+      - name: dns
+        ports:
+        - name: dns
+          containerPort: 53
+          protocol: UDP
+        - name: dns-tcp
+          containerPort: 53
+          protocol: TCP
+        - name: metrics
+          containerPort: 9153
+        - name: syslog
+          containerPort: 514
+          protocol: UDP
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: matching
+spec:
+  selector:
+    app: dns
+  ports:
+  - port: 53
+    protocol: UDP
+  - port: 53
+    targetPort: dns-tcp
+  - port: 9153
+    protocol: TCP
+  - port: 80
+    targetPort: missing
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mismatched
+spec:
+  selector:
+    app: dns
+  ports:
+  - port: 9153
+    protocol: UDP
+  - port: 1514
+    targetPort: syslog
+`)
+	for _, obj := range objs {
+		err := validateServicePortProtocols(obj, objs)
+		if obj.GetName() == "mismatched" {
+			want := `Service "mismatched" routes to containerPorts declared with another protocol: port 9153 (UDP) targets containerPort 9153 (TCP); port 1514 (TCP) targets containerPort syslog (UDP)`
+			if err == nil || err.Error() != want {
+				t.Errorf("Expected %q, got %v", want, err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateIngressPathType(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: missing
+spec:
+  rules:
+  - host: example.com
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: web
+            port:
+              number: 80
+      - path: /api
+        backend:
+          service:
+            name: api
+            port:
+              number: 80
+  - http:
+      paths:
+      - path: /health
+        backend:
+          service:
+            name: web
+            port:
+              number: 80
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: complete
+spec:
+  defaultBackend:
+    service:
+      name: web
+      port:
+        number: 80
+  rules:
+  - http:
+      paths:
+      - path: /
+        pathType: ImplementationSpecific
+        backend:
+          service:
+            name: web
+            port:
+              number: 80
+---
+apiVersion: extensions/v1beta1
+kind: Ingress
+metadata:
+  name: legacy
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          serviceName: web
+          servicePort: 80
+`)
+	for _, obj := range objs {
+		err := validateIngressPathType(obj)
+		if obj.GetName() == "missing" {
+			if err == nil || !strings.Contains(err.Error(), `path(s) "/api" of host "example.com", "/health"`) {
+				t.Errorf("Expected the paths without pathType to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
+
+func TestValidateWebhookServices(t *testing.T) {
+	objs := decodeObjects("templates/test.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: webhook
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: dangling
+webhooks:
+- name: validate.example.com
+  clientConfig:
+    service:
+      name: webhook
+      namespace: default
+- name: audit.example.com
+  clientConfig:
+    service:
+      name: auditor
+      namespace: default
+- name: remote.example.com
+  clientConfig:
+    url: https://example.com/validate
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: external
+  annotations:
+    helm.sh/lint-external: Service/injector
+webhooks:
+- name: inject.example.com
+  clientConfig:
+    service:
+      name: injector
+      namespace: default
+`)
+	index := indexObjects(objs)
+	for _, obj := range objs {
+		err := validateWebhookServices(obj, index)
+		if obj.GetName() == "dangling" {
+			if err == nil || !strings.Contains(err.Error(), `not rendered by the chart: webhook "audit.example.com" calls Service "auditor".`) {
+				t.Errorf("Expected the dangling Service to be reported, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %s: %s", obj, err)
+		}
+	}
+}
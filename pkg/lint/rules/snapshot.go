@@ -0,0 +1,76 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// renderSnapshot joins the non-empty rendered templates in the format of
+// `helm template`, ordered by template path so that the result only changes
+// when the rendered content does.
+func renderSnapshot(rendered map[string]string) string {
+	paths := make([]string, 0, len(rendered))
+	for path, content := range rendered {
+		if strings.TrimSpace(content) != "" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", path, strings.TrimSpace(rendered[path]))
+	}
+	return b.String()
+}
+
+// validateSnapshot compares the rendered snapshot with the one stored in
+// filename, reporting the differences as a unified diff. With update, the
+// stored snapshot is replaced instead.
+func validateSnapshot(filename string, update bool, snapshot string) error {
+	if update {
+		return errors.Wrap(os.WriteFile(filename, []byte(snapshot), 0644), "unable to update snapshot")
+	}
+	stored, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return errors.Errorf("snapshot %s does not exist, create it with --update-snapshot", filename)
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to read snapshot")
+	}
+	if string(stored) == snapshot {
+		return nil
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(stored)),
+		B:        difflib.SplitLines(snapshot),
+		FromFile: filename,
+		ToFile:   "rendered",
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Errorf("rendered templates differ from snapshot %s, update it with --update-snapshot if the change is intended:\n%s", filename, diff)
+}
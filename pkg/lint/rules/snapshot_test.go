@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestRenderSnapshot(t *testing.T) {
+	snapshot := renderSnapshot(map[string]string{
+		"foo/templates/svc.yaml":     "kind: Service\n",
+		"foo/templates/_helpers.tpl": "\n  \n",
+		"foo/templates/cm.yaml":      "kind: ConfigMap",
+	})
+	want := "---\n# Source: foo/templates/cm.yaml\nkind: ConfigMap\n---\n# Source: foo/templates/svc.yaml\nkind: Service\n"
+	if snapshot != want {
+		t.Errorf("Expected snapshot %q, got %q", want, snapshot)
+	}
+}
+
+func TestTemplatesWithSnapshot(t *testing.T) {
+	mychart := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "snapshot", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  replicas: {{ 1 | quote }}\n"),
+		}},
+	}
+	tmpdir := t.TempDir()
+	snapshot := filepath.Join(tmpdir, "snapshot.yaml")
+
+	lint := func(update bool) []support.Message {
+		t.Helper()
+		if err := chartutil.SaveDir(mychart, tmpdir); err != nil {
+			t.Fatal(err)
+		}
+		linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+		TemplatesWithOptions(&linter, values, namespace, TemplateOptions{Snapshot: snapshot, UpdateSnapshot: update})
+		return linter.Messages
+	}
+
+	if msgs := lint(false); len(msgs) != 1 || !strings.Contains(msgs[0].Err.Error(), "does not exist") {
+		t.Fatalf("Expected a missing snapshot to be reported, got %v", msgs)
+	}
+	if msgs := lint(true); len(msgs) != 0 {
+		t.Fatalf("Unexpected messages updating the snapshot: %v", msgs)
+	}
+	if _, err := os.Stat(snapshot); err != nil {
+		t.Fatalf("Expected the snapshot to be written: %s", err)
+	}
+	if msgs := lint(false); len(msgs) != 0 {
+		t.Fatalf("Unexpected messages for an unchanged chart: %v", msgs)
+	}
+
+	mychart.Templates[0].Data = []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  replicas: {{ 2 | quote }}\n")
+	msgs := lint(false)
+	if len(msgs) != 1 || msgs[0].RuleID != RuleSnapshot || msgs[0].Severity != support.ErrorSev {
+		t.Fatalf("Expected the changed render to be reported, got %v", msgs)
+	}
+	for _, line := range []string{`-  replicas: "1"`, `+  replicas: "2"`} {
+		if !strings.Contains(msgs[0].Err.Error(), line) {
+			t.Errorf("Expected the diff to contain %q, got %s", line, msgs[0].Err)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// blockScalarSearch matches the lines starting a YAML block scalar, whose
+// content is not checked for indentation.
+var blockScalarSearch = regexp.MustCompile(`(^|:\s|^-\s)\s*[|>][-+0-9]*\s*$`)
+
+// Style checks the layout of the YAML files of a chart: Chart.yaml,
+// values.yaml and the templates. Every line must be indented by a multiple
+// of the configured width with spaces, have no trailing whitespace, and
+// every file must end with a newline. The indentation of lines starting
+// with a template action, comments and block scalars is not checked, nor
+// that of partials.
+func Style(linter *support.Linter, config *StyleConfig) {
+	indent := 2
+	if config != nil && config.Indent > 0 {
+		indent = config.Indent
+	}
+
+	files := []string{"Chart.yaml", "values.yaml"}
+	templatesDir := filepath.Join(linter.ChartDir, "templates")
+	_ = filepath.WalkDir(templatesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".tpl":
+			rel, _ := filepath.Rel(linter.ChartDir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(linter.ChartDir, name))
+		if err != nil {
+			// Missing files are reported by the other rules.
+			continue
+		}
+		checkIndent := filepath.Ext(name) != ".tpl"
+		linter.RunLinterRuleWithID(RuleStyle, support.WarningSev, name, validateStyle(data, indent, checkIndent))
+	}
+}
+
+// validateStyle checks the layout of a single file. The keys of a mapping in
+// a list item may be aligned with the first key after the dash rather than
+// indented by a multiple of the width.
+func validateStyle(data []byte, indent int, checkIndent bool) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var problems []string
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	blockIndent := -1
+	// bases are the columns indentation is counted from.
+	bases := []int{0}
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		n := i + 1
+		if strings.TrimRight(line, " \t") != line {
+			problems = append(problems, fmt.Sprintf("line %d has trailing whitespace", n))
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+		if blockIndent >= 0 {
+			if spaces > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			problems = append(problems, fmt.Sprintf("line %d is indented with a tab", n))
+			continue
+		}
+		if !checkIndent || strings.HasPrefix(trimmed, "{{") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		for len(bases) > 1 && bases[len(bases)-1] > spaces {
+			bases = bases[:len(bases)-1]
+		}
+		if (spaces-bases[len(bases)-1])%indent != 0 {
+			problems = append(problems, fmt.Sprintf("line %d is indented by %d spaces, not a multiple of %d", n, spaces, indent))
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			content := strings.TrimLeft(trimmed[1:], " ")
+			bases = append(bases, spaces+len(trimmed)-len(content))
+		}
+		if blockScalarSearch.MatchString(strings.TrimRight(trimmed, " ")) {
+			blockIndent = spaces
+		}
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		problems = append(problems, "the file does not end with a newline")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("style: %s", strings.Join(problems, "; "))
+}
+
+// validateDocumentSeparators checks that a rendered template separates its
+// documents consistently: it must not render empty documents, nor both start
+// and end with a separator, and it must start with one as the config
+// requires. Documents holding only comments are empty.
+func validateDocumentSeparators(content string, config *DocumentSeparatorsConfig) error {
+	if config == nil {
+		return nil
+	}
+	var problems []string
+	separators, empty := 0, 0
+	leading, hasContent, docContent := false, false, false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t") {
+			switch {
+			case separators == 0 && !docContent:
+				leading = true
+			case !docContent:
+				empty++
+			}
+			separators++
+			docContent = false
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			hasContent, docContent = true, true
+		}
+	}
+	if !hasContent {
+		return nil
+	}
+	if leading && !docContent {
+		problems = append(problems, "it both starts and ends with a document separator")
+	}
+	if empty > 0 {
+		problems = append(problems, fmt.Sprintf("it renders %d empty document(s) between separators", empty))
+	}
+	switch {
+	case config.Leading == "always" && !leading:
+		problems = append(problems, "it doesn't start with a document separator")
+	case config.Leading == "never" && leading:
+		problems = append(problems, "it starts with a document separator")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("inconsistent document separators: %s", strings.Join(problems, "; "))
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateStyle(t *testing.T) {
+	clean := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        args:
+          - --verbose
+        command: |
+          run   \
+             --flag
+{{- include "chart.extra" . | nindent 6 }}
+    # commented:
+   # out
+`
+	if err := validateStyle([]byte(clean), 2, true); err != nil {
+		t.Errorf("Unexpected style error: %s", err)
+	}
+
+	messy := "key: value \nnested:\n   odd: 1\n\tkey: tab\nlast: line"
+	err := validateStyle([]byte(messy), 2, true)
+	if err == nil {
+		t.Fatal("Expected style errors")
+	}
+	for _, want := range []string{
+		"line 1 has trailing whitespace",
+		"line 3 is indented by 3 spaces, not a multiple of 2",
+		"line 4 is indented with a tab",
+		"the file does not end with a newline",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+
+	if err := validateStyle([]byte("a:\n  b: 1\n"), 4, true); err == nil || !strings.Contains(err.Error(), "line 2 is indented by 2 spaces, not a multiple of 4") {
+		t.Errorf("Expected the indent of 4 to be enforced, got %v", err)
+	}
+	if err := validateStyle([]byte("{{- define \"x\" }}\n   {{ .Values.x }}\n{{- end }}\n"), 2, false); err != nil {
+		t.Errorf("Unexpected style error without the indentation check: %s", err)
+	}
+}
+
+func TestValidateDocumentSeparators(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		config  *DocumentSeparatorsConfig
+		err     string
+	}{{
+		name:    "off without a config",
+		content: "---\n---\nkind: ConfigMap\n---\n",
+	}, {
+		name:    "leading separators",
+		content: "---\nkind: ConfigMap\n---\nkind: Secret\n",
+		config:  &DocumentSeparatorsConfig{},
+	}, {
+		name:    "trailing separators",
+		content: "kind: ConfigMap\n---\nkind: Secret\n---\n",
+		config:  &DocumentSeparatorsConfig{},
+	}, {
+		name:    "only comments",
+		content: "---\n# disabled\n---\n",
+		config:  &DocumentSeparatorsConfig{},
+	}, {
+		name:    "leading and trailing separators",
+		content: "---\nkind: ConfigMap\n--- # next\n",
+		config:  &DocumentSeparatorsConfig{},
+		err:     "inconsistent document separators: it both starts and ends with a document separator",
+	}, {
+		name:    "empty documents",
+		content: "kind: ConfigMap\n---\n\n---\n# disabled\n---\nkind: Secret\n",
+		config:  &DocumentSeparatorsConfig{},
+		err:     "inconsistent document separators: it renders 2 empty document(s) between separators",
+	}, {
+		name:    "missing leading separator",
+		content: "kind: ConfigMap\n---\nkind: Secret\n",
+		config:  &DocumentSeparatorsConfig{Leading: "always"},
+		err:     "inconsistent document separators: it doesn't start with a document separator",
+	}, {
+		name:    "forbidden leading separator",
+		content: "---\nkind: ConfigMap\n",
+		config:  &DocumentSeparatorsConfig{Leading: "never"},
+		err:     "inconsistent document separators: it starts with a document separator",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDocumentSeparators(tt.content, tt.config)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -32,6 +32,7 @@ import (
 	apipath "k8s.io/apimachinery/pkg/api/validation/path"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
 
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
@@ -42,6 +43,83 @@ import (
 var (
 	crdHookSearch     = regexp.MustCompile(`"?helm\.sh/hook"?:\s+crd-install`)
 	releaseTimeSearch = regexp.MustCompile(`\.Release\.Time`)
+	// volatileValueSearch matches template actions calling the functions
+	// which return a different value on every render.
+	volatileValueSearch = regexp.MustCompile(`\{\{[^}]*\b(now|randAlphaNum|randAlpha|randNumeric|randAscii|randInt|uuidv4|genPrivateKey|genCA)\b`)
+	annotationKeySearch = regexp.MustCompile(`^(\s*)["']?([^"':\s]+)["']?:`)
+)
+
+// deprecatedBuiltins are the built-in objects and template functions which
+// are deprecated, with their replacements.
+var deprecatedBuiltins = []struct {
+	search      *regexp.Regexp
+	name        string
+	replacement string
+}{
+	{regexp.MustCompile(`\.Capabilities\.KubeVersion\.GitVersion\b`), ".Capabilities.KubeVersion.GitVersion", ".Capabilities.KubeVersion.Version"},
+	{regexp.MustCompile(`\.Capabilities\.TillerVersion\b`), ".Capabilities.TillerVersion", ".Capabilities.HelmVersion"},
+	{regexp.MustCompile(`\{\{[^}]*\btrimall\b`), "the trimall function", "trimAll"},
+}
+
+// Identifiers of the template rules. They can be used in ignore comments
+// within templates, e.g. `# helm-lint:ignore deprecated-api`.
+const (
+	RuleTemplateExtension = "template-extension"
+	RuleCRDHook           = "crd-install-hook"
+	RuleReleaseTime       = "release-time"
+	RuleTopIndent         = "top-indent"
+	RuleYAMLSyntax        = "yaml-syntax"
+	RuleMetadataName      = "metadata-name"
+	RuleDeprecatedAPI     = "deprecated-api"
+	RuleMatchSelector     = "match-selector"
+	RuleListAnnotations   = "list-annotations"
+	RuleSharedMountPath   = "shared-mount-path"
+	RuleNodeLabelTypo     = "node-label-typo"
+	RuleServiceAccountRef = "service-account-ref"
+	RuleHookIntent        = "hook-intent"
+	RuleClaimAccessModes  = "claim-access-modes"
+	RuleLoadBalancer      = "load-balancer-default"
+	RuleExplicitRoot      = "run-as-root"
+	RuleTopologySpread    = "topology-spread"
+	RuleSharedHostPort    = "shared-host-port"
+	RuleResourceLimits    = "resource-limits"
+	RuleSidecarResources  = "sidecar-resources"
+	RuleRevisionHistory   = "revision-history-limit"
+	RuleFSGroup           = "fs-group"
+	RuleReplicasSchema    = "replicas-schema"
+	RuleIngressPathType   = "ingress-path-type"
+	RuleEnvFrom           = "env-from"
+	RuleAnnotationChurn   = "annotation-churn"
+	RuleMatchExpressions  = "match-expressions"
+	RuleSecretType        = "secret-type"
+	RuleStyle             = "style"
+	RuleQuestions         = "questions"
+	RuleOwnerReferences   = "owner-references"
+	RuleResidualTemplate  = "residual-template"
+	RuleClaimTemplateName = "claim-template-name"
+	RuleImagePullSecrets  = "image-pull-secrets"
+	RuleDeprecatedBuiltin = "deprecated-builtin"
+	RuleSnapshot          = "snapshot"
+	RuleServiceTargetPort = "service-target-port"
+	RuleWebhookService    = "webhook-service"
+	RuleDependencyAlias   = "dependency-alias"
+	RuleImageRegistry     = "image-registry"
+	RuleUnusedIgnore      = "unused-ignore"
+	RuleClusterCRDSchema  = "cluster-crd-schema"
+	RuleClusterCRDMissing = "cluster-crd-missing"
+	RuleConfigChecksum    = "config-checksum"
+	RuleStartupProbe      = "startup-probe"
+	RuleResourcePolicy    = "resource-policy"
+	RuleCRFieldTypes      = "cr-field-types"
+	RulePortProtocol      = "port-protocol"
+	RuleUnusedValues      = "unused-values"
+	RuleValuesSchema      = "values-schema"
+	RuleDocSeparators     = "document-separators"
+	RuleResourceKinds     = "resource-kinds"
+	RuleHPAMetrics        = "hpa-metrics"
+	RuleRenderedCount     = "rendered-count"
+	RuleHelperPrefix      = "helper-prefix"
+	RuleNotesFile         = "notes-file"
 )
 
 // Templates lints the templates in the Linter.
@@ -49,8 +127,65 @@ func Templates(linter *support.Linter, values map[string]interface{}, namespace
 	TemplatesWithKubeVersion(linter, values, namespace, nil)
 }
 
+// TemplateOptions holds the optional settings used when linting templates.
+type TemplateOptions struct {
+	// KubeVersion is the Kubernetes version used for capabilities and deprecation checks.
+	KubeVersion *chartutil.KubeVersion
+	// Policies are evaluated against every object rendered by the chart.
+	// Their findings are identified by the policy name.
+	Policies []Policy
+	// ReportUnusedIgnores reports ignore comments which don't suppress any finding.
+	ReportUnusedIgnores bool
+	// LookupConfig, when set, connects the lookup template function to the
+	// cluster instead of having it return empty results.
+	LookupConfig *rest.Config
+	// RenderCache, when set, is consulted before rendering the chart. The
+	// render is shared with `helm template` when it renders the chart with
+	// the same values and release, i.e. with ReleaseName and the namespace,
+	// and lint mode let no missing required value or fail through.
+	RenderCache engine.RenderCache
+	// ReleaseName is the name of the release the chart is rendered for, as
+	// an install. Unset, it is "test-release".
+	ReleaseName string
+	// Snapshot, when set, is the file the rendered templates are compared
+	// with. With UpdateSnapshot, the file is rewritten instead.
+	Snapshot       string
+	UpdateSnapshot bool
+	// RulesConfig configures the rules which are off without settings.
+	RulesConfig *RulesConfig
+	// StrictRender fails the render on references to missing values
+	// instead of rendering them as empty.
+	StrictRender bool
+	// ExpandValues runs the string values containing template
+	// syntax through `tpl` before rendering, so errors in them are found even
+	// if the chart doesn't expand them in the tested configuration.
+	ExpandValues bool
+	// ConventionsOnly only checks the Helm conventions of the template
+	// sources, including the names of the defined templates and the location
+	// of NOTES.txt. The templates are not rendered, so none of the rules
+	// about the rendered manifests run.
+	ConventionsOnly bool
+	// ClusterCRDs, when set, are the schemas of the custom resources served
+	// by the cluster. Rendered custom resources are validated against them.
+	ClusterCRDs CRDSchemas
+	// APIVersions, when set, replaces the API versions the templates see as
+	// served by the cluster in .Capabilities.APIVersions.
+	APIVersions chartutil.VersionSet
+	// RenderedCount reports the number of objects the templates of the
+	// chart render, by kind, as an info.
+	RenderedCount bool
+	// ChartCache loads the chart, to reuse it across lint runs.
+	ChartCache *loader.Cache
+}
+
 // TemplatesWithKubeVersion lints the templates in the Linter, allowing to specify the kubernetes version.
 func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) {
+	TemplatesWithOptions(linter, values, namespace, TemplateOptions{KubeVersion: kubeVersion})
+}
+
+// TemplatesWithOptions lints the templates in the Linter using the given options.
+func TemplatesWithOptions(linter *support.Linter, values map[string]interface{}, namespace string, opts TemplateOptions) {
+	kubeVersion := opts.KubeVersion
 	fpath := "templates/"
 	templatesPath := filepath.Join(linter.ChartDir, fpath)
 
@@ -62,7 +197,7 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 	}
 
 	// Load chart and parse templates
-	chart, err := loader.Load(linter.ChartDir)
+	chart, err := opts.ChartCache.Load(linter.ChartDir)
 
 	chartLoaded := linter.RunLinterRule(support.ErrorSev, fpath, err)
 
@@ -70,15 +205,55 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 		return
 	}
 
+	ignores := parseTemplateIgnores(chart.Templates)
+	runRule := func(ruleID string, severity int, path string, err error) bool {
+		if err != nil && ignores.suppress(path, ruleID) {
+			return false
+		}
+		return linter.RunLinterRuleWithID(ruleID, severity, path, err)
+	}
+	// lintSource checks the Helm conventions of a template's source.
+	lintSource := func(fileName string, data []byte) {
+		runRule(RuleTemplateExtension, support.ErrorSev, fileName, validateAllowedExtension(fileName))
+		// These are v3 specific checks to make sure and warn people if their
+		// chart is not compatible with v3
+		runRule(RuleCRDHook, support.WarningSev, fileName, validateNoCRDHooks(data))
+		runRule(RuleReleaseTime, support.ErrorSev, fileName, validateNoReleaseTime(data))
+		runRule(RuleDeprecatedBuiltin, support.InfoSev, fileName, validateNoDeprecatedBuiltins(data))
+		runRule(RuleReplicasSchema, support.InfoSev, fileName, validateReplicasSchema(data, chart.Schema))
+		runRule(RuleHelperPrefix, support.InfoSev, fileName, validateHelperNames(data, chart))
+	}
+	for _, fileName := range chartFileNames(chart) {
+		runRule(RuleNotesFile, support.WarningSev, fileName, validateNotesFile(fileName))
+	}
+
+	if opts.ConventionsOnly {
+		for _, template := range chart.Templates {
+			lintSource(template.Name, template.Data)
+		}
+		if opts.ReportUnusedIgnores {
+			ignores.reportUnused(linter)
+		}
+		return
+	}
+
 	options := chartutil.ReleaseOptions{
-		Name:      "test-release",
+		Name:      opts.ReleaseName,
 		Namespace: namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+	if options.Name == "" {
+		options.Name = "test-release"
 	}
 
 	caps := chartutil.DefaultCapabilities.Copy()
 	if kubeVersion != nil {
 		caps.KubeVersion = *kubeVersion
 	}
+	if opts.APIVersions != nil {
+		caps.APIVersions = opts.APIVersions
+	}
 
 	// lint ignores import-values
 	// See https://github.com/helm/helm/issues/9658
@@ -97,7 +272,20 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 		return
 	}
 	var e engine.Engine
+	if opts.LookupConfig != nil {
+		e = engine.New(opts.LookupConfig)
+		e.LintLookup = true
+	}
+	e.Strict = opts.StrictRender
+	if opts.ExpandValues {
+		expander := e
+		expander.LintMode = true
+		if !linter.RunLinterRule(support.ErrorSev, "values.yaml", expandValueTemplates(expander, chart, valuesToRender)) {
+			return
+		}
+	}
 	e.LintMode = true
+	e.Cache = opts.RenderCache
 	renderedContentMap, err := e.Render(chart, valuesToRender)
 
 	renderOk := linter.RunLinterRule(support.ErrorSev, fpath, err)
@@ -106,6 +294,10 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 		return
 	}
 
+	if opts.Snapshot != "" {
+		linter.RunLinterRuleWithID(RuleSnapshot, support.ErrorSev, fpath, validateSnapshot(opts.Snapshot, opts.UpdateSnapshot, renderSnapshot(renderedContentMap)))
+	}
+
 	/* Iterate over all the templates to check:
 	- It is a .yaml file
 	- All the values in the template file is defined
@@ -113,15 +305,17 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 	- Generated content is a valid Yaml file
 	- Metadata.Namespace is not set
 	*/
+	rulesConfig := opts.RulesConfig
+	if rulesConfig == nil {
+		rulesConfig = &RulesConfig{}
+	}
+	var objects []renderedObject
 	for _, template := range chart.Templates {
 		fileName, data := template.Name, template.Data
 		fpath = fileName
 
-		linter.RunLinterRule(support.ErrorSev, fpath, validateAllowedExtension(fileName))
-		// These are v3 specific checks to make sure and warn people if their
-		// chart is not compatible with v3
-		linter.RunLinterRule(support.WarningSev, fpath, validateNoCRDHooks(data))
-		linter.RunLinterRule(support.ErrorSev, fpath, validateNoReleaseTime(data))
+		lintSource(fileName, data)
+		runRule(RuleAnnotationChurn, support.InfoSev, fpath, validateNoVolatileAnnotations(data))
 
 		// We only apply the following lint rules to yaml files
 		if filepath.Ext(fileName) != ".yaml" || filepath.Ext(fileName) == ".yml" {
@@ -137,7 +331,8 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 
 		renderedContent := renderedContentMap[path.Join(chart.Name(), fileName)]
 		if strings.TrimSpace(renderedContent) != "" {
-			linter.RunLinterRule(support.WarningSev, fpath, validateTopIndentLevel(renderedContent))
+			runRule(RuleTopIndent, support.WarningSev, fpath, validateTopIndentLevel(renderedContent))
+			runRule(RuleDocSeparators, support.WarningSev, fpath, validateDocumentSeparators(renderedContent, rulesConfig.DocumentSeparators))
 
 			decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(renderedContent), 4096)
 
@@ -154,21 +349,81 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 
 				//  If YAML linting fails here, it will always fail in the next block as well, so we should return here.
 				// fix https://github.com/helm/helm/issues/11391
-				if !linter.RunLinterRule(support.ErrorSev, fpath, validateYamlContent(err)) {
+				if !runRule(RuleYAMLSyntax, support.ErrorSev, fpath, validateYamlContent(err)) {
 					return
 				}
 				if yamlStruct != nil {
 					// NOTE: set to warnings to allow users to support out-of-date kubernetes
 					// Refs https://github.com/helm/helm/issues/8596
-					linter.RunLinterRule(support.WarningSev, fpath, validateMetadataName(yamlStruct))
-					linter.RunLinterRule(support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
+					runRule(RuleMetadataName, support.WarningSev, fpath, validateMetadataName(yamlStruct))
+					runRule(RuleDeprecatedAPI, support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
 
-					linter.RunLinterRule(support.ErrorSev, fpath, validateMatchSelector(yamlStruct, renderedContent))
-					linter.RunLinterRule(support.ErrorSev, fpath, validateListAnnotations(yamlStruct, renderedContent))
+					runRule(RuleMatchSelector, support.ErrorSev, fpath, validateMatchSelector(yamlStruct, renderedContent))
+					runRule(RuleListAnnotations, support.ErrorSev, fpath, validateListAnnotations(yamlStruct, renderedContent))
 				}
 			}
+			objects = append(objects, decodeObjects(fpath, renderedContent)...)
+		}
+	}
+
+	if opts.RenderedCount {
+		linter.RunLinterRuleWithID(RuleRenderedCount, support.InfoSev, "templates/", renderedCount(objects))
+	}
+
+	index := indexObjects(objects)
+	// A Service only defaults to type LoadBalancer if the chart renders it so
+	// with its own values, so with user supplied values the chart is rendered
+	// again without them.
+	defaults := index
+	if len(values) > 0 && hasLoadBalancerService(objects) {
+		defaults = indexObjects(renderDefaultObjects(opts.ChartCache, linter.ChartDir, e, options, caps))
+	}
+	hostPorts := collectHostPorts(objects)
+	for _, obj := range objects {
+		runRule(RuleSharedMountPath, support.InfoSev, obj.path, validateNoSharedMountPaths(obj))
+		runRule(RuleNodeLabelTypo, support.InfoSev, obj.path, validateNodeLabelKeys(obj))
+		runRule(RuleServiceAccountRef, support.InfoSev, obj.path, validateServiceAccountRef(obj, index))
+		runRule(RuleImagePullSecrets, support.InfoSev, obj.path, validateImagePullSecrets(obj, index))
+		runRule(RuleHookIntent, support.InfoSev, obj.path, validateHookIntent(obj))
+		runRule(RuleResourcePolicy, support.InfoSev, obj.path, validateResourcePolicy(obj))
+		runRule(RuleClaimAccessModes, support.InfoSev, obj.path, validateClaimAccessModes(obj, index))
+		runRule(RuleClaimTemplateName, support.InfoSev, obj.path, validateClaimTemplateNames(obj, index))
+		runRule(RuleLoadBalancer, support.InfoSev, obj.path, validateLoadBalancerDefault(obj, defaults))
+		runRule(RuleExplicitRoot, support.WarningSev, obj.path, validateNoExplicitRoot(obj))
+		runRule(RuleTopologySpread, support.InfoSev, obj.path, validateTopologySpreadConstraints(obj))
+		runRule(RuleSharedHostPort, support.InfoSev, obj.path, validateNoSharedHostPorts(obj, hostPorts))
+		runRule(RuleResourceLimits, support.ErrorSev, obj.path, validateRequestsWithinLimits(obj))
+		runRule(RuleSidecarResources, support.InfoSev, obj.path, validateSidecarResources(obj))
+		runRule(RuleStartupProbe, support.InfoSev, obj.path, validateStartupProbes(obj, rulesConfig.StartupProbe))
+		runRule(RuleRevisionHistory, support.InfoSev, obj.path, validateRevisionHistoryLimit(obj))
+		runRule(RuleHPAMetrics, support.InfoSev, obj.path, validateHPAMetrics(obj, index))
+		runRule(RuleServiceTargetPort, support.InfoSev, obj.path, validateServiceTargetPorts(obj, objects))
+		runRule(RulePortProtocol, support.InfoSev, obj.path, validateServicePortProtocols(obj, objects))
+		runRule(RuleWebhookService, support.InfoSev, obj.path, validateWebhookServices(obj, index))
+		runRule(RuleImageRegistry, support.WarningSev, obj.path, validateImageRegistry(obj, rulesConfig.ImageRegistry))
+		runRule(RuleFSGroup, support.InfoSev, obj.path, validateFSGroup(obj))
+		runRule(RuleIngressPathType, support.ErrorSev, obj.path, validateIngressPathType(obj))
+		runRule(RuleEnvFrom, support.InfoSev, obj.path, validateEnvFrom(obj, index))
+		runRule(RuleConfigChecksum, support.InfoSev, obj.path, validateConfigChecksum(obj, index))
+		runRule(RuleMatchExpressions, support.ErrorSev, obj.path, validateMatchExpressions(obj))
+		runRule(RuleSecretType, support.ErrorSev, obj.path, validateSecretType(obj))
+		runRule(RuleOwnerReferences, support.InfoSev, obj.path, validateNoOwnerReferences(obj, rulesConfig.OwnerReferences))
+		runRule(RuleResourceKinds, support.ErrorSev, obj.path, validateResourceKinds(obj, rulesConfig.ResourceKinds))
+		runRule(RuleResidualTemplate, support.InfoSev, obj.path, validateNoResidualTemplates(obj))
+		runRule(RuleCRFieldTypes, support.ErrorSev, obj.path, validateCRFieldTypes(obj, rulesConfig.CRFieldTypes))
+		if opts.ClusterCRDs != nil {
+			runRule(RuleClusterCRDSchema, support.ErrorSev, obj.path, validateClusterCRDSchema(obj, opts.ClusterCRDs))
+			runRule(RuleClusterCRDMissing, support.InfoSev, obj.path, validateClusterCRDInstalled(obj, opts.ClusterCRDs))
+		}
+
+		for _, policy := range opts.Policies {
+			runRule(policy.Name, policy.severity, obj.path, validatePolicy(policy, obj))
 		}
 	}
+
+	if opts.ReportUnusedIgnores {
+		ignores.reportUnused(linter)
+	}
 }
 
 // validateTopIndentLevel checks that the content does not start with an indent level > 0.
@@ -293,6 +548,40 @@ func validateNoCRDHooks(manifest []byte) error {
 	return nil
 }
 
+// validateNoVolatileAnnotations scans the source of a template for
+// annotations whose values change on every render, such as timestamps or
+// random strings. Changed pod annotations roll out the pods on every upgrade.
+// The checksum/ annotations are skipped, as they intentionally change with
+// the content they checksum.
+func validateNoVolatileAnnotations(template []byte) error {
+	var volatile []string
+	annotationsIndent := -1
+	for i, line := range strings.Split(string(template), "\n") {
+		m := annotationKeySearch.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent, key := len(m[1]), m[2]
+		if annotationsIndent >= 0 && indent <= annotationsIndent {
+			annotationsIndent = -1
+		}
+		if key == "annotations" {
+			annotationsIndent = indent
+			continue
+		}
+		if annotationsIndent < 0 || strings.HasPrefix(key, "checksum/") {
+			continue
+		}
+		if f := volatileValueSearch.FindStringSubmatch(line); f != nil {
+			volatile = append(volatile, fmt.Sprintf("%s (line %d, %s)", key, i+1, f[1]))
+		}
+	}
+	if len(volatile) == 0 {
+		return nil
+	}
+	return errors.Errorf("annotation(s) %s change on every render, which rolls out pods on every upgrade. Derive them from the content they describe, e.g. with sha256sum as in a checksum/ annotation", strings.Join(volatile, ", "))
+}
+
 func validateNoReleaseTime(manifest []byte) error {
 	if releaseTimeSearch.Match(manifest) {
 		return errors.New(".Release.Time has been removed in v3, please replace with the `now` function in your templates")
@@ -300,6 +589,22 @@ func validateNoReleaseTime(manifest []byte) error {
 	return nil
 }
 
+// validateNoDeprecatedBuiltins scans the source of a template for built-in
+// objects and functions which are deprecated, so charts can move to their
+// replacements before they are removed.
+func validateNoDeprecatedBuiltins(template []byte) error {
+	var found []string
+	for _, b := range deprecatedBuiltins {
+		if b.search.Match(template) {
+			found = append(found, fmt.Sprintf("%s is deprecated, use %s instead", b.name, b.replacement))
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(found, "; "))
+}
+
 // validateMatchSelector ensures that template specs have a selector declared.
 // See https://github.com/helm/helm/issues/1990
 func validateMatchSelector(yamlStruct *K8sYamlStruct, manifest string) error {
@@ -349,3 +654,34 @@ type k8sYamlMetadata struct {
 	Namespace string
 	Name      string
 }
+
+// renderDefaultObjects renders the templates of the chart at chartDir with
+// the values of the chart alone, and decodes the objects they render. It
+// returns no objects if the chart fails to render without user supplied
+// values.
+func renderDefaultObjects(cache *loader.Cache, chartDir string, e engine.Engine, options chartutil.ReleaseOptions, caps *chartutil.Capabilities) []renderedObject {
+	ch, err := cache.Load(chartDir)
+	if err != nil {
+		return nil
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(ch, map[string]interface{}{}); err != nil {
+		return nil
+	}
+	cvals, err := chartutil.CoalesceValues(ch, map[string]interface{}{})
+	if err != nil {
+		return nil
+	}
+	valuesToRender, err := chartutil.ToRenderValues(ch, cvals, options, caps)
+	if err != nil {
+		return nil
+	}
+	rendered, err := e.Render(ch, valuesToRender)
+	if err != nil {
+		return nil
+	}
+	var objects []renderedObject
+	for _, template := range ch.Templates {
+		objects = append(objects, decodeObjects(template.Name, rendered[path.Join(ch.Name(), template.Name)])...)
+	}
+	return objects
+}
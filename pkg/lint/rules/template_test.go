@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -212,7 +214,7 @@ func TestDeprecatedAPIFails(t *testing.T) {
 		Templates: []*chart.File{
 			{
 				Name: "templates/baddeployment.yaml",
-				Data: []byte("apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: baddep\nspec: {selector: {matchLabels: {foo: bar}}}"),
+				Data: []byte("apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: baddep\nspec: {revisionHistoryLimit: 3, selector: {matchLabels: {foo: bar}}}"),
 			},
 			{
 				Name: "templates/goodsecret.yaml",
@@ -458,3 +460,293 @@ items:
 		t.Fatalf("List objects keep annotations should pass. got: %s", err)
 	}
 }
+
+// TestTemplatesFilesRootedAtSubchart checks that .Files of a subchart linted
+// on its own, as with --with-subcharts, reads the subchart's files rather
+// than those of its parent.
+func TestTemplatesFilesRootedAtSubchart(t *testing.T) {
+	ps, err := LoadPolicies(writePolicies(t, `policies:
+- name: sub-config
+  expression: "object.kind != 'ConfigMap' || object.data.conf == 'from-sub'"
+  severity: error
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpdir := t.TempDir()
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "parent", Version: "0.1.0"},
+		Files:    []*chart.File{{Name: "config/app.conf", Data: []byte("from-parent")}},
+	}
+	if err := chartutil.SaveDir(parent, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "sub", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sub\ndata:\n  conf: {{ .Files.Get \"config/app.conf\" | quote }}\n"),
+		}},
+		Files: []*chart.File{{Name: "config/app.conf", Data: []byte("from-sub")}},
+	}
+	if err := chartutil.SaveDir(sub, filepath.Join(tmpdir, "parent", "charts")); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, "parent", "charts", "sub")}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{Policies: ps})
+	for _, msg := range linter.Messages {
+		if msg.Severity > support.InfoSev {
+			t.Errorf("Unexpected message: %s", msg)
+		}
+	}
+}
+
+func TestTemplatesWithStrictRender(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "strictrender", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: strict\ndata:\n  port: {{ .Values.service.port | quote }}\n"),
+		}},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	vals := map[string]interface{}{"service": map[string]interface{}{}}
+
+	linter := support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, vals, namespace, TemplateOptions{})
+	if linter.HighestSeverity == support.ErrorSev {
+		t.Fatalf("Expected the missing value to render as empty, got %v", linter.Messages)
+	}
+
+	linter = support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, vals, namespace, TemplateOptions{StrictRender: true})
+	if len(linter.Messages) != 1 || !strings.Contains(linter.Messages[0].Err.Error(), `map has no entry for key "port"`) {
+		t.Errorf("Expected the missing key to fail the render, got %v", linter.Messages)
+	}
+}
+
+func TestTemplatesWithRenderedCount(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "renderedcount", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/deployments.yaml",
+			Data: []byte("{{- range .Values.apps }}\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ . }}\n{{- end }}\n"),
+		}, {
+			Name: "templates/service.yaml",
+			Data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\n# only a comment\n---\nkey: not an object\n"),
+		}},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(ch, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	countOf := func(linter support.Linter) []string {
+		var counts []string
+		for _, msg := range linter.Messages {
+			if msg.RuleID == RuleRenderedCount {
+				if msg.Severity != support.InfoSev || msg.Path != "templates/" {
+					t.Errorf("Expected the count to be an info on templates/, got %s", msg)
+				}
+				counts = append(counts, msg.Err.Error())
+			}
+		}
+		return counts
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, map[string]interface{}{"apps": []interface{}{"web", "worker"}}, namespace, TemplateOptions{RenderedCount: true})
+	if counts := countOf(linter); len(counts) != 1 || counts[0] != "renders 3 Kubernetes object(s): Deployment x2, Service x1" {
+		t.Errorf("Unexpected rendered count: %v", counts)
+	}
+
+	linter = support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, map[string]interface{}{"apps": []interface{}{"web"}}, namespace, TemplateOptions{})
+	if counts := countOf(linter); len(counts) != 0 {
+		t.Errorf("Expected no rendered count without the option, got %v", counts)
+	}
+
+	// A chart which fails to render only reports the error.
+	linter = support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, map[string]interface{}{"apps": "web"}, namespace, TemplateOptions{RenderedCount: true, StrictRender: true})
+	if counts := countOf(linter); len(counts) != 0 || linter.HighestSeverity != support.ErrorSev {
+		t.Errorf("Expected only the render error, got %v", linter.Messages)
+	}
+}
+
+func TestTemplatesLoadBalancerDefault(t *testing.T) {
+	service := "apiVersion: v1\nkind: Service\nmetadata:\n  name: {{ .Values.name }}\nspec:\n  type: {{ .Values.service.type }}\n"
+	tests := []struct {
+		name     string
+		defaults string
+		values   map[string]interface{}
+		reported bool
+	}{{
+		name:     "default",
+		defaults: "LoadBalancer",
+		values:   map[string]interface{}{},
+		reported: true,
+	}, {
+		name:     "unrelated user value",
+		defaults: "LoadBalancer",
+		values:   map[string]interface{}{"ingress": map[string]interface{}{"className": "LoadBalancer"}},
+		reported: true,
+	}, {
+		name:     "user set type",
+		defaults: "ClusterIP",
+		values:   map[string]interface{}{"service": map[string]interface{}{"type": "LoadBalancer"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &chart.Chart{
+				Metadata:  &chart.Metadata{APIVersion: "v2", Name: "loadbalancer", Version: "0.1.0"},
+				Templates: []*chart.File{{Name: "templates/service.yaml", Data: []byte(service)}},
+				Raw:       []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("name: web\nservice:\n  type: " + tt.defaults + "\n")}},
+			}
+			dir := t.TempDir()
+			if err := chartutil.SaveDir(ch, dir); err != nil {
+				t.Fatal(err)
+			}
+
+			linter := support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+			TemplatesWithOptions(&linter, tt.values, namespace, TemplateOptions{})
+			reported := false
+			for _, msg := range linter.Messages {
+				reported = reported || msg.RuleID == RuleLoadBalancer
+			}
+			if reported != tt.reported {
+				t.Errorf("Expected the LoadBalancer default to be reported: %t, got %v", tt.reported, linter.Messages)
+			}
+		})
+	}
+}
+
+func TestTemplatesConventionsOnly(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "conventions", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/configmap.yaml",
+			Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ required \"name is required\" .Values.name }}\n  namespace: fixed\ndata:\n  created: {{ .Release.Time }}\n"),
+		}, {
+			Name: "templates/pod.yaml",
+			Data: []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: root\nspec:\n  securityContext:\n    runAsUser: 0\n"),
+		}, {
+			Name: "templates/README.md",
+			Data: []byte("# Templates\n"),
+		}},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(ch, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{ConventionsOnly: true})
+	var ruleIDs []string
+	for _, msg := range linter.Messages {
+		ruleIDs = append(ruleIDs, msg.RuleID)
+	}
+	sort.Strings(ruleIDs)
+	if strings.Join(ruleIDs, ",") != RuleReleaseTime+","+RuleTemplateExtension {
+		t.Errorf("Expected only the template source conventions to be checked, got %v", linter.Messages)
+	}
+}
+
+func TestTemplatesConventionsOnlyHelpersAndNotes(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "conventions", Version: "0.1.0", Dependencies: []*chart.Dependency{{Name: "redis", Version: "0.1.0"}}},
+		Templates: []*chart.File{{
+			Name: "templates/_helpers.tpl",
+			Data: []byte("{{- define \"conventions.name\" -}}conventions{{- end }}\n{{- define \"fullname\" -}}{{ .Release.Name }}{{- end }}\n"),
+		}, {
+			Name: "templates/notes.txt",
+			Data: []byte("Installed {{ .Release.Name }}\n"),
+		}},
+		Files: []*chart.File{{Name: "NOTES.txt", Data: []byte("Installed\n")}},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(ch, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(dir, ch.Metadata.Name)}
+	TemplatesWithOptions(&linter, values, namespace, TemplateOptions{ConventionsOnly: true})
+	var found []string
+	for _, msg := range linter.Messages {
+		found = append(found, msg.RuleID+" "+msg.Path)
+	}
+	sort.Strings(found)
+	expected := []string{RuleHelperPrefix + " templates/_helpers.tpl", RuleNotesFile + " NOTES.txt", RuleNotesFile + " templates/notes.txt"}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %v, got %v", expected, linter.Messages)
+	}
+}
+
+func TestValidateNoVolatileAnnotations(t *testing.T) {
+	template := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    deployed-at: {{ now | date "20060102150405" | quote }}
+spec:
+  template:
+    metadata:
+      annotations:
+        checksum/config: {{ include (print $.Template.BasePath "/configmap.yaml") . | sha256sum }}
+        checksum/random: {{ randAlphaNum 5 | quote }}
+        "rollme": {{ randAlphaNum 5 | quote }}
+        {{- with .Values.podAnnotations }}
+        {{- toYaml . | nindent 8 }}
+        {{- end }}
+      labels:
+        build: {{ uuidv4 | quote }}
+    spec:
+      containers:
+      - name: web
+        env:
+        - name: STARTED
+          value: {{ now | quote }}
+`)
+	err := validateNoVolatileAnnotations(template)
+	if err == nil {
+		t.Fatal("Expected the volatile annotations to be reported")
+	}
+	want := "annotation(s) deployed-at (line 6, now), rollme (line 13, randAlphaNum) change on every render"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q in %q", want, err)
+	}
+
+	if err := validateNoVolatileAnnotations([]byte("metadata:\n  labels:\n    at: {{ now }}\n  annotations:\n    note: {{ .Values.note }}\n")); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestValidateNoDeprecatedBuiltins(t *testing.T) {
+	template := []byte(`metadata:
+  labels:
+    kube: {{ .Capabilities.KubeVersion.GitVersion | quote }}
+    name: {{ .Values.name | trimall "-" }}
+`)
+	err := validateNoDeprecatedBuiltins(template)
+	if err == nil {
+		t.Fatal("Expected the deprecated built-ins to be reported")
+	}
+	for _, want := range []string{
+		".Capabilities.KubeVersion.GitVersion is deprecated, use .Capabilities.KubeVersion.Version instead",
+		"the trimall function is deprecated, use trimAll instead",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+
+	if err := validateNoDeprecatedBuiltins([]byte("kube: {{ .Capabilities.KubeVersion.Version }}\n# trimall is deprecated\nname: {{ trimAll \"-\" .Values.name }}\n")); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -17,12 +17,21 @@ limitations under the License.
 package rules
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -40,17 +49,166 @@ func Values(linter *support.Linter) {
 //
 // If additional values are supplied, they are coalesced into the values in values.yaml.
 func ValuesWithOverrides(linter *support.Linter, values map[string]interface{}) {
+	ValuesWithOptions(linter, values, ValuesOptions{})
+}
+
+// ValuesOptions holds the optional settings used when linting values.
+type ValuesOptions struct {
+	// SchemaStrict reports each value violating the schema as an error of
+	// its own, naming the JSON path of the value and the reason.
+	SchemaStrict bool
+}
+
+// ValuesWithOptions tests the values.yaml file, as ValuesWithOverrides does,
+// using the given options.
+func ValuesWithOptions(linter *support.Linter, values map[string]interface{}, opts ValuesOptions) {
 	file := "values.yaml"
 	vf := filepath.Join(linter.ChartDir, file)
+
+	// A schema which isn't valid JSON Schema is reported on its own, and
+	// the values are only checked for well-formedness against it.
+	schemaFile := "values.schema.json"
+	schemaValid := true
+	if schema, err := os.ReadFile(filepath.Join(linter.ChartDir, schemaFile)); err == nil && len(schema) > 0 {
+		schemaValid = linter.RunLinterRuleWithID(RuleValuesSchema, support.ErrorSev, schemaFile, validateSchemaDocument(schema))
+		if schemaValid {
+			linter.RunLinterRuleWithID(RuleValuesSchema, support.ErrorSev, schemaFile, validateSchemaKeywords(schema))
+		}
+	}
+
 	fileExists := linter.RunLinterRule(support.InfoSev, file, validateValuesFileExistence(vf))
 
 	if !fileExists {
 		return
 	}
 
+	if !schemaValid {
+		_, err := chartutil.ReadValuesFile(vf)
+		linter.RunLinterRule(support.ErrorSev, file, errors.Wrap(err, "unable to parse YAML"))
+		return
+	}
+	if opts.SchemaStrict {
+		coalesced, schema, err := valuesAndSchema(vf, values)
+		if !linter.RunLinterRule(support.ErrorSev, file, err) || len(schema) == 0 {
+			return
+		}
+		for _, violation := range schemaViolations(coalesced, schema) {
+			linter.RunLinterRuleWithID(RuleValuesSchema, support.ErrorSev, file, violation)
+		}
+		return
+	}
 	linter.RunLinterRule(support.ErrorSev, file, validateValuesFile(vf, values))
 }
 
+// validateSchemaDocument checks that a values schema compiles: that it is
+// valid against the meta-schema of its draft, draft-07 unless $schema
+// declares another one, and that its references resolve.
+func validateSchemaDocument(schema []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		return errors.Wrap(err, "the schema is not valid JSON")
+	}
+	loader := gojsonschema.NewSchemaLoader()
+	loader.Validate = true
+	loader.Draft = gojsonschema.Draft7
+	if _, err := loader.Compile(gojsonschema.NewGoLoader(doc)); err != nil {
+		return errors.Errorf("the schema is not valid JSON Schema: %s", strings.Join(strings.Fields(strings.ReplaceAll(err.Error(), "\n", "; ")), " "))
+	}
+	return nil
+}
+
+// schemaKeywords are the keywords of the JSON Schema drafts, by the
+// $schema URL of each. Draft 6 and 7 share their keywords but for the
+// ones draft 7 adds.
+var schemaKeywords = func() map[string]map[string]bool {
+	common := []string{"$schema", "$ref", "title", "description", "default", "multipleOf", "maximum",
+		"exclusiveMaximum", "minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern",
+		"additionalItems", "items", "maxItems", "minItems", "uniqueItems", "maxProperties",
+		"minProperties", "required", "additionalProperties", "definitions", "properties",
+		"patternProperties", "dependencies", "enum", "type", "format", "allOf", "anyOf", "oneOf", "not"}
+	draft6 := append([]string{"$id", "examples", "contains", "propertyNames", "const"}, common...)
+	draft7 := append([]string{"$comment", "readOnly", "writeOnly", "contentMediaType", "contentEncoding", "if", "then", "else"}, draft6...)
+	set := func(keywords []string) map[string]bool {
+		m := map[string]bool{}
+		for _, k := range keywords {
+			m[k] = true
+		}
+		return m
+	}
+	return map[string]map[string]bool{
+		"http://json-schema.org/draft-04/schema": set(append([]string{"id"}, common...)),
+		"http://json-schema.org/draft-06/schema": set(draft6),
+		"http://json-schema.org/draft-07/schema": set(draft7),
+	}
+}()
+
+// validateSchemaKeywords checks that a values schema only uses the keywords
+// of its draft. Unknown keywords, such as misspelled ones or the keywords of
+// later drafts, are ignored by the validation, so the values they are meant
+// to restrict are not validated. Keywords starting with x- are extensions,
+// and schemas declaring drafts unknown to Helm are skipped.
+func validateSchemaKeywords(schema []byte) error {
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil
+	}
+	draft := "http://json-schema.org/draft-07/schema"
+	if s, ok := root["$schema"].(string); ok {
+		draft = strings.TrimSuffix(strings.Replace(s, "https://", "http://", 1), "#")
+	}
+	keywords, ok := schemaKeywords[draft]
+	if !ok {
+		return nil
+	}
+	var unknown []string
+	var walk func(node interface{}, pointer string)
+	walk = func(node interface{}, pointer string) {
+		schema, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		keys := make([]string, 0, len(schema))
+		for key := range schema {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := schema[key]
+			switch key {
+			case "properties", "patternProperties", "definitions", "dependencies":
+				children, _ := value.(map[string]interface{})
+				names := make([]string, 0, len(children))
+				for name := range children {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					walk(children[name], pointer+"/"+key+"/"+name)
+				}
+			case "items", "allOf", "anyOf", "oneOf":
+				if list, ok := value.([]interface{}); ok {
+					for i, item := range list {
+						walk(item, fmt.Sprintf("%s/%s/%d", pointer, key, i))
+					}
+					continue
+				}
+				walk(value, pointer+"/"+key)
+			case "additionalItems", "additionalProperties", "contains", "propertyNames", "not", "if", "then", "else":
+				walk(value, pointer+"/"+key)
+			default:
+				if !keywords[key] && !strings.HasPrefix(key, "x-") {
+					unknown = append(unknown, pointer+"/"+key)
+				}
+			}
+		}
+	}
+	walk(root, "#")
+	if len(unknown) == 0 {
+		return nil
+	}
+	return errors.Errorf("the schema uses keywords which %s doesn't define, so they don't validate anything: %s", path.Base(path.Dir(draft)), strings.Join(unknown, ", "))
+}
+
 func validateValuesFileExistence(valuesPath string) error {
 	_, err := os.Stat(valuesPath)
 	if err != nil {
@@ -60,9 +218,20 @@ func validateValuesFileExistence(valuesPath string) error {
 }
 
 func validateValuesFile(valuesPath string, overrides map[string]interface{}) error {
+	values, schema, err := valuesAndSchema(valuesPath, overrides)
+	if err != nil || len(schema) == 0 {
+		return err
+	}
+	return chartutil.ValidateAgainstSingleSchema(values, schema)
+}
+
+// valuesAndSchema reads the values file at valuesPath, with overrides
+// coalesced into it, and the schema next to it, which is empty if there is
+// none.
+func valuesAndSchema(valuesPath string, overrides map[string]interface{}) (map[string]interface{}, []byte, error) {
 	values, err := chartutil.ReadValuesFile(valuesPath)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse YAML")
+		return nil, nil, errors.Wrap(err, "unable to parse YAML")
 	}
 
 	// Helm 3.0.0 carried over the values linting from Helm 2.x, which only tests the top
@@ -77,10 +246,227 @@ func validateValuesFile(valuesPath string, overrides map[string]interface{}) err
 	schemaPath := valuesPath[:len(valuesPath)-len(ext)] + ".schema.json"
 	schema, err := os.ReadFile(schemaPath)
 	if len(schema) == 0 {
+		return coalescedValues, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return coalescedValues, schema, nil
+}
+
+// schemaViolations validates values against schema, as
+// chartutil.ValidateAgainstSingleSchema does, and returns each violation on
+// its own, prefixed with the JSON path of the value, such as $.image.tag.
+func schemaViolations(values map[string]interface{}, schema []byte) (violations []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			violations = []error{errors.Errorf("unable to validate schema: %s", r)}
+		}
+	}()
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return []error{err}
+	}
+	if values == nil {
+		valuesJSON = []byte("{}")
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(valuesJSON))
+	if err != nil {
+		return []error{err}
+	}
+	for _, desc := range result.Errors() {
+		violations = append(violations, errors.Errorf("%s: %s", violationPath(desc), desc.Description()))
+	}
+	return violations
+}
+
+// violationPath returns the JSON path of the value a schema violation is
+// about. The path of a missing required value is the one it is expected at.
+func violationPath(desc gojsonschema.ResultError) string {
+	p := "$"
+	if field := desc.Field(); field != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+		p += "." + field
+	}
+	if property, ok := desc.Details()["property"].(string); ok && desc.Type() == "required" {
+		p += "." + property
+	}
+	return p
+}
+
+// replicasValueSearch matches replicas set directly from a value, such as
+// `replicas: {{ .Values.replicaCount }}`, capturing the path of the value.
+var replicasValueSearch = regexp.MustCompile(`replicas:\s*\{\{-?\s*\$?\.Values\.([\w.]+)\s*(?:\|[^}]*)?-?\}\}`)
+
+// validateReplicasSchema checks that the values the replicas of a template
+// are set from are bounded by the chart's schema. Without a minimum and a
+// maximum, any number, including a negative one, can be supplied. Charts
+// without a schema don't validate their values at all and are skipped.
+func validateReplicasSchema(template []byte, schema []byte) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	matches := replicasValueSearch.FindAllSubmatch(template, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	var root map[string]interface{}
+	// An invalid schema is reported when linting the values.
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil
+	}
+
+	var unbounded []string
+	seen := map[string]bool{}
+	for _, m := range matches {
+		key := string(m[1])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		property := schemaProperty(root, strings.Split(key, "."))
+		var missing []string
+		if !schemaHasAny(property, "minimum", "exclusiveMinimum") {
+			missing = append(missing, "minimum")
+		}
+		if !schemaHasAny(property, "maximum", "exclusiveMaximum") {
+			missing = append(missing, "maximum")
+		}
+		if len(missing) > 0 {
+			unbounded = append(unbounded, fmt.Sprintf("%s (no %s)", key, strings.Join(missing, " or ")))
+		}
+	}
+	if len(unbounded) == 0 {
 		return nil
 	}
+	return errors.Errorf("replicas are set from values which the schema does not bound: %s. Add a minimum and a maximum for them to values.schema.json", strings.Join(unbounded, ", "))
+}
+
+// schemaProperty returns the schema of the value at the given path, or nil
+// if the schema doesn't describe it.
+func schemaProperty(schema map[string]interface{}, path []string) map[string]interface{} {
+	for _, name := range path {
+		properties, _ := schema["properties"].(map[string]interface{})
+		schema, _ = properties[name].(map[string]interface{})
+		if schema == nil {
+			return nil
+		}
+	}
+	return schema
+}
+
+func schemaHasAny(schema map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := schema[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandValueTemplates runs every string value containing template syntax
+// through the engine, the way a chart passing it to `tpl` would, and replaces
+// it with the result. The values of subcharts are left as they are, since
+// they would be expanded in the context of the subchart.
+func expandValueTemplates(e engine.Engine, c *chart.Chart, top chartutil.Values) error {
+	vals, err := top.Table("Values")
 	if err != nil {
-		return err
+		return nil
+	}
+	subcharts := map[string]bool{}
+	for _, dep := range c.Dependencies() {
+		subcharts[dep.Name()] = true
+	}
+	if c.Metadata != nil {
+		for _, dep := range c.Metadata.Dependencies {
+			subcharts[dep.Name] = true
+			if dep.Alias != "" {
+				subcharts[dep.Alias] = true
+			}
+		}
+	}
+
+	// The value templates are rendered with the helpers of the chart and
+	// its subcharts available, but none of their other templates.
+	tc := partialsOnly(c)
+	partials := tc.Templates
+	var expand func(v interface{}, field string) (interface{}, error)
+	expand = func(v interface{}, field string) (interface{}, error) {
+		switch v := v.(type) {
+		case string:
+			if !strings.Contains(v, "{{") {
+				return v, nil
+			}
+			name := "values/" + field
+			tc.Templates = append(partials[:len(partials):len(partials)], &chart.File{Name: name, Data: []byte(v)})
+			out, err := e.Render(tc, top)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to expand the template in value %s", field)
+			}
+			return out[path.Join(tc.ChartFullPath(), name)], nil
+		case map[string]interface{}:
+			return v, expandTable(v, field, expand)
+		case chartutil.Values:
+			return v, expandTable(v, field, expand)
+		case []interface{}:
+			for i := range v {
+				expanded, err := expand(v[i], fmt.Sprintf("%s[%d]", field, i))
+				if err != nil {
+					return nil, err
+				}
+				v[i] = expanded
+			}
+		}
+		return v, nil
+	}
+
+	for _, key := range sortedKeys(vals) {
+		if subcharts[key] {
+			continue
+		}
+		expanded, err := expand(vals[key], key)
+		if err != nil {
+			return err
+		}
+		vals[key] = expanded
+	}
+	return nil
+}
+
+func expandTable(table map[string]interface{}, field string, expand func(interface{}, string) (interface{}, error)) error {
+	for _, key := range sortedKeys(table) {
+		expanded, err := expand(table[key], field+"."+key)
+		if err != nil {
+			return err
+		}
+		table[key] = expanded
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// partialsOnly returns a copy of the chart and its subcharts keeping only the
+// partial templates, whose names start with an underscore.
+func partialsOnly(c *chart.Chart) *chart.Chart {
+	cp := *c
+	cp.Templates = nil
+	for _, t := range c.Templates {
+		if strings.HasPrefix(path.Base(t.Name), "_") {
+			cp.Templates = append(cp.Templates, t)
+		}
+	}
+	deps := make([]*chart.Chart, 0, len(c.Dependencies()))
+	for _, dep := range c.Dependencies() {
+		deps = append(deps, partialsOnly(dep))
 	}
-	return chartutil.ValidateAgainstSingleSchema(coalescedValues, schema)
+	cp.SetDependencies(deps...)
+	return &cp
 }
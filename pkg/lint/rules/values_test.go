@@ -19,11 +19,15 @@ package rules
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 )
 
 var nonExistingValuesFilePath = filepath.Join("/fake/dir", "values.yaml")
@@ -167,3 +171,225 @@ func createTestingSchema(t *testing.T, dir string) string {
 	}
 	return schemafile
 }
+
+func TestValidateReplicasSchema(t *testing.T) {
+	template := []byte(`spec:
+  {{- if not .Values.autoscaling.enabled }}
+  replicas: {{ .Values.replicaCount }}
+  {{- end }}
+---
+spec:
+  replicas: {{- .Values.worker.replicas | int -}}
+`)
+	tests := []struct {
+		name   string
+		schema string
+		errs   []string
+	}{{
+		name: "no schema",
+	}, {
+		name:   "undescribed values",
+		schema: `{"type": "object"}`,
+		errs:   []string{"replicaCount (no minimum or maximum)", "worker.replicas (no minimum or maximum)"},
+	}, {
+		name: "partially bounded",
+		schema: `{"properties": {
+  "replicaCount": {"type": "integer", "minimum": 1, "maximum": 10},
+  "worker": {"properties": {"replicas": {"type": "integer", "exclusiveMinimum": 0}}}
+}}`,
+		errs: []string{"worker.replicas (no maximum)"},
+	}, {
+		name: "bounded",
+		schema: `{"properties": {
+  "replicaCount": {"type": "integer", "minimum": 1, "maximum": 10},
+  "worker": {"properties": {"replicas": {"type": "integer", "minimum": 0, "exclusiveMaximum": 100}}}
+}}`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReplicasSchema(template, []byte(tt.schema))
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected the unbounded replicas to be reported")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in %q", want, err)
+				}
+			}
+			if len(tt.errs) == 1 && strings.Contains(err.Error(), "replicaCount") {
+				t.Errorf("Expected the bounded replicaCount not to be reported, got %s", err)
+			}
+		})
+	}
+}
+
+func TestExpandValueTemplates(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "expand", Version: "0.1.0"},
+		Templates: []*chart.File{{
+			Name: "templates/_helpers.tpl",
+			Data: []byte(`{{ define "expand.fullname" }}{{ .Release.Name }}-expand{{ end }}`),
+		}, {
+			Name: "templates/configmap.yaml",
+			Data: []byte("{{ fail \"not rendered while expanding values\" }}"),
+		}},
+	}
+	sub := &chart.Chart{Metadata: &chart.Metadata{APIVersion: "v2", Name: "sub", Version: "0.1.0"}}
+	ch.AddDependency(sub)
+
+	vals := map[string]interface{}{
+		"host":   "{{ include \"expand.fullname\" . }}.example.com",
+		"hosts":  []interface{}{"{{ .Values.domain }}", "static"},
+		"ports":  map[string]interface{}{"http": 80, "name": "{{ .Release.Namespace }}"},
+		"domain": "example.com",
+		"sub":    map[string]interface{}{"host": "{{ .Values.subhost }}"},
+	}
+	top, err := chartutil.ToRenderValues(ch, vals, chartutil.ReleaseOptions{Name: "rel", Namespace: "ns"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expandValueTemplates(engine.Engine{LintMode: true}, ch, top); err != nil {
+		t.Fatalf("Failed to expand values: %s", err)
+	}
+	expanded, _ := top.Table("Values")
+	assert.Equal(t, "rel-expand.example.com", expanded["host"])
+	assert.Equal(t, []interface{}{"example.com", "static"}, expanded["hosts"])
+	assert.Equal(t, map[string]interface{}{"http": 80, "name": "ns"}, expanded["ports"])
+	assert.Equal(t, "{{ .Values.subhost }}", expanded["sub"].(map[string]interface{})["host"])
+
+	vals = map[string]interface{}{"broken": map[string]interface{}{"host": "{{ .Release.Name | nosuchfunc }}"}}
+	top, err = chartutil.ToRenderValues(ch, vals, chartutil.ReleaseOptions{Name: "rel"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = expandValueTemplates(engine.Engine{LintMode: true}, ch, top)
+	if err == nil || !strings.Contains(err.Error(), "unable to expand the template in value broken.host") {
+		t.Errorf("Expected an error expanding broken.host, got %v", err)
+	}
+}
+
+func TestValidateSchemaDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{{
+		name:   "valid",
+		schema: testSchema,
+	}, {
+		name:   "invalid JSON",
+		schema: `{"type": "object",}`,
+		err:    "the schema is not valid JSON",
+	}, {
+		name:   "invalid keyword value",
+		schema: `{"type": "object", "properties": {"replicas": {"type": "int"}}}`,
+		err:    "the schema is not valid JSON Schema",
+	}, {
+		name:   "draft 4 exclusive minimum",
+		schema: `{"$schema": "http://json-schema.org/draft-04/schema#", "properties": {"replicas": {"minimum": 1, "exclusiveMinimum": 1}}}`,
+		err:    "the schema is not valid JSON Schema",
+	}, {
+		name:   "unresolved reference",
+		schema: `{"properties": {"image": {"$ref": "#/definitions/image"}}}`,
+		err:    "the schema is not valid JSON Schema",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchemaDocument([]byte(tt.schema))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{{
+		name:   "known keywords",
+		schema: testSchema,
+	}, {
+		name: "misspelled and later draft keywords",
+		schema: `{"$schema": "http://json-schema.org/draft-07/schema#", "$defs": {}, "x-order": 1, "properties": {
+  "image": {"type": "object", "properties": {"tag": {"typ": "string"}}, "required": ["tag"]},
+  "ports": {"items": [{"minimun": 1}], "dependentRequired": {}}
+}}`,
+		err: "the schema uses keywords which draft-07 doesn't define, so they don't validate anything: #/$defs, #/properties/image/properties/tag/typ, #/properties/ports/dependentRequired, #/properties/ports/items/0/minimun",
+	}, {
+		name:   "draft 7 keyword in a draft 4 schema",
+		schema: `{"$schema": "http://json-schema.org/draft-04/schema#", "properties": {"mode": {"const": "simple"}}}`,
+		err:    "the schema uses keywords which draft-04 doesn't define, so they don't validate anything: #/properties/mode/const",
+	}, {
+		name:   "unknown draft",
+		schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "$defs": {}}`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchemaKeywords([]byte(tt.schema))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Expected %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSchemaViolations(t *testing.T) {
+	schema := []byte(`{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {"tag": {"type": "string"}}
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`)
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   []string
+	}{
+		{"valid", map[string]interface{}{"image": map[string]interface{}{"repository": "nginx", "tag": "1.25"}}, nil},
+		{"missing", nil, []string{"$.image: image is required"}},
+		{"nested", map[string]interface{}{
+			"image":    map[string]interface{}{"tag": 1},
+			"replicas": 0,
+		}, []string{
+			"$.image.repository: repository is required",
+			"$.image.tag: Invalid type. Expected: string, given: integer",
+			"$.replicas: Must be greater than or equal to 1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range schemaViolations(tt.values, schema) {
+				got = append(got, err.Error())
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
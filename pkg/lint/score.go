@@ -0,0 +1,62 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"math"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// defaultSeverityWeights are the points a finding of each severity costs.
+var defaultSeverityWeights = map[string]float64{
+	"info":    1,
+	"warning": 5,
+	"error":   20,
+}
+
+// Score rates a chart from 0 to 100 based on the findings of its lint. Every
+// finding costs the points of its severity, multiplied by the weight of its
+// rule. Both default to the built in weights unless set in config.
+func Score(messages []support.Message, config *rules.ScoreConfig) int {
+	cost := 0.0
+	for _, msg := range messages {
+		var label string
+		switch msg.Severity {
+		case support.InfoSev:
+			label = "info"
+		case support.WarningSev:
+			label = "warning"
+		case support.ErrorSev:
+			label = "error"
+		default:
+			continue
+		}
+		weight, ruleWeight := defaultSeverityWeights[label], 1.0
+		if config != nil {
+			if w, ok := config.Severities[label]; ok {
+				weight = w
+			}
+			if w, ok := config.Rules[msg.RuleID]; ok && msg.RuleID != "" {
+				ruleWeight = w
+			}
+		}
+		cost += weight * ruleWeight
+	}
+	return int(math.Max(0, math.Round(100-cost)))
+}
@@ -0,0 +1,51 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestScore(t *testing.T) {
+	messages := []support.Message{
+		{Severity: support.InfoSev, Path: "Chart.yaml", Err: errors.New("icon is recommended")},
+		{Severity: support.WarningSev, Path: "templates/a.yaml", Err: errors.New("deprecated"), RuleID: rules.RuleDeprecatedAPI},
+		{Severity: support.ErrorSev, Path: "templates/b.yaml", Err: errors.New("invalid"), RuleID: rules.RuleSecretType},
+	}
+
+	if score := Score(nil, nil); score != 100 {
+		t.Errorf("Expected a chart without findings to score 100, got %d", score)
+	}
+	if score := Score(messages, nil); score != 74 {
+		t.Errorf("Expected the default weights to score 74, got %d", score)
+	}
+	config := &rules.ScoreConfig{
+		Severities: map[string]float64{"info": 0, "warning": 10},
+		Rules:      map[string]float64{rules.RuleDeprecatedAPI: 2.5},
+	}
+	if score := Score(messages, config); score != 55 {
+		t.Errorf("Expected the configured weights to score 55, got %d", score)
+	}
+	config = &rules.ScoreConfig{Severities: map[string]float64{"error": 200}}
+	if score := Score(messages, config); score != 0 {
+		t.Errorf("Expected the score not to go below 0, got %d", score)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"strings"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// WithSkipRules drops the findings matched by skips, as SkipRules does.
+func WithSkipRules(skips []string) LinterOption {
+	return func(lint *linterOptions) {
+		lint.SkipRules = skips
+	}
+}
+
+// SkipRules returns messages without those matched by one of skips: either
+// the ID of the rule which produced them, such as chart-name or
+// deprecated-api, or a prefix of their path, such as templates or
+// values.yaml. Unlike ignore files, skips apply to every chart linted.
+func SkipRules(messages []support.Message, skips []string) []support.Message {
+	if len(skips) == 0 {
+		return messages
+	}
+	var kept []support.Message
+	for _, msg := range messages {
+		if !skipped(msg, skips) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+func skipped(msg support.Message, skips []string) bool {
+	for _, skip := range skips {
+		if skip == "" {
+			continue
+		}
+		if msg.RuleID == skip || strings.HasPrefix(msg.Path, skip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestSkipRules(t *testing.T) {
+	messages := []support.Message{
+		{Severity: support.WarningSev, Path: "Chart.yaml", RuleID: "app-version", Err: errors.New("app version")},
+		{Severity: support.InfoSev, Path: "Chart.yaml", RuleID: "icon", Err: errors.New("icon")},
+		{Severity: support.ErrorSev, Path: "templates/deployment.yaml", RuleID: "deprecated-api", Err: errors.New("deprecated")},
+		{Severity: support.ErrorSev, Path: "templates/", Err: errors.New("render")},
+		{Severity: support.ErrorSev, Path: "values.yaml", Err: errors.New("values")},
+	}
+
+	tests := []struct {
+		name  string
+		skips []string
+		kept  []int
+	}{
+		{"no skips", nil, []int{0, 1, 2, 3, 4}},
+		{"rule IDs", []string{"app-version", "deprecated-api"}, []int{1, 3, 4}},
+		{"path prefixes", []string{"templates", "values"}, []int{0, 1}},
+		{"unknown and empty skips", []string{"", "chart-name"}, []int{0, 1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []support.Message
+			for _, i := range tt.kept {
+				want = append(want, messages[i])
+			}
+			if got := SkipRules(messages, tt.skips); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -47,6 +47,8 @@ type Message struct {
 	Severity int
 	Path     string
 	Err      error
+	// RuleID identifies the rule which produced the message, when known.
+	RuleID string
 }
 
 func (m Message) Error() string {
@@ -60,13 +62,22 @@ func NewMessage(severity int, path string, err error) Message {
 
 // RunLinterRule returns true if the validation passed
 func (l *Linter) RunLinterRule(severity int, path string, err error) bool {
+	return l.RunLinterRuleWithID("", severity, path, err)
+}
+
+// RunLinterRuleWithID returns true if the validation passed. Failures are
+// recorded with the given rule ID, so they can be told apart from the
+// findings of other rules.
+func (l *Linter) RunLinterRuleWithID(ruleID string, severity int, path string, err error) bool {
 	// severity is out of bound
 	if severity < 0 || severity >= len(sev) {
 		return false
 	}
 
 	if err != nil {
-		l.Messages = append(l.Messages, NewMessage(severity, path, err))
+		msg := NewMessage(severity, path, err)
+		msg.RuleID = ruleID
+		l.Messages = append(l.Messages, msg)
 
 		if severity > l.HighestSeverity {
 			l.HighestSeverity = severity
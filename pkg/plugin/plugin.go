@@ -42,6 +42,17 @@ type Downloaders struct {
 	Command string `json:"command"`
 }
 
+// Loaders represents the plugins capability if it can convert charts
+// stored in other formats into standard charts
+type Loaders struct {
+	// Patterns are the globs matched against the base name of a chart path.
+	Patterns []string `json:"patterns"`
+	// Command is the executable path with which the plugin converts the
+	// chart. It is called with the chart path and the directory into which
+	// the standard chart, with Chart.yaml at its root, must be written.
+	Command string `json:"command"`
+}
+
 // PlatformCommand represents a command for a particular operating system and architecture
 type PlatformCommand struct {
 	OperatingSystem string `json:"os"`
@@ -97,6 +108,10 @@ type Metadata struct {
 	// for special protocols.
 	Downloaders []Downloaders `json:"downloaders"`
 
+	// Loaders field is used if the plugin supply loader mechanism
+	// for charts stored in other formats.
+	Loaders []Loaders `json:"loaders"`
+
 	// UseTunnelDeprecated indicates that this command needs a tunnel.
 	// Setting this will cause a number of side effects, such as the
 	// automatic setting of HELM_HOST.
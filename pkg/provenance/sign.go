@@ -295,6 +295,21 @@ func (s *Signatory) Verify(chartpath, sigpath string) (*Verification, error) {
 	return ver, nil
 }
 
+// VerifyMessage checks the signature of a clear-signed message, such as a
+// file other than a chart signed with gpg --clearsign, and returns the
+// signed content along with the entity that signed it.
+func (s *Signatory) VerifyMessage(data []byte) ([]byte, *openpgp.Entity, error) {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("signature block not found")
+	}
+	by, err := s.verifySignature(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block.Plaintext, by, nil
+}
+
 func (s *Signatory) decodeSignature(filename string) (*clearsign.Block, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
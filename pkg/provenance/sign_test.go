@@ -16,6 +16,7 @@ limitations under the License.
 package provenance
 
 import (
+	"bytes"
 	"crypto"
 	"fmt"
 	"io"
@@ -24,6 +25,7 @@ import (
 	"strings"
 	"testing"
 
+	"golang.org/x/crypto/openpgp/clearsign"        //nolint
 	pgperrors "golang.org/x/crypto/openpgp/errors" //nolint
 )
 
@@ -303,6 +305,45 @@ func TestDecodeSignature(t *testing.T) {
 	}
 }
 
+func TestVerifyMessage(t *testing.T) {
+	signer, err := NewFromFiles(testKeyfile, testPubfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := "severities:\n  run-as-root: error\n"
+	var out bytes.Buffer
+	w, err := clearsign.Encode(&out, signer.Entity.PrivateKey, &defaultPGPConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, message); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, by, err := signer.VerifyMessage(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != message {
+		t.Errorf("Expected the signed content %q, got %q", message, content)
+	}
+	if _, ok := by.Identities[testKeyName]; !ok {
+		t.Errorf("Expected identity %q", testKeyName)
+	}
+
+	tampered := bytes.Replace(out.Bytes(), []byte("error"), []byte("info"), 1)
+	if _, _, err := signer.VerifyMessage(tampered); err == nil {
+		t.Error("Expected the tampered message to fail verification")
+	}
+	if _, _, err := signer.VerifyMessage([]byte(message)); err == nil {
+		t.Error("Expected the unsigned message to fail verification")
+	}
+}
+
 func TestVerify(t *testing.T) {
 	signer, err := NewFromFiles(testKeyfile, testPubfile)
 	if err != nil {